@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/index"
+	"github.com/laytan/youtupedia/internal/store"
+)
+
+var channelsCmd = &command{
+	name:        "channels",
+	description: "Manage the indexed channels.",
+	subcommands: []*command{
+		{
+			name:        "list",
+			description: "List the channels, with their amount of videos and failures.",
+			run:         channelsList,
+		},
+		{
+			name: "add",
+			args: "<channel-id>",
+			description: "Add a channel using the YouTube API, without indexing it.\n" +
+				"Use the index command to index its videos.",
+			run: channelsAdd,
+		},
+		{
+			name: "add-feed",
+			args: "<feed-url|path>",
+			description: "Add a podcast RSS feed as a channel, without indexing it.\n" +
+				"Use the index command to queue its episodes, which failures run transcribes.",
+			run: channelsAddFeed,
+		},
+		{
+			name: "remove",
+			args: "<channel-id>",
+			description: "Remove a channel, including its videos, transcripts and failures.\n" +
+				"Without --yes, only shows what would be removed.",
+			run: channelsRemove,
+		},
+		{
+			name:        "refresh",
+			args:        "[channel-id...]",
+			description: "Update the title, thumbnail and URL of channels from the YouTube API, or their feed, all channels if none are given.\nDirectory channels have nothing to refresh, see index-dir.",
+			run:         channelsRefresh,
+		},
+		{
+			name: "settings",
+			args: "<channel-id>",
+			description: "Show or change what gets indexed of a channel.\n" +
+				"Only the given flags are changed, without flags the current settings are shown.",
+			run: channelsSettings,
+		},
+	},
+}
+
+func channelsList(ctx context.Context, c *command, args []string) error {
+	if err := c.parse(c.flags(), args, 0); err != nil {
+		return err
+	}
+
+	db, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	channels, err := db.Channels(ctx)
+	if err != nil {
+		return fmt.Errorf("retrieving channels: %w", err)
+	}
+
+	w := newTabWriter()
+	fmt.Fprintln(w, "ID\tSOURCE\tURL\tTITLE\tVIDEOS\tFAILURES")
+	for _, ch := range channels {
+		counts, err := db.ChannelCounts(ctx, ch.ID)
+		if err != nil {
+			return fmt.Errorf("counting videos of %q: %w", ch.ID, err)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\n", ch.ID, ch.Source, ch.CustomUrl, ch.Title, counts.Videos, counts.Failures)
+	}
+
+	return w.Flush()
+}
+
+func channelsAdd(ctx context.Context, c *command, args []string) error {
+	flags := c.flags()
+	if err := c.parse(flags, args, 1); err != nil {
+		return err
+	}
+	id := flags.Arg(0)
+
+	db, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	if ch, err := db.Channel(ctx, id); err == nil {
+		log.Printf("[INFO]: Channel %q (%s) is already added", ch.Title, ch.CustomUrl)
+		return nil
+	}
+
+	indexer, err := newIndexer(db)
+	if err != nil {
+		return err
+	}
+
+	ch, err := indexer.Channel(ctx, id)
+	if err != nil {
+		return fmt.Errorf("adding channel %q: %w", id, err)
+	}
+
+	log.Printf("[INFO]: Added channel %q (%s), run `index %s` to index its videos", ch.Title, ch.CustomUrl, ch.ID)
+	return nil
+}
+
+func channelsAddFeed(ctx context.Context, c *command, args []string) error {
+	flags := c.flags()
+	handle := flags.String("handle", "", "handle of the channel in URLs, like @podcast, defaults to the title of the podcast")
+	if err := c.parse(flags, args, 1); err != nil {
+		return err
+	}
+	location := flags.Arg(0)
+
+	db, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	indexer, err := newIndexer(db)
+	if err != nil {
+		return err
+	}
+
+	ch, err := indexer.AddFeed(ctx, location, *handle)
+	if err != nil {
+		return fmt.Errorf("adding feed %q: %w", location, err)
+	}
+
+	log.Printf("[INFO]: Added podcast %q (%s), run `index %s` to queue its episodes", ch.Title, ch.CustomUrl, ch.ID)
+	return nil
+}
+
+func channelsRemove(ctx context.Context, c *command, args []string) error {
+	flags := c.flags()
+	yes := flags.Bool("yes", false, "actually remove the channel")
+	if err := c.parse(flags, args, 1); err != nil {
+		return err
+	}
+	id := flags.Arg(0)
+
+	db, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	// The videos and failures are removed by the database, the foreign keys cascade on delete.
+	return db.Tx(ctx, func(q store.Querier) error {
+		ch, err := q.Channel(ctx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("channel %q does not exist", id)
+			}
+
+			return fmt.Errorf("retrieving channel %q: %w", id, err)
+		}
+
+		counts, err := q.ChannelCounts(ctx, id)
+		if err != nil {
+			return fmt.Errorf("counting videos of %q: %w", id, err)
+		}
+
+		if !*yes {
+			log.Printf(
+				"[INFO]: This removes channel %q with %d videos, their transcripts, and %d failures, run again with --yes to confirm",
+				ch.Title,
+				counts.Videos,
+				counts.Failures,
+			)
+			return nil
+		}
+
+		if err := q.DeleteChannel(ctx, id); err != nil {
+			return fmt.Errorf("deleting channel %q: %w", id, err)
+		}
+
+		log.Printf(
+			"[INFO]: Removed channel %q with %d videos and %d failures",
+			ch.Title,
+			counts.Videos,
+			counts.Failures,
+		)
+		return nil
+	})
+}
+
+func channelsRefresh(ctx context.Context, c *command, args []string) error {
+	flags := c.flags()
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	ids := flags.Args()
+	if len(ids) == 0 {
+		channels, err := db.Channels(ctx)
+		if err != nil {
+			return fmt.Errorf("retrieving channels: %w", err)
+		}
+
+		for _, ch := range channels {
+			ids = append(ids, ch.ID)
+		}
+	}
+
+	indexer, err := newIndexer(db)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		ch, err := indexer.RefreshChannel(ctx, id)
+		if err != nil {
+			return fmt.Errorf("refreshing channel %q: %w", id, err)
+		}
+
+		log.Printf("[INFO]: Refreshed channel %q (%s)", ch.Title, ch.CustomUrl)
+	}
+
+	return nil
+}
+
+func channelsSettings(ctx context.Context, c *command, args []string) error {
+	flags := c.flags()
+	shorts := flags.Bool("shorts", true, "index Shorts")
+	live := flags.Bool("live", true, "index recordings of live streams")
+	premieres := flags.Bool("premieres", true, "index premieres")
+	minDuration := flags.Duration("min-duration", 0, "skip videos shorter than this, for example 1m30s")
+	blocklist := flags.String("title-blocklist", "", "skip videos with a title matching this regular expression")
+	captions := flags.String("captions", string(store.CaptionSourceBest), "caption source: best, manual, auto or whisper")
+	whisper := flags.Bool("whisper-fallback", true, "transcribe videos without (matching) captions using whisper")
+	whisperModel := flags.String("whisper-model", "", "whisper model, for example small.en, empty for the model of the transcriber")
+	whisperLanguage := flags.String("whisper-language", "", "language hint for whisper, for example de or auto, empty for the default")
+	diarize := flags.Bool("diarize", false, "label speakers when transcribing with whisper, needs a tinydiarize model, like small.en-tdrz")
+	upgrade := flags.String(
+		"upgrade-captions",
+		string(store.UpgradeCaptionsNever),
+		"upgrade automatic captions using whisper: never, low_quality or always",
+	)
+	priority := flags.Int("priority", 0, "failures of channels with a higher priority are transcribed first, can be negative")
+	if err := c.parse(flags, args, 1); err != nil {
+		return err
+	}
+	id := flags.Arg(0)
+
+	switch store.CaptionSource(*captions) {
+	case store.CaptionSourceBest, store.CaptionSourceManual, store.CaptionSourceAuto, store.CaptionSourceWhisper:
+	default:
+		return fmt.Errorf("unknown caption source %q: %w", *captions, errUsage)
+	}
+
+	switch store.UpgradeCaptions(*upgrade) {
+	case store.UpgradeCaptionsNever, store.UpgradeCaptionsLowQuality, store.UpgradeCaptionsAlways:
+	default:
+		return fmt.Errorf("unknown upgrade captions setting %q: %w", *upgrade, errUsage)
+	}
+
+	if _, err := regexp.Compile(*blocklist); err != nil {
+		return fmt.Errorf("parsing --title-blocklist: %w", err)
+	}
+
+	db, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.Channel(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("channel %q does not exist", id)
+		}
+
+		return fmt.Errorf("retrieving channel %q: %w", id, err)
+	}
+
+	settings, err := store.ChannelSettingsOrDefault(ctx, db, id)
+	if err != nil {
+		return fmt.Errorf("retrieving settings of %q: %w", id, err)
+	}
+
+	oldPriority := settings.Priority
+	changed := false
+	flags.Visit(func(f *flag.Flag) {
+		changed = true
+		switch f.Name {
+		case "shorts":
+			settings.IncludeShorts = *shorts
+		case "live":
+			settings.IncludeLive = *live
+		case "premieres":
+			settings.IncludePremieres = *premieres
+		case "min-duration":
+			settings.MinDuration = int32(minDuration.Seconds())
+		case "title-blocklist":
+			settings.TitleBlocklist = *blocklist
+		case "captions":
+			settings.CaptionSource = *captions
+		case "whisper-fallback":
+			settings.WhisperFallback = *whisper
+		case "whisper-model":
+			settings.WhisperModel = *whisperModel
+		case "whisper-language":
+			settings.WhisperLanguage = *whisperLanguage
+		case "diarize":
+			settings.Diarize = *diarize
+		case "upgrade-captions":
+			settings.UpgradeCaptions = *upgrade
+		case "priority":
+			settings.Priority = int32(*priority)
+		}
+	})
+
+	if changed {
+		err = db.Tx(ctx, func(q store.Querier) error {
+			// The channel priority is part of the score of the failures that are queued already.
+			if settings.Priority != oldPriority {
+				if err := q.ShiftFailurePriorities(ctx, store.ShiftFailurePrioritiesParams{
+					Amount:    float64(settings.Priority-oldPriority) * index.ChannelPriorityWeight,
+					ChannelID: id,
+				}); err != nil {
+					return fmt.Errorf("updating failure priorities of %q: %w", id, err)
+				}
+			}
+
+			settings, err = q.UpsertChannelSettings(ctx, store.UpsertChannelSettingsParams{
+				ChannelID:        id,
+				IncludeShorts:    settings.IncludeShorts,
+				IncludeLive:      settings.IncludeLive,
+				IncludePremieres: settings.IncludePremieres,
+				MinDuration:      settings.MinDuration,
+				TitleBlocklist:   settings.TitleBlocklist,
+				CaptionSource:    settings.CaptionSource,
+				WhisperFallback:  settings.WhisperFallback,
+				WhisperModel:     settings.WhisperModel,
+				WhisperLanguage:  settings.WhisperLanguage,
+				UpgradeCaptions:  settings.UpgradeCaptions,
+				Priority:         settings.Priority,
+				Diarize:          settings.Diarize,
+			})
+			if err != nil {
+				return fmt.Errorf("saving settings of %q: %w", id, err)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	w := newTabWriter()
+	fmt.Fprintf(w, "shorts\t%t\n", settings.IncludeShorts)
+	fmt.Fprintf(w, "live\t%t\n", settings.IncludeLive)
+	fmt.Fprintf(w, "premieres\t%t\n", settings.IncludePremieres)
+	fmt.Fprintf(w, "min-duration\t%s\n", time.Duration(settings.MinDuration)*time.Second)
+	fmt.Fprintf(w, "title-blocklist\t%q\n", settings.TitleBlocklist)
+	fmt.Fprintf(w, "captions\t%s\n", settings.CaptionSource)
+	fmt.Fprintf(w, "whisper-fallback\t%t\n", settings.WhisperFallback)
+	fmt.Fprintf(w, "whisper-model\t%q\n", settings.WhisperModel)
+	fmt.Fprintf(w, "whisper-language\t%q\n", settings.WhisperLanguage)
+	fmt.Fprintf(w, "diarize\t%t\n", settings.Diarize)
+	fmt.Fprintf(w, "upgrade-captions\t%s\n", settings.UpgradeCaptions)
+	fmt.Fprintf(w, "priority\t%d\n", settings.Priority)
+	return w.Flush()
+}
@@ -0,0 +1,568 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/failures"
+	"github.com/laytan/youtupedia/internal/store"
+	"github.com/laytan/youtupedia/internal/transcribe"
+)
+
+var failuresCmd = &command{
+	name:        "failures",
+	description: "Manage and process the failures queue, videos without captions and pages that exceeded the quota.",
+	subcommands: []*command{
+		{
+			name: "run",
+			description: "Transcribe the videos without captions, podcast episodes, local files, and the videos queued by retranscribe and upgrade, using whisper.\n" +
+				"Requires yt-dlp, for YouTube videos, ffmpeg and a transcription backend, selected with TRANSCRIBER:\n" +
+				"  whisper.cpp (default)  the whisper.cpp binary, see WHISPER_BIN and WHISPER_MODEL\n" +
+				"  whisper.cpp-server     a whisper.cpp server at WHISPER_SERVER_URL, running WHISPER_SERVER_MODEL\n" +
+				"  openai                 an OpenAI compatible API, see OPENAI_BASE_URL, OPENAI_API_KEY and OPENAI_MODEL\n" +
+				"  fake                   transcribes every video as a single line of text, for trying out the pipeline\n" +
+				"Channels can override the model and language of the backend, see `channels settings`.\n" +
+				"With --watch, it keeps running and transcribes new failures as they are created.\n" +
+				"Multiple workers can run against the same database, each failure is claimed by one of them.",
+			run: failuresRun,
+		},
+		{
+			name:        "list",
+			description: "List the failures, in the order they are processed, highest priority first.",
+			run:         failuresList,
+		},
+		{
+			name:        "show",
+			args:        "<failure-id>",
+			description: "Show a failure, including the full error of its last attempt.",
+			run:         failuresShow,
+		},
+		{
+			name: "bump",
+			description: "Move the failures of a channel, or of a single video, to the front of the queue.\n" +
+				"The order of the bumped failures among themselves is kept.",
+			run: failuresBump,
+		},
+		{
+			name: "retry",
+			args: "[failure-id...]",
+			description: "Reset the attempts of failures, making them due now, including dead ones.\n" +
+				"Without IDs, the failures matching the filter flags are retried.",
+			run: failuresRetry,
+		},
+		{
+			name: "drop",
+			args: "[failure-id...]",
+			description: "Remove failures from the queue.\n" +
+				"Without IDs, the failures matching the filter flags are removed.\n" +
+				"Without --yes, only shows what would be removed.",
+			run: failuresDrop,
+		},
+		{
+			name: "purge",
+			description: "Remove the dead failures matching the filter flags.\n" +
+				"Without --yes, only shows what would be removed.",
+			run: failuresPurge,
+		},
+	},
+}
+
+func failuresRun(ctx context.Context, c *command, args []string) error {
+	flags := c.flags()
+	channel := flags.String("channel", "", "only process failures of this channel, by ID or @handle")
+	limit := flags.Int("limit", 0, "stop after this amount of failures, 0 for no limit")
+	watch := flags.Bool("watch", false, "keep running, waiting for new failures when the queue is empty")
+	poll := flags.Duration("poll", time.Minute, "how often to check the queue in watch mode, besides database notifications")
+	workerID := flags.String("worker-id", "", "unique ID of this worker, defaults to the hostname and process ID")
+	lease := flags.Duration("lease", time.Hour, "how long until a claimed failure can be claimed by other workers, if this worker stops responding")
+	workDir := flags.String("work-dir", "", "directory for the downloaded audio, defaults to youtupedia in the temporary directory")
+	minFree := flags.Int64("min-free-mb", 1000, "megabytes of disk space to keep free in the work directory")
+	downloaders := flags.Int("downloaders", 1, "videos downloaded at the same time")
+	transcribers := flags.Int("transcribers", 1, "videos transcribed at the same time, whisper.cpp threads are divided between them")
+	indexers := flags.Int("indexers", 1, "videos indexed at the same time")
+	downloadQueue := flags.Int("download-queue", 0, "claimed failures that can wait for a downloader")
+	transcribeQueue := flags.Int("transcribe-queue", 0, "downloaded videos that can wait for a transcriber")
+	indexQueue := flags.Int("index-queue", 0, "transcribed videos that can wait for an indexer")
+	report := flags.Duration("report", time.Minute, "how often the progress is logged")
+	if err := c.parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	if *downloaders < 1 || *transcribers < 1 || *indexers < 1 {
+		return fmt.Errorf("--downloaders, --transcribers and --indexers must be at least 1: %w", errUsage)
+	}
+
+	db, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	yt, err := youtube()
+	if err != nil {
+		return err
+	}
+
+	opts := failures.RunOptions{Limit: *limit, Watch: *watch}
+	if *channel != "" {
+		ch, err := channelByIdOrHandle(ctx, db, *channel)
+		if err != nil {
+			return err
+		}
+
+		opts.ChannelID = ch.ID
+	}
+
+	if *watch {
+		notifications, err := store.Listen(ctx, pgDsn, store.FailuresChannel)
+		if err != nil {
+			log.Printf("[WARN]: not receiving notifications for new failures, polling every %s: %v", *poll, err)
+		}
+		opts.Notifications = notifications
+	}
+
+	transcriber, err := newTranscriber(*transcribers)
+	if err != nil {
+		return err
+	}
+
+	pipeline := failures.New(db, yt, failures.Options{
+		Transcriber:  transcriber,
+		PollInterval: *poll,
+		WorkerID:     *workerID,
+		WorkDir:      *workDir,
+		MinFreeSpace: *minFree * 1_000_000,
+		Lease:        *lease,
+		Download: failures.StageOptions{
+			Workers: *downloaders,
+			Queue:   *downloadQueue,
+		},
+		Transcribe: failures.StageOptions{
+			Workers: *transcribers,
+			Queue:   *transcribeQueue,
+		},
+		Index: failures.StageOptions{
+			Workers: *indexers,
+			Queue:   *indexQueue,
+		},
+		ReportInterval: *report,
+	})
+	if err := pipeline.WhisperNoCaptionFailures(ctx, opts); err != nil {
+		return fmt.Errorf("processing no caption failures: %w", err)
+	}
+
+	log.Println("[INFO]: Finished failures processing")
+	return nil
+}
+
+// newTranscriber returns the transcription backend configured by the TRANSCRIBER environment variable,
+// the processors are divided between the workers that transcribe at the same time.
+func newTranscriber(workers int) (transcribe.Transcriber, error) {
+	switch backend := os.Getenv("TRANSCRIBER"); backend {
+	case "", "whisper.cpp":
+		threads := (runtime.NumCPU() - 1) / workers
+		if threads < 1 {
+			threads = 1
+		}
+
+		return transcribe.NewWhisperCpp(transcribe.WhisperCppOptions{
+			Bin:     os.Getenv("WHISPER_BIN"),
+			Model:   os.Getenv("WHISPER_MODEL"),
+			Threads: threads,
+		}), nil
+	case "whisper.cpp-server":
+		url := os.Getenv("WHISPER_SERVER_URL")
+		if url == "" {
+			return nil, errors.New("WHISPER_SERVER_URL environment variable must be set")
+		}
+
+		return transcribe.NewServer(transcribe.ServerOptions{
+			URL:   url,
+			Model: os.Getenv("WHISPER_SERVER_MODEL"),
+		}), nil
+	case "openai":
+		return transcribe.NewOpenAI(transcribe.OpenAIOptions{
+			BaseURL: os.Getenv("OPENAI_BASE_URL"),
+			APIKey:  os.Getenv("OPENAI_API_KEY"),
+			Model:   os.Getenv("OPENAI_MODEL"),
+		}), nil
+	case "fake":
+		return &transcribe.Fake{
+			Segments: []transcribe.Segment{{Text: "This video was transcribed by the fake transcriber."}},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown TRANSCRIBER %q: %w", backend, errUsage)
+	}
+}
+
+// failureFilter are the flags that select failures, shared by the failures subcommands.
+type failureFilter struct {
+	flags     *flag.FlagSet
+	channel   *string
+	typ       *string
+	olderThan *time.Duration
+	dead      *bool
+}
+
+func newFailureFilter(flags *flag.FlagSet) *failureFilter {
+	return &failureFilter{
+		flags:     flags,
+		channel:   flags.String("channel", "", "only failures of this channel, by ID or @handle"),
+		typ:       flags.String("type", "", "only failures of this type: "+failureTypes()),
+		olderThan: flags.Duration("older-than", 0, "only failures created longer than this ago, for example 72h"),
+		dead:      flags.Bool("dead", false, "only dead failures, or with --dead=false only failures that are still attempted"),
+	}
+}
+
+// failureTypes returns the known failure types, for usage messages.
+func failureTypes() string {
+	types := make([]string, len(store.FailureTypes))
+	for i, t := range store.FailureTypes {
+		types[i] = string(t)
+	}
+
+	return strings.Join(types, ", ")
+}
+
+func isFailureType(typ string) bool {
+	for _, t := range store.FailureTypes {
+		if string(t) == typ {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isSet returns whether any of the filter flags are given.
+func (f *failureFilter) isSet() bool {
+	set := false
+	f.flags.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "channel", "type", "older-than", "dead":
+			set = true
+		}
+	})
+	return set
+}
+
+func (f *failureFilter) params(ctx context.Context, db store.Store, max int) (store.ListFailuresParams, error) {
+	params := store.ListFailuresParams{MaxResults: int32(max)}
+
+	if *f.channel != "" {
+		ch, err := channelByIdOrHandle(ctx, db, *f.channel)
+		if err != nil {
+			return params, err
+		}
+
+		params.ChannelID = sql.NullString{String: ch.ID, Valid: true}
+	}
+
+	if *f.typ != "" {
+		if !isFailureType(*f.typ) {
+			return params, fmt.Errorf("unknown failure type %q: %w", *f.typ, errUsage)
+		}
+
+		params.Type = sql.NullString{String: *f.typ, Valid: true}
+	}
+
+	if *f.olderThan > 0 {
+		params.CreatedBefore = sql.NullTime{Time: time.Now().Add(-*f.olderThan), Valid: true}
+	}
+
+	f.flags.Visit(func(fl *flag.Flag) {
+		if fl.Name == "dead" {
+			params.Dead = sql.NullBool{Bool: *f.dead, Valid: true}
+		}
+	})
+
+	return params, nil
+}
+
+// selectFailures returns the failures with the given IDs, or the failures matching the filter if there are none.
+// To prevent accidentally selecting the whole queue, either IDs or a filter is required.
+func (f *failureFilter) selectFailures(ctx context.Context, db store.Store, ids []string) ([]store.Failure, error) {
+	if len(ids) > 0 {
+		var res []store.Failure
+		for _, arg := range ids {
+			id, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid failure ID %q: %w", arg, errUsage)
+			}
+
+			failure, err := failureByID(ctx, db, id)
+			if err != nil {
+				return nil, err
+			}
+
+			res = append(res, failure)
+		}
+
+		return res, nil
+	}
+
+	if !f.isSet() {
+		f.flags.Usage()
+		return nil, fmt.Errorf("failure IDs or filter flags are required: %w", errUsage)
+	}
+
+	params, err := f.params(ctx, db, math.MaxInt32)
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := db.ListFailures(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving failures: %w", err)
+	}
+
+	return selected, nil
+}
+
+func failuresList(ctx context.Context, c *command, args []string) error {
+	flags := c.flags()
+	filter := newFailureFilter(flags)
+	limit := flags.Int("limit", 50, "maximum amount of failures")
+	if err := c.parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	db, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	params, err := filter.params(ctx, db, *limit)
+	if err != nil {
+		return err
+	}
+
+	selected, err := db.ListFailures(ctx, params)
+	if err != nil {
+		return fmt.Errorf("retrieving failures: %w", err)
+	}
+
+	w := newTabWriter()
+	fmt.Fprintln(w, "ID\tCHANNEL\tTYPE\tDATA\tPRIORITY\tAGE\tATTEMPTS\tSTATE\tLAST ERROR")
+	for _, f := range selected {
+		fmt.Fprintf(
+			w,
+			"%d\t%s\t%s\t%s\t%.1f\t%s\t%d\t%s\t%s\n",
+			f.ID,
+			f.ChannelID,
+			f.Type,
+			truncate(f.Data, 20),
+			f.Priority,
+			time.Since(f.CreatedAt).Round(time.Hour),
+			f.Attempts,
+			f.State(),
+			truncate(f.LastError, 60),
+		)
+	}
+
+	return w.Flush()
+}
+
+func failuresShow(ctx context.Context, c *command, args []string) error {
+	flags := c.flags()
+	if err := c.parse(flags, args, 1); err != nil {
+		return err
+	}
+
+	id, err := strconv.ParseInt(flags.Arg(0), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid failure ID %q: %w", flags.Arg(0), errUsage)
+	}
+
+	db, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	f, err := failureByID(ctx, db, id)
+	if err != nil {
+		return err
+	}
+
+	w := newTabWriter()
+	fmt.Fprintf(w, "ID\t%d\n", f.ID)
+	fmt.Fprintf(w, "Channel\t%s\n", f.ChannelID)
+	fmt.Fprintf(w, "Type\t%s\n", f.Type)
+	fmt.Fprintf(w, "Data\t%s\n", f.Data)
+	fmt.Fprintf(w, "Priority\t%.1f\n", f.Priority)
+	fmt.Fprintf(w, "Created\t%s\n", f.CreatedAt.Format(time.DateTime))
+	fmt.Fprintf(w, "Updated\t%s\n", f.UpdatedAt.Format(time.DateTime))
+	fmt.Fprintf(w, "Attempts\t%d\n", f.Attempts)
+	fmt.Fprintf(w, "State\t%s\n", f.State())
+	fmt.Fprintf(w, "Next attempt\t%s\n", f.NextAttemptAt.Format(time.DateTime))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if f.LastError != "" {
+		fmt.Printf("\nLast error:\n%s\n", f.LastError)
+	}
+
+	return nil
+}
+
+func failuresRetry(ctx context.Context, c *command, args []string) error {
+	flags := c.flags()
+	filter := newFailureFilter(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	selected, err := filter.selectFailures(ctx, db, flags.Args())
+	if err != nil {
+		return err
+	}
+
+	if err := db.Tx(ctx, func(q store.Querier) error {
+		for _, f := range selected {
+			if err := q.RetryFailure(ctx, f.ID); err != nil {
+				return fmt.Errorf("retrying failure %d: %w", f.ID, err)
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	log.Printf("[INFO]: %d failures will be retried on the next run", len(selected))
+	return nil
+}
+
+func failuresDrop(ctx context.Context, c *command, args []string) error {
+	flags := c.flags()
+	filter := newFailureFilter(flags)
+	yes := flags.Bool("yes", false, "actually remove the failures")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	selected, err := filter.selectFailures(ctx, db, flags.Args())
+	if err != nil {
+		return err
+	}
+
+	return dropFailures(ctx, db, selected, *yes)
+}
+
+func failuresBump(ctx context.Context, c *command, args []string) error {
+	flags := c.flags()
+	channel := flags.String("channel", "", "bump the failures of this channel, by ID or @handle")
+	video := flags.String("video", "", "bump the failures of this video ID")
+	if err := c.parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	if (*channel == "") == (*video == "") {
+		return fmt.Errorf("either --channel or --video is required: %w", errUsage)
+	}
+
+	db, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	params := store.BumpFailuresParams{Data: sql.NullString{String: *video, Valid: *video != ""}}
+	if *channel != "" {
+		ch, err := channelByIdOrHandle(ctx, db, *channel)
+		if err != nil {
+			return err
+		}
+
+		params.ChannelID = sql.NullString{String: ch.ID, Valid: true}
+	}
+
+	n, err := db.BumpFailures(ctx, params)
+	if err != nil {
+		return fmt.Errorf("bumping failures: %w", err)
+	}
+
+	log.Printf("[INFO]: Moved %d failures to the front of the queue", n)
+	return nil
+}
+
+func failuresPurge(ctx context.Context, c *command, args []string) error {
+	flags := c.flags()
+	filter := newFailureFilter(flags)
+	yes := flags.Bool("yes", false, "actually remove the failures")
+	if err := c.parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	db, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	params, err := filter.params(ctx, db, math.MaxInt32)
+	if err != nil {
+		return err
+	}
+	params.Dead = sql.NullBool{Bool: true, Valid: true}
+
+	selected, err := db.ListFailures(ctx, params)
+	if err != nil {
+		return fmt.Errorf("retrieving failures: %w", err)
+	}
+
+	return dropFailures(ctx, db, selected, *yes)
+}
+
+func dropFailures(ctx context.Context, db store.Store, selected []store.Failure, yes bool) error {
+	if !yes {
+		log.Printf("[INFO]: This removes %d failures, run again with --yes to confirm", len(selected))
+		return nil
+	}
+
+	if err := db.Tx(ctx, func(q store.Querier) error {
+		for _, f := range selected {
+			if err := q.DeleteFailure(ctx, f.ID); err != nil {
+				return fmt.Errorf("deleting failure %d: %w", f.ID, err)
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	log.Printf("[INFO]: Removed %d failures", len(selected))
+	return nil
+}
+
+func failureByID(ctx context.Context, db store.Store, id int64) (store.Failure, error) {
+	failure, err := db.Failure(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return failure, fmt.Errorf("failure %d does not exist", id)
+		}
+
+		return failure, fmt.Errorf("retrieving failure %d: %w", id, err)
+	}
+
+	return failure, nil
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/index"
+	"github.com/laytan/youtupedia/internal/quality"
+	"github.com/laytan/youtupedia/internal/store"
+)
+
+var indexCmd = &command{
+	name: "index",
+	args: "<channel-id>",
+	description: "Index the new videos of a channel, adding the channel if it is new.\n" +
+		"Videos without captions, the episodes of podcasts, see channels add-feed,\n" +
+		"and the files of media libraries, see index-dir, are added to the failures queue.",
+	run: func(ctx context.Context, c *command, args []string) error {
+		flags := c.flags()
+		since := flags.String("since", "", "stop at videos published before this date (YYYY-MM-DD)")
+		limit := flags.Int("limit", 0, "stop after this amount of videos, 0 for no limit")
+		dryRun := flags.Bool("dry-run", false, "only log the videos that would be indexed")
+		if err := c.parse(flags, args, 1); err != nil {
+			return err
+		}
+
+		opts := index.ChannelOptions{Limit: *limit, DryRun: *dryRun}
+		var err error
+		if opts.Since, err = parseDate(*since); err != nil {
+			return fmt.Errorf("parsing --since: %w", err)
+		}
+
+		db, err := openStore()
+		if err != nil {
+			return err
+		}
+
+		indexer, err := newIndexer(db)
+		if err != nil {
+			return err
+		}
+
+		id := flags.Arg(0)
+		channel, err := indexer.Channel(ctx, id)
+		if err != nil {
+			return fmt.Errorf("getting channel %q: %w", id, err)
+		}
+
+		log.Printf("[INFO]: Index for channel %q", channel.Title)
+		if err := indexer.IndexChannel(ctx, channel, opts); err != nil {
+			return fmt.Errorf("indexing channel %q: %w", channel.ID, err)
+		}
+
+		log.Printf("[INFO]: Finished indexing %q", id)
+		return nil
+	},
+}
+
+var indexDirCmd = &command{
+	name: "index-dir",
+	args: "<path>",
+	description: "Index a directory of audio and video files, adding it as a channel if it is new.\n" +
+		"Files are identified by a hash of their content, new files are added to the failures queue to be transcribed by whisper.\n" +
+		"The web interface plays the files at the matched timestamps.",
+	run: func(ctx context.Context, c *command, args []string) error {
+		flags := c.flags()
+		handle := flags.String("handle", "", "handle of the channel in URLs, like @talks, defaults to the name of the directory")
+		since := flags.String("since", "", "skip files modified before this date (YYYY-MM-DD)")
+		limit := flags.Int("limit", 0, "stop after this amount of files, 0 for no limit")
+		dryRun := flags.Bool("dry-run", false, "only log the files that would be queued")
+		if err := c.parse(flags, args, 1); err != nil {
+			return err
+		}
+
+		opts := index.ChannelOptions{Limit: *limit, DryRun: *dryRun}
+		var err error
+		if opts.Since, err = parseDate(*since); err != nil {
+			return fmt.Errorf("parsing --since: %w", err)
+		}
+
+		db, err := openStore()
+		if err != nil {
+			return err
+		}
+
+		indexer, err := newIndexer(db)
+		if err != nil {
+			return err
+		}
+
+		dir := flags.Arg(0)
+		channel, err := indexer.AddDirectory(ctx, dir, *handle)
+		if err != nil {
+			return fmt.Errorf("adding directory %q: %w", dir, err)
+		}
+
+		log.Printf("[INFO]: Index directory %q as %q (%s)", channel.Directory, channel.Title, channel.CustomUrl)
+		if err := indexer.IndexChannel(ctx, channel, opts); err != nil {
+			return fmt.Errorf("indexing directory %q: %w", channel.Directory, err)
+		}
+
+		log.Printf("[INFO]: Finished indexing %q, run `failures run` to transcribe the queued files", channel.Directory)
+		return nil
+	},
+}
+
+var reindexCmd = &command{
+	name: "reindex",
+	description: "Rebuild the searchable data of videos from their stored transcripts.\n" +
+		"Only videos stemmed with an outdated stemmer are rebuilt, unless --force is given.",
+	run: func(ctx context.Context, c *command, args []string) error {
+		flags := c.flags()
+		channelId := flags.String("channel", "", "only reindex videos of this channel ID")
+		videoId := flags.String("video", "", "only reindex this video ID")
+		force := flags.Bool("force", false, "also reindex videos that are up to date")
+		if err := c.parse(flags, args, 0); err != nil {
+			return err
+		}
+
+		db, err := openStore()
+		if err != nil {
+			return err
+		}
+
+		n, err := index.Reindex(ctx, db, index.ReindexOptions{
+			ChannelID: *channelId,
+			VideoID:   *videoId,
+			Force:     *force,
+		})
+		if err != nil {
+			return fmt.Errorf("reindexing: %w", err)
+		}
+
+		log.Printf("[INFO]: Finished reindexing %d videos", n)
+		return nil
+	},
+}
+
+var retranscribeCmd = &command{
+	name: "retranscribe",
+	args: "<@handle|channel-id>",
+	description: "Queue videos of a channel to be transcribed again by `failures run`.\n" +
+		"Set the whisper model of the channel first, see `channels settings`, videos already transcribed by it are skipped.\n" +
+		"Without --type, all videos transcribed by whisper are queued.",
+	run: func(ctx context.Context, c *command, args []string) error {
+		flags := c.flags()
+		typ := flags.String("type", "", "only videos with this transcript type, for example whisper_cpp:base.en")
+		videoId := flags.String("video", "", "only this video ID")
+		if err := c.parse(flags, args, 1); err != nil {
+			return err
+		}
+
+		db, err := openStore()
+		if err != nil {
+			return err
+		}
+
+		channel, err := channelByIdOrHandle(ctx, db, flags.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		settings, err := store.ChannelSettingsOrDefault(ctx, db, channel.ID)
+		if err != nil {
+			return fmt.Errorf("retrieving settings of %q: %w", channel.ID, err)
+		}
+
+		n, err := db.QueueRetranscribes(ctx, store.QueueRetranscribesParams{
+			FailureType:    string(store.FailureTypeRetranscribe),
+			Priority:       index.Priority(settings, time.Time{}, nil),
+			ChannelID:      channel.ID,
+			TranscriptType: sql.NullString{String: *typ, Valid: *typ != ""},
+			VideoID:        sql.NullString{String: *videoId, Valid: *videoId != ""},
+		})
+		if err != nil {
+			return fmt.Errorf("queueing videos of %q: %w", channel.ID, err)
+		}
+
+		log.Printf("[INFO]: Queued %d videos of %q to be transcribed again", n, channel.Title)
+		return nil
+	},
+}
+
+var upgradeCmd = &command{
+	name: "upgrade",
+	args: "<@handle|channel-id>",
+	description: "Queue videos of a channel with automatic captions to be transcribed by `failures run`.\n" +
+		"The automatic captions are only replaced if the transcript of whisper is better, the old captions are kept, see `videos versions`.\n" +
+		"Without --all, only captions that are low quality by their punctuation and repetition are queued.\n" +
+		"Captions that were kept over a whisper transcript before are not queued again.",
+	run: func(ctx context.Context, c *command, args []string) error {
+		flags := c.flags()
+		all := flags.Bool("all", false, "queue all videos with automatic captions")
+		videoId := flags.String("video", "", "only this video ID")
+		dryRun := flags.Bool("dry-run", false, "print the quality of the captions without queueing")
+		if err := c.parse(flags, args, 1); err != nil {
+			return err
+		}
+
+		db, err := openStore()
+		if err != nil {
+			return err
+		}
+
+		channel, err := channelByIdOrHandle(ctx, db, flags.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		settings, err := store.ChannelSettingsOrDefault(ctx, db, channel.ID)
+		if err != nil {
+			return fmt.Errorf("retrieving settings of %q: %w", channel.ID, err)
+		}
+
+		setting := store.UpgradeCaptionsLowQuality
+		if *all {
+			setting = store.UpgradeCaptionsAlways
+		}
+
+		videos, err := db.VideosOfChannel(ctx, channel.ID)
+		if err != nil {
+			return fmt.Errorf("retrieving videos of %q: %w", channel.ID, err)
+		}
+
+		w := newTabWriter()
+		fmt.Fprintln(w, "VIDEO\tWORDS\tPUNCTUATION\tREPETITION\tUPGRADE")
+		var queued int
+		for _, video := range videos {
+			if store.TranscriptType(video.TranscriptType) != store.TubeAuto || (*videoId != "" && video.ID != *videoId) {
+				continue
+			}
+
+			transcripts, err := db.TranscriptsOfVideo(ctx, video.ID)
+			if err != nil {
+				return fmt.Errorf("retrieving transcripts of %q: %w", video.ID, err)
+			}
+
+			lines := make([]string, 0, len(transcripts))
+			for _, t := range transcripts {
+				lines = append(lines, t.Text)
+			}
+
+			m := quality.Measure(lines)
+			reason := index.UpgradeReason(setting, lines)
+			fmt.Fprintf(w, "%s\t%d\t%.3f\t%.3f\t%s\n", video.ID, m.Words, m.Punctuation, m.Repetition, reason)
+			if reason == "" || *dryRun {
+				continue
+			}
+
+			ok, err := index.QueueUpgrade(ctx, db, channel.ID, video.ID, index.Priority(settings, video.PublishedAt, nil))
+			if err != nil {
+				return fmt.Errorf("queueing upgrade of %q: %w", video.ID, err)
+			}
+
+			if ok {
+				queued++
+			}
+		}
+
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		log.Printf("[INFO]: Queued %d videos of %q to be upgraded", queued, channel.Title)
+		return nil
+	},
+}
+
+var resegmentCmd = &command{
+	name: "resegment",
+	description: "Deduplicate and group the stored automatic captions of all videos into segments.\n" +
+		"Videos are only segmented once, new videos are segmented when they are indexed.",
+	run: func(ctx context.Context, c *command, args []string) error {
+		if err := c.parse(c.flags(), args, 0); err != nil {
+			return err
+		}
+
+		db, err := openStore()
+		if err != nil {
+			return err
+		}
+
+		indexer, err := newIndexer(db)
+		if err != nil {
+			return err
+		}
+
+		if err := indexer.ResegmentVideos(ctx); err != nil {
+			return fmt.Errorf("resegmenting auto captions: %w", err)
+		}
+
+		log.Println("[INFO]: Finished resegmenting")
+		return nil
+	},
+}
+
+func newIndexer(db *store.DB) (*index.Indexer, error) {
+	yt, err := youtube()
+	if err != nil {
+		return nil, err
+	}
+
+	return index.New(db, yt, index.Options{}), nil
+}
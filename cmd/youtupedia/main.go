@@ -3,77 +3,197 @@ package main
 import (
 	"context"
 	"database/sql"
-	_ "embed"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"os"
+	"text/tabwriter"
 
-	"github.com/laytan/youtupedia/internal/failures"
-	"github.com/laytan/youtupedia/internal/index"
-	"github.com/laytan/youtupedia/internal/search"
 	"github.com/laytan/youtupedia/internal/store"
 	"github.com/laytan/youtupedia/internal/tube"
-	"github.com/laytan/youtupedia/internal/youtupedia"
 	_ "github.com/lib/pq"
 )
 
 var (
-	queries *store.Queries
-	db      *sql.DB
-	yt      *tube.Client
-	ytKey   = os.Getenv("YT_KEY")
-	pgDsn   = os.Getenv("POSTGRES_DSN")
+	ytKey = os.Getenv("YT_KEY")
+	pgDsn = os.Getenv("POSTGRES_DSN")
 )
 
+var root = &command{
+	name:        "youtupedia",
+	description: "Search YouTube channels through their captions.\nWithout a command, the web interface is served on the default port.",
+	subcommands: []*command{
+		serveCmd,
+		searchCmd,
+		channelsCmd,
+		videosCmd,
+		indexCmd,
+		indexDirCmd,
+		reindexCmd,
+		retranscribeCmd,
+		upgradeCmd,
+		resegmentCmd,
+		failuresCmd,
+		submissionsCmd,
+		contributorsCmd,
+		statsCmd,
+	},
+}
+
 func main() {
-	if ytKey == "" {
-		panic("YT_KEY environment variable must be set")
+	args := os.Args[1:]
+	if len(args) == 0 {
+		args = []string{"serve"}
 	}
 
-	if pgDsn == "" {
-		panic("POSTGRES_DSN environment variable must be set")
+	root.link(nil)
+	if err := root.execute(context.Background(), args); err != nil {
+		log.Fatalf("[ERROR]: %v", err)
 	}
+}
 
-	ctx := context.Background()
-	d, err := sql.Open("postgres", pgDsn)
-	if err != nil {
-		log.Fatalf("[ERROR]: opening database: %v", err)
+// command is a command, or a group of subcommands, of the CLI.
+type command struct {
+	name        string
+	args        string // Positional arguments, shown in the usage.
+	description string
+
+	// run is called with the arguments after the name of the command,
+	// it should parse them using the flag set of c.flags().
+	run         func(ctx context.Context, c *command, args []string) error
+	subcommands []*command
+
+	parent *command
+}
+
+var errUsage = errors.New("invalid usage")
+
+func (c *command) link(parent *command) {
+	c.parent = parent
+	for _, sub := range c.subcommands {
+		sub.link(c)
 	}
+}
 
-	db = d
-	queries = store.New(db)
-	yt = &tube.Client{Key: ytKey}
+func (c *command) path() string {
+	if c.parent == nil {
+		return c.name
+	}
 
-	failures.Queries = queries
-	failures.Db = db
-	failures.Yt = yt
+	return c.parent.path() + " " + c.name
+}
 
-	index.Queries = queries
-	index.Db = db
-	index.Yt = yt
+func (c *command) execute(ctx context.Context, args []string) error {
+	if len(c.subcommands) == 0 {
+		return c.run(ctx, c, args)
+	}
 
-	search.Queries = queries
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		c.usage(nil)
+		return nil
+	}
 
-	if len(os.Args) > 2 && os.Args[1] == "index" {
-		id := os.Args[2]
-		channel, err := index.Channel(ctx, id)
-		if err != nil {
-			log.Panicf("[ERROR]: Getting channel %q: %v", id, err)
+	for _, sub := range c.subcommands {
+		if sub.name == args[0] {
+			return sub.execute(ctx, args[1:])
 		}
+	}
+
+	c.usage(nil)
+	return fmt.Errorf("unknown command %q: %w", c.path()+" "+args[0], errUsage)
+}
+
+// flags returns a flag set for the command, printing the usage of the command on errors and -h.
+func (c *command) flags() *flag.FlagSet {
+	flags := flag.NewFlagSet(c.path(), flag.ExitOnError)
+	flags.Usage = func() { c.usage(flags) }
+	return flags
+}
+
+// parse parses the arguments into the flags, and checks that there are n positional arguments.
+func (c *command) parse(flags *flag.FlagSet, args []string, n int) error {
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != n {
+		flags.Usage()
+		return fmt.Errorf("%s expects %d argument(s), got %d: %w", c.path(), n, flags.NArg(), errUsage)
+	}
+
+	return nil
+}
 
-		log.Printf("[INFO]: Index for channel %q", channel.Title)
-		if err := index.IndexChannel(ctx, channel); err != nil {
-			log.Panicf("[ERROR]: Indexing channel %q: %v", channel.ID, err)
+func (c *command) usage(flags *flag.FlagSet) {
+	out := os.Stderr
+
+	hasFlags := false
+	if flags != nil {
+		flags.VisitAll(func(*flag.Flag) { hasFlags = true })
+	}
+
+	usage := c.path()
+	if len(c.subcommands) > 0 {
+		usage += " <command>"
+	}
+	if hasFlags {
+		usage += " [flags]"
+	}
+	if c.args != "" {
+		usage += " " + c.args
+	}
+
+	fmt.Fprintf(out, "Usage: %s\n\n%s\n", usage, c.description)
+
+	if len(c.subcommands) > 0 {
+		fmt.Fprint(out, "\nCommands:\n")
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		for _, sub := range c.subcommands {
+			fmt.Fprintf(w, "  %s\t%s\n", sub.name, firstLine(sub.description))
 		}
+		w.Flush()
+	}
 
-		log.Printf("[INFO]: Finished indexing %q", id)
-	} else if len(os.Args) > 1 && os.Args[1] == "failures" { // TODO: allow passing in channel.
-		if err := failures.WhisperNoCaptionFailures(ctx); err != nil {
-			log.Panicf("[ERROR]: Processing no caption failures: %v", err)
+	if hasFlags {
+		fmt.Fprint(out, "\nFlags:\n")
+		flags.PrintDefaults()
+	}
+}
+
+func firstLine(s string) string {
+	for i, ch := range s {
+		if ch == '\n' {
+			return s[:i]
 		}
+	}
+
+	return s
+}
+
+// openStore opens the database at POSTGRES_DSN.
+func openStore() (*store.DB, error) {
+	if pgDsn == "" {
+		return nil, errors.New("POSTGRES_DSN environment variable must be set")
+	}
+
+	d, err := sql.Open("postgres", pgDsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+
+	return store.NewDB(d), nil
+}
 
-		log.Println("[INFO]: Finished failures processing")
-	} else {
-		youtupedia.Queries = queries
-		youtupedia.Start(ctx)
+// youtube returns a YouTube API client using YT_KEY.
+func youtube() (*tube.Client, error) {
+	if ytKey == "" {
+		return nil, errors.New("YT_KEY environment variable must be set")
 	}
+
+	return &tube.Client{Key: ytKey}, nil
+}
+
+func newTabWriter() *tabwriter.Writer {
+	return tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 }
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/search"
+	"github.com/laytan/youtupedia/internal/store"
+)
+
+var searchCmd = &command{
+	name: "search",
+	args: "<@handle|channel-id> <query>",
+	description: "Search the captions of a channel.\n" +
+		"A speaker:<label> in the query only matches lines of that speaker, in transcripts that are diarized.\n" +
+		"The --mode decides how the words match: the exact phrase, all words anywhere in a video,\n" +
+		"all words within a few consecutive lines, or any of the words.\n" +
+		"Results are written to stdout, logs to stderr, so the output can be piped into other tools.\n" +
+		"Videos stemmed with an outdated stemmer may not match, run reindex after upgrading.",
+	run: func(ctx context.Context, c *command, args []string) error {
+		flags := c.flags()
+		limit := flags.Int("limit", 0, "maximum amount of videos, 0 for the default of 100")
+		since := flags.String("since", "", "only videos published on or after this date (YYYY-MM-DD)")
+		until := flags.String("until", "", "only videos published before this date (YYYY-MM-DD)")
+		meta := flags.Bool("meta", false, "also search titles and descriptions")
+		mode := flags.String("mode", "phrase", "how the words match: phrase, all, near or any")
+		format := flags.String("format", "table", "output format: table, json or ndjson")
+		if err := c.parse(flags, args, 2); err != nil {
+			return err
+		}
+
+		query := search.Query{
+			Metadata: *meta,
+			Limit:    *limit,
+		}
+		query.Text, query.Speaker = search.SplitSpeaker(flags.Arg(1))
+		if query.Text == "" {
+			return fmt.Errorf("query %q has nothing to search for besides the speaker: %w", flags.Arg(1), errUsage)
+		}
+
+		var err error
+		if query.Mode, err = search.ParseMode(*mode); err != nil {
+			return fmt.Errorf("unknown mode %q: %w", *mode, errUsage)
+		}
+		if query.Since, err = parseDate(*since); err != nil {
+			return fmt.Errorf("parsing --since: %w", err)
+		}
+		if query.Until, err = parseDate(*until); err != nil {
+			return fmt.Errorf("parsing --until: %w", err)
+		}
+
+		var write func(hits []hit) error
+		switch *format {
+		case "table":
+			write = writeHitsTable
+		case "json":
+			write = writeHitsJSON
+		case "ndjson":
+			write = writeHitsNDJSON
+		default:
+			return fmt.Errorf("unknown format %q: %w", *format, errUsage)
+		}
+
+		db, err := openStore()
+		if err != nil {
+			return err
+		}
+
+		channel, err := channelByIdOrHandle(ctx, db, flags.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		results, err := search.New(db, search.Options{}).Channel(ctx, &channel, query)
+		if err != nil {
+			return fmt.Errorf("searching: %w", err)
+		}
+
+		return write(hits(results))
+	},
+}
+
+// hit is a single search result, a caption line or a match in the title or description.
+type hit struct {
+	VideoID     string    `json:"video_id"`
+	Title       string    `json:"title"`
+	PublishedAt time.Time `json:"published_at"`
+	Match       string    `json:"match"` // "caption", "title" or "description".
+	Seconds     int32     `json:"seconds"`
+	Timestamp   string    `json:"timestamp"` // Empty when not matching a caption.
+	Quote       string    `json:"quote"`
+	Speaker     string    `json:"speaker,omitempty"` // Empty when not diarized.
+	URL         string    `json:"url"`
+}
+
+func hits(results []search.Result) []hit {
+	var hits []hit
+	for _, r := range results {
+		base := hit{
+			VideoID:     r.Video.ID,
+			Title:       r.Video.Title,
+			PublishedAt: r.Video.PublishedAt,
+			URL:         r.Video.URL(0),
+		}
+
+		if r.InTitle {
+			h := base
+			h.Match = "title"
+			h.Quote = r.Video.Title
+			hits = append(hits, h)
+		}
+
+		if r.InDescription {
+			h := base
+			h.Match = "description"
+			hits = append(hits, h)
+		}
+
+		for _, t := range r.Results {
+			h := base
+			h.Match = "caption"
+			h.Seconds = t.Start
+			h.Timestamp = t.StartDuration().String()
+			h.Quote = t.Text
+			h.Speaker = r.Speaker(t.ID)
+			h.URL = r.Video.URL(t.StartDuration())
+			hits = append(hits, h)
+		}
+	}
+
+	return hits
+}
+
+func writeHitsTable(hits []hit) error {
+	w := newTabWriter()
+	fmt.Fprintln(w, "TITLE\tDATE\tAT\tQUOTE\tURL")
+	for _, h := range hits {
+		at := h.Timestamp
+		if h.Match != "caption" {
+			at = h.Match
+		}
+
+		quote := h.Quote
+		if h.Speaker != "" {
+			quote = h.Speaker + ": " + quote
+		}
+
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\t%s\t%s\n",
+			truncate(h.Title, 50),
+			h.PublishedAt.Format(time.DateOnly),
+			at,
+			truncate(quote, 80),
+			h.URL,
+		)
+	}
+
+	return w.Flush()
+}
+
+func writeHitsJSON(hits []hit) error {
+	if hits == nil {
+		hits = []hit{}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(hits)
+}
+
+func writeHitsNDJSON(hits []hit) error {
+	enc := json.NewEncoder(os.Stdout)
+	for _, h := range hits {
+		if err := enc.Encode(h); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+
+	return string(runes[:n-3]) + "..."
+}
+
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(time.DateOnly, value)
+}
+
+// channelByIdOrHandle retrieves the channel by its custom url when it starts with an @,
+// and by its ID otherwise.
+func channelByIdOrHandle(ctx context.Context, db store.Store, value string) (store.Channel, error) {
+	var channel store.Channel
+	var err error
+	if strings.HasPrefix(value, "@") {
+		channel, err = db.ChannelByUrl(ctx, value)
+	} else {
+		channel, err = db.Channel(ctx, value)
+	}
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return channel, fmt.Errorf("channel %q does not exist", value)
+		}
+
+		return channel, fmt.Errorf("retrieving channel %q: %w", value, err)
+	}
+
+	return channel, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/laytan/youtupedia/internal/index"
+	"github.com/laytan/youtupedia/internal/search"
+	"github.com/laytan/youtupedia/internal/youtupedia"
+)
+
+var serveCmd = &command{
+	name:        "serve",
+	description: "Serve the web interface.\nVideos stemmed with an outdated stemmer are reindexed in the background, see reindex.",
+	run: func(ctx context.Context, c *command, args []string) error {
+		flags := c.flags()
+		port := flags.Int("port", 8080, "port to listen on")
+		admin := flags.Bool("admin", false, "serve the read-only admin pages under /admin, these are not authenticated")
+		contributions := flags.Bool("contributions", false, "serve /contribute, where contributors submit transcripts for videos without captions")
+		if err := c.parse(flags, args, 0); err != nil {
+			return err
+		}
+
+		db, err := openStore()
+		if err != nil {
+			return err
+		}
+
+		indexer, err := newIndexer(db)
+		if err != nil {
+			return err
+		}
+
+		// Videos stemmed with an outdated stem.Version would not match queries reliably,
+		// they are reindexed in the background while serving, this is a no-op once all videos are up to date.
+		go func() {
+			reindexed, err := index.Reindex(ctx, db, index.ReindexOptions{})
+			if err != nil {
+				log.Printf("[ERROR]: reindexing outdated videos: %v", err)
+				return
+			}
+
+			if reindexed > 0 {
+				log.Printf("[INFO]: Reindexed %d outdated videos", reindexed)
+			}
+		}()
+
+		searcher := search.New(db, search.Options{})
+		youtupedia.New(db, searcher, indexer, youtupedia.Options{Admin: *admin, Contributions: *contributions}).Start(ctx, fmt.Sprintf(":%d", *port))
+		return nil
+	},
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/laytan/youtupedia/internal/stem"
+)
+
+var statsCmd = &command{
+	name:        "stats",
+	description: "Show the amount of indexed channels, videos, transcripts and failures.",
+	run: func(ctx context.Context, c *command, args []string) error {
+		if err := c.parse(c.flags(), args, 0); err != nil {
+			return err
+		}
+
+		db, err := openStore()
+		if err != nil {
+			return err
+		}
+
+		stats, err := db.Stats(ctx, stem.Version)
+		if err != nil {
+			return fmt.Errorf("retrieving stats: %w", err)
+		}
+
+		videos, err := db.VideoCountsByTranscriptType(ctx)
+		if err != nil {
+			return fmt.Errorf("counting videos: %w", err)
+		}
+
+		failures, err := db.FailureCountsByType(ctx)
+		if err != nil {
+			return fmt.Errorf("counting failures: %w", err)
+		}
+
+		w := newTabWriter()
+		fmt.Fprintf(w, "Channels\t%d\n", stats.Channels)
+		fmt.Fprintf(w, "Videos\t%d\n", stats.Videos)
+		for _, v := range videos {
+			fmt.Fprintf(w, "  %s\t%d\n", v.TranscriptType, v.Count)
+		}
+		fmt.Fprintf(w, "Outdated videos\t%d\n", stats.OutdatedVideos)
+		fmt.Fprintf(w, "Transcript lines\t%d\n", stats.Transcripts)
+		fmt.Fprintf(w, "Failures\t%d\n", stats.Failures)
+		for _, f := range failures {
+			fmt.Fprintf(w, "  %s\t%d\n", f.Type, f.Count)
+		}
+
+		return w.Flush()
+	},
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/store"
+)
+
+var contributorsCmd = &command{
+	name:        "contributors",
+	description: "Manage who can submit transcripts on /contribute, see `serve --contributions`.",
+	subcommands: []*command{
+		{
+			name: "add",
+			args: "<name>",
+			description: "Add a contributor, printing the token they sign in with.\n" +
+				"The token is only stored hashed, so it can't be shown again.",
+			run: contributorsAdd,
+		},
+		{
+			name:        "list",
+			description: "List the contributors.",
+			run:         contributorsList,
+		},
+		{
+			name:        "disable",
+			args:        "<name>",
+			description: "Prevent a contributor from signing in and submitting, their submissions are kept.",
+			run:         contributorsDisable,
+		},
+	},
+}
+
+func contributorsAdd(ctx context.Context, c *command, args []string) error {
+	flags := c.flags()
+	if err := c.parse(flags, args, 1); err != nil {
+		return err
+	}
+
+	db, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	token, hash, err := store.NewContributorToken()
+	if err != nil {
+		return err
+	}
+
+	contributor, err := db.CreateContributor(ctx, store.CreateContributorParams{
+		Name:      flags.Arg(0),
+		TokenHash: hash,
+	})
+	if err != nil {
+		return fmt.Errorf("creating contributor %q: %w", flags.Arg(0), err)
+	}
+
+	log.Printf("[INFO]: Added contributor %q, they sign in with this token:", contributor.Name)
+	fmt.Println(token)
+	return nil
+}
+
+func contributorsList(ctx context.Context, c *command, args []string) error {
+	if err := c.parse(c.flags(), args, 0); err != nil {
+		return err
+	}
+
+	db, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	contributors, err := db.Contributors(ctx)
+	if err != nil {
+		return fmt.Errorf("retrieving contributors: %w", err)
+	}
+
+	w := newTabWriter()
+	fmt.Fprintln(w, "ID\tNAME\tADDED\tDISABLED")
+	for _, contributor := range contributors {
+		fmt.Fprintf(
+			w,
+			"%d\t%s\t%s\t%t\n",
+			contributor.ID,
+			contributor.Name,
+			contributor.CreatedAt.Format(time.DateOnly),
+			contributor.Disabled,
+		)
+	}
+
+	return w.Flush()
+}
+
+func contributorsDisable(ctx context.Context, c *command, args []string) error {
+	flags := c.flags()
+	if err := c.parse(flags, args, 1); err != nil {
+		return err
+	}
+
+	db, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	n, err := db.DisableContributor(ctx, flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("disabling contributor %q: %w", flags.Arg(0), err)
+	}
+
+	if n == 0 {
+		return fmt.Errorf("contributor %q does not exist", flags.Arg(0))
+	}
+
+	log.Printf("[INFO]: Disabled contributor %q", flags.Arg(0))
+	return nil
+}
+
+var submissionsCmd = &command{
+	name:        "submissions",
+	description: "Moderate the transcripts submitted by contributors.",
+	subcommands: []*command{
+		{
+			name:        "list",
+			description: "List the submissions, oldest first.",
+			run:         submissionsList,
+		},
+		{
+			name:        "show",
+			args:        "<submission-id>",
+			description: "Show a submission, including its parsed lines.",
+			run:         submissionsShow,
+		},
+		{
+			name: "approve",
+			args: "<submission-id>",
+			description: "Index a pending submission as the community transcript of its video, and remove the video from the failures queue.\n" +
+				"Retrieves the video from the YouTube API, costing 1 quota.",
+			run: submissionsApprove,
+		},
+		{
+			name:        "reject",
+			args:        "<submission-id>",
+			description: "Reject a pending submission, recording why.",
+			run:         submissionsReject,
+		},
+	},
+}
+
+func submissionsList(ctx context.Context, c *command, args []string) error {
+	flags := c.flags()
+	status := flags.String("status", string(store.SubmissionPending), "only submissions with this status: pending, approved or rejected, empty for all")
+	limit := flags.Int("limit", 50, "maximum amount of submissions")
+	if err := c.parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	db, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	submissions, err := db.ListSubmissions(ctx, store.ListSubmissionsParams{
+		Status:     sql.NullString{String: *status, Valid: *status != ""},
+		MaxResults: int32(*limit),
+	})
+	if err != nil {
+		return fmt.Errorf("retrieving submissions: %w", err)
+	}
+
+	w := newTabWriter()
+	fmt.Fprintln(w, "ID\tVIDEO\tCHANNEL\tCONTRIBUTOR\tFORMAT\tSUBMITTED\tSTATUS\tREASON")
+	for _, s := range submissions {
+		fmt.Fprintf(
+			w,
+			"%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			s.ID,
+			s.VideoID,
+			s.ChannelID,
+			s.Contributor,
+			s.Format,
+			s.CreatedAt.Format("2006-01-02 15:04"),
+			s.Status,
+			truncate(s.Reason, 60),
+		)
+	}
+
+	return w.Flush()
+}
+
+func submissionsShow(ctx context.Context, c *command, args []string) error {
+	flags := c.flags()
+	if err := c.parse(flags, args, 1); err != nil {
+		return err
+	}
+
+	db, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	s, err := submission(ctx, db, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	lines, err := s.ParseLines()
+	if err != nil {
+		return err
+	}
+
+	w := newTabWriter()
+	fmt.Fprintf(w, "ID\t%d\n", s.ID)
+	fmt.Fprintf(w, "Video\t%s\n", s.VideoID)
+	fmt.Fprintf(w, "Channel\t%s\n", s.ChannelID)
+	fmt.Fprintf(w, "Contributor\t%d\n", s.ContributorID)
+	fmt.Fprintf(w, "Format\t%s\n", s.Format)
+	fmt.Fprintf(w, "Submitted\t%s\n", s.CreatedAt.Format(time.DateTime))
+	fmt.Fprintf(w, "Status\t%s\n", s.Status)
+	fmt.Fprintf(w, "Reason\t%s\n", s.Reason)
+	fmt.Fprintf(w, "Lines\t%d\n", len(lines))
+	fmt.Fprintf(w, "URL\thttps://youtu.be/%s\n", s.VideoID)
+	fmt.Fprintln(w)
+	for _, line := range lines {
+		fmt.Fprintf(w, "%s\t%s\n", time.Duration(line.Start)*time.Second, line.Text)
+	}
+
+	return w.Flush()
+}
+
+func submissionsApprove(ctx context.Context, c *command, args []string) error {
+	flags := c.flags()
+	if err := c.parse(flags, args, 1); err != nil {
+		return err
+	}
+
+	db, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	s, err := submission(ctx, db, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	indexer, err := newIndexer(db)
+	if err != nil {
+		return err
+	}
+
+	if err := indexer.ApproveSubmission(ctx, s.ID); err != nil {
+		return err
+	}
+
+	log.Printf("[INFO]: Approved submission %d, video %q is indexed", s.ID, s.VideoID)
+	return nil
+}
+
+func submissionsReject(ctx context.Context, c *command, args []string) error {
+	flags := c.flags()
+	reason := flags.String("reason", "", "why the submission is rejected (required)")
+	if err := c.parse(flags, args, 1); err != nil {
+		return err
+	}
+
+	if *reason == "" {
+		return fmt.Errorf("--reason is required: %w", errUsage)
+	}
+
+	db, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	s, err := submission(ctx, db, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if store.SubmissionStatus(s.Status) != store.SubmissionPending {
+		return fmt.Errorf("submission %d is %s already", s.ID, s.Status)
+	}
+
+	if err := db.SetSubmissionStatus(ctx, store.SetSubmissionStatusParams{
+		ID:     s.ID,
+		Status: string(store.SubmissionRejected),
+		Reason: *reason,
+	}); err != nil {
+		return fmt.Errorf("rejecting submission %d: %w", s.ID, err)
+	}
+
+	log.Printf("[INFO]: Rejected submission %d", s.ID)
+	return nil
+}
+
+// submission retrieves the submission with the ID in value.
+func submission(ctx context.Context, db store.Store, value string) (store.Submission, error) {
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return store.Submission{}, fmt.Errorf("invalid submission ID %q: %w", value, errUsage)
+	}
+
+	s, err := db.Submission(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return s, fmt.Errorf("submission %d does not exist", id)
+	} else if err != nil {
+		return s, fmt.Errorf("retrieving submission %d: %w", id, err)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/index"
+	"github.com/laytan/youtupedia/internal/store"
+)
+
+var videosCmd = &command{
+	name:        "videos",
+	description: "Inspect and manage indexed videos.",
+	subcommands: []*command{
+		{
+			name:        "show",
+			args:        "<video-id>",
+			description: "Show the details of a video.",
+			run:         videosShow,
+		},
+		{
+			name:        "versions",
+			args:        "<video-id>",
+			description: "List the transcripts that were replaced or rejected by retranscribes and upgrades.",
+			run:         videosVersions,
+		},
+		{
+			name:        "delete",
+			args:        "<video-id>",
+			description: "Delete a video and its transcripts.",
+			run:         videosDelete,
+		},
+		{
+			name:        "reindex",
+			args:        "<video-id>",
+			description: "Rebuild the searchable data of a video from its stored transcripts.",
+			run:         videosReindex,
+		},
+	},
+}
+
+func videosShow(ctx context.Context, c *command, args []string) error {
+	flags := c.flags()
+	transcript := flags.Bool("transcript", false, "also print the transcript lines, with the confidence of whisper")
+	if err := c.parse(flags, args, 1); err != nil {
+		return err
+	}
+
+	db, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	video, err := video(ctx, db, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	transcripts, err := db.TranscriptsOfVideo(ctx, video.ID)
+	if err != nil {
+		return fmt.Errorf("retrieving transcripts: %w", err)
+	}
+
+	w := newTabWriter()
+	fmt.Fprintf(w, "ID\t%s\n", video.ID)
+	fmt.Fprintf(w, "Title\t%s\n", video.Title)
+	fmt.Fprintf(w, "Channel\t%s\n", video.ChannelID)
+	fmt.Fprintf(w, "Published\t%s\n", video.PublishedAt)
+	fmt.Fprintf(w, "Indexed\t%s\n", video.CreatedAt)
+	fmt.Fprintf(w, "Transcript type\t%s\n", video.TranscriptType)
+	fmt.Fprintf(w, "Transcript lines\t%d\n", len(transcripts))
+	fmt.Fprintf(w, "Stem version\t%d\n", video.StemVersion)
+	fmt.Fprintf(w, "URL\t%s\n", video.URL(0))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if *transcript {
+		details, err := db.TranscriptDetailsOfVideo(ctx, video.ID)
+		if err != nil {
+			return fmt.Errorf("retrieving transcript details: %w", err)
+		}
+
+		byID := make(map[int64]store.TranscriptDetail, len(details))
+		for _, d := range details {
+			byID[d.TranscriptID] = d
+		}
+
+		fmt.Println()
+		for _, t := range transcripts {
+			confidence, speaker := "-", "-"
+			if d, ok := byID[t.ID]; ok {
+				confidence = fmt.Sprintf("%.0f%%", d.Confidence*100)
+				if d.Speaker != "" {
+					speaker = d.Speaker
+				}
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", t.StartDuration(), confidence, speaker, t.Text)
+		}
+		return w.Flush()
+	}
+
+	return nil
+}
+
+func videosVersions(ctx context.Context, c *command, args []string) error {
+	flags := c.flags()
+	id := flags.Int64("version", 0, "print the lines of the version with this ID")
+	if err := c.parse(flags, args, 1); err != nil {
+		return err
+	}
+
+	db, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	video, err := video(ctx, db, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	versions, err := db.TranscriptVersionsOfVideo(ctx, video.ID)
+	if err != nil {
+		return fmt.Errorf("retrieving transcript versions: %w", err)
+	}
+
+	w := newTabWriter()
+	if *id == 0 {
+		fmt.Fprintln(w, "ID	CREATED	STATUS	TYPE	LINES	REASON")
+		for _, v := range versions {
+			lines, err := v.ParseLines()
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(
+				w,
+				"%d\t%s\t%s\t%s\t%d\t%s\n",
+				v.ID,
+				v.CreatedAt.Format("2006-01-02 15:04"),
+				v.Status,
+				v.TranscriptType,
+				len(lines),
+				v.Reason,
+			)
+		}
+		return w.Flush()
+	}
+
+	for _, v := range versions {
+		if v.ID != *id {
+			continue
+		}
+
+		lines, err := v.ParseLines()
+		if err != nil {
+			return err
+		}
+
+		for _, line := range lines {
+			fmt.Fprintf(w, "%s\t%s\n", time.Duration(line.Start)*time.Second, line.Text)
+		}
+		return w.Flush()
+	}
+
+	return fmt.Errorf("video %q has no transcript version %d", video.ID, *id)
+}
+
+func videosDelete(ctx context.Context, c *command, args []string) error {
+	flags := c.flags()
+	if err := c.parse(flags, args, 1); err != nil {
+		return err
+	}
+
+	db, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	video, err := video(ctx, db, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	// The transcripts are removed by the database, the foreign key cascades on delete.
+	if err := db.DeleteVideo(ctx, video.ID); err != nil {
+		return fmt.Errorf("deleting video %q: %w", video.ID, err)
+	}
+
+	log.Printf("[INFO]: Deleted video %q", video.Title)
+	return nil
+}
+
+func videosReindex(ctx context.Context, c *command, args []string) error {
+	flags := c.flags()
+	if err := c.parse(flags, args, 1); err != nil {
+		return err
+	}
+
+	db, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	video, err := video(ctx, db, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if _, err := index.Reindex(ctx, db, index.ReindexOptions{VideoID: video.ID, Force: true}); err != nil {
+		return fmt.Errorf("reindexing video %q: %w", video.ID, err)
+	}
+
+	log.Printf("[INFO]: Reindexed video %q", video.Title)
+	return nil
+}
+
+func video(ctx context.Context, db store.Store, id string) (store.Video, error) {
+	video, err := db.Video(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return video, fmt.Errorf("video %q does not exist", id)
+		}
+
+		return video, fmt.Errorf("retrieving video %q: %w", id, err)
+	}
+
+	return video, nil
+}
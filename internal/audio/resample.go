@@ -0,0 +1,197 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// WhisperSampleRate is the sample rate whisper expects.
+const WhisperSampleRate = 16000
+
+// zeroCrossings is the amount of zero crossings on each side of the sinc filter,
+// more is a sharper low-pass filter, but slower.
+const zeroCrossings = 16
+
+// Resample reads the WAV file from src, and writes it as a 16 bit mono WAV file at the sample rate to dst.
+// The input is streamed, so src can be a pipe, like the output of a decoder.
+func Resample(dst io.WriteSeeker, src io.Reader, sampleRate int) error {
+	r, err := NewReader(src)
+	if err != nil {
+		return err
+	}
+
+	w, err := NewWriter(dst, sampleRate)
+	if err != nil {
+		return err
+	}
+
+	f := newFilter(r.Format.SampleRate, sampleRate)
+
+	in := make([]float64, 16*1024)
+	var out []float64
+	for {
+		n, err := r.Read(in)
+		out = f.process(in[:n], out[:0])
+		if werr := w.Write(out); werr != nil {
+			return werr
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := w.Write(f.flush(out[:0])); err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("closing resampled WAV: %w", err)
+	}
+
+	return nil
+}
+
+// filter resamples using a windowed sinc filter, which also low-pass filters when downsampling,
+// so frequencies above the new Nyquist frequency don't alias.
+//
+// The ratio between the rates is exact, so the position of an output sample between
+// two input samples, its phase, repeats, and the filter of each phase is calculated once.
+type filter struct {
+	from, to int // Sample rates, divided by their greatest common divisor.
+	width    int // Input samples on each side of an output sample.
+	kernels  [][]float64
+
+	buf   []float64 // Input samples, buf[0] is input sample base.
+	base  int64
+	next  int64 // Output sample that is calculated next.
+	total int64 // Input samples received.
+}
+
+func newFilter(fromRate, toRate int) *filter {
+	g := gcd(fromRate, toRate)
+	f := &filter{from: fromRate / g, to: toRate / g}
+
+	// The cutoff relative to the input Nyquist frequency, lower when downsampling.
+	cutoff := math.Min(1, float64(toRate)/float64(fromRate))
+	f.width = int(math.Ceil(zeroCrossings / cutoff))
+
+	f.kernels = make([][]float64, f.to)
+	for phase := range f.kernels {
+		frac := float64(phase) / float64(f.to)
+		kernel := make([]float64, 2*f.width)
+
+		var sum float64
+		for j := range kernel {
+			// Distance from the output sample to input sample j, the first one is width-1 before it.
+			d := frac + float64(f.width-1-j)
+			kernel[j] = cutoff * sinc(cutoff*d) * blackman(d/float64(f.width))
+			sum += kernel[j]
+		}
+
+		// Normalize, so a constant signal keeps its level.
+		for j := range kernel {
+			kernel[j] /= sum
+		}
+
+		f.kernels[phase] = kernel
+	}
+
+	return f
+}
+
+// process adds the input samples, and appends the output samples that can be calculated to out.
+func (f *filter) process(in []float64, out []float64) []float64 {
+	f.buf = append(f.buf, in...)
+	f.total += int64(len(in))
+
+	for {
+		k0, phase := f.position(f.next)
+		last := k0 + int64(f.width) // Last input sample the output sample needs.
+		if last >= f.base+int64(len(f.buf)) {
+			break
+		}
+
+		out = append(out, f.sample(k0, phase))
+		f.next++
+	}
+
+	// Drop the input samples that are not needed for the next output samples.
+	k0, _ := f.position(f.next)
+	if drop := k0 - int64(f.width) + 1 - f.base; drop > 0 {
+		if drop > int64(len(f.buf)) {
+			drop = int64(len(f.buf))
+		}
+
+		n := copy(f.buf, f.buf[drop:])
+		f.buf = f.buf[:n]
+		f.base += drop
+	}
+
+	return out
+}
+
+// flush appends the output samples left at the end of the input to out, with silence after the input.
+func (f *filter) flush(out []float64) []float64 {
+	for {
+		k0, phase := f.position(f.next)
+		if k0 >= f.total {
+			return out
+		}
+
+		out = append(out, f.sample(k0, phase))
+		f.next++
+	}
+}
+
+// position returns the input sample before output sample n, and the phase of n after it.
+func (f *filter) position(n int64) (int64, int) {
+	num := n * int64(f.from)
+	return num / int64(f.to), int(num % int64(f.to))
+}
+
+func (f *filter) sample(k0 int64, phase int) float64 {
+	var v float64
+	first := k0 - int64(f.width) + 1
+	for j, weight := range f.kernels[phase] {
+		i := first + int64(j) - f.base
+		if i < 0 || i >= int64(len(f.buf)) {
+			continue // Silence before and after the input.
+		}
+
+		v += f.buf[i] * weight
+	}
+
+	return v
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// blackman is the Blackman window, for x from -1 to 1.
+func blackman(x float64) float64 {
+	if x <= -1 || x >= 1 {
+		return 0
+	}
+
+	return 0.42 + 0.5*math.Cos(math.Pi*x) + 0.08*math.Cos(2*math.Pi*x)
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+
+	return a
+}
@@ -0,0 +1,118 @@
+package audio_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/laytan/youtupedia/internal/audio"
+)
+
+// stereoWAV returns a 16 bit stereo WAV file of the tone, with unknown sizes like a streamed WAV file.
+func stereoWAV(rate int, seconds float64, freq float64) []byte {
+	frames := int(float64(rate) * seconds)
+	le := binary.LittleEndian
+
+	b := []byte("RIFF")
+	b = le.AppendUint32(b, 0xFFFFFFFF)
+	b = append(b, "WAVEfmt "...)
+	b = le.AppendUint32(b, 16)
+	b = le.AppendUint16(b, 1) // PCM.
+	b = le.AppendUint16(b, 2) // Stereo.
+	b = le.AppendUint32(b, uint32(rate))
+	b = le.AppendUint32(b, uint32(rate*4))
+	b = le.AppendUint16(b, 4)
+	b = le.AppendUint16(b, 16)
+	b = append(b, "LIST"...) // A chunk that should be skipped.
+	b = le.AppendUint32(b, 4)
+	b = append(b, "INFO"...)
+	b = append(b, "data"...)
+	b = le.AppendUint32(b, 0xFFFFFFFF)
+	for i := 0; i < frames; i++ {
+		v := uint16(int16(math.Sin(2*math.Pi*freq*float64(i)/float64(rate)) * 0.5 * (1 << 15)))
+		b = le.AppendUint16(b, v)
+		b = le.AppendUint16(b, v)
+	}
+
+	return b
+}
+
+func resample(t *testing.T, src []byte) (audio.Format, []float64) {
+	t.Helper()
+
+	fh, err := os.Create(filepath.Join(t.TempDir(), "out.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+
+	if err := audio.Resample(fh, bytes.NewReader(src), audio.WhisperSampleRate); err != nil {
+		t.Fatalf("Resample(): %v", err)
+	}
+
+	if _, err := fh.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := audio.NewReader(fh)
+	if err != nil {
+		t.Fatalf("reading resampled WAV: %v", err)
+	}
+
+	samples := make([]float64, 2*audio.WhisperSampleRate)
+	n, err := r.Read(samples)
+	if err != io.EOF {
+		t.Fatalf("Read() error = %v, want io.EOF", err)
+	}
+
+	return r.Format, samples[:n]
+}
+
+func TestResample(t *testing.T) {
+	format, samples := resample(t, stereoWAV(48000, 1, 440))
+
+	want := audio.Format{Channels: 1, SampleRate: 16000, BitsPerSample: 16}
+	if format != want {
+		t.Errorf("format = %+v, want %+v", format, want)
+	}
+
+	if len(samples) != 16000 {
+		t.Fatalf("resampled to %d samples, want 16000", len(samples))
+	}
+
+	// Skip the edges, where the filter mixes in silence.
+	for i := 100; i < len(samples)-100; i++ {
+		want := math.Sin(2*math.Pi*440*float64(i)/16000) * 0.5
+		if math.Abs(samples[i]-want) > 0.01 {
+			t.Fatalf("sample %d = %f, want %f", i, samples[i], want)
+		}
+	}
+}
+
+func TestResampleFiltersAliases(t *testing.T) {
+	// 10 kHz can't be represented at 16 kHz, it should be filtered instead of aliasing to 6 kHz.
+	_, samples := resample(t, stereoWAV(44100, 1, 10000))
+
+	var sum float64
+	for _, s := range samples[100 : len(samples)-100] {
+		sum += s * s
+	}
+
+	if rms := math.Sqrt(sum / float64(len(samples)-200)); rms > 0.01 {
+		t.Errorf("RMS of filtered tone = %f, want it below 0.01", rms)
+	}
+}
+
+func TestNewReaderRejectsZeroBitsPerSample(t *testing.T) {
+	src := stereoWAV(8000, 0.1, 440)
+	binary.LittleEndian.PutUint16(src[34:36], 0) // Bits per sample of the fmt chunk.
+
+	// Frames of zero bytes would never reach the end of a streamed data chunk.
+	if _, err := audio.NewReader(bytes.NewReader(src)); err == nil {
+		t.Error("NewReader() of a WAV with 0 bits per sample succeeded, want an error")
+	}
+}
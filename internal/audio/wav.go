@@ -0,0 +1,251 @@
+// Package audio reads and writes WAV files, and resamples them to the 16 kHz mono that whisper expects.
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Format describes the samples of a WAV file.
+type Format struct {
+	Channels      int
+	SampleRate    int
+	BitsPerSample int
+	Float         bool // IEEE floating point samples instead of integers.
+}
+
+// unknownSize is the size in the headers of WAV files that are streamed, like the output of ffmpeg to a pipe.
+const unknownSize = 0xFFFFFFFF
+
+// Reader reads the samples of a WAV file, mixed down to mono.
+type Reader struct {
+	Format Format
+
+	r         *bufio.Reader
+	remaining int64 // Bytes left in the data chunk, -1 if unknown.
+	frame     []byte
+}
+
+// NewReader reads the header of the WAV file, up to the start of its data chunk.
+// The sizes in the header may be unknown, the data is then read until EOF.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReaderSize(r, 64*1024)
+
+	var riff [12]byte
+	if _, err := io.ReadFull(br, riff[:]); err != nil {
+		return nil, fmt.Errorf("reading RIFF header: %w", err)
+	}
+
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return nil, errors.New("not a WAV file")
+	}
+
+	wr := &Reader{r: br}
+	hasFormat := false
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			return nil, fmt.Errorf("reading chunk header: %w", err)
+		}
+
+		id := string(header[0:4])
+		size := binary.LittleEndian.Uint32(header[4:8])
+
+		switch id {
+		case "fmt ":
+			if err := wr.readFormat(size); err != nil {
+				return nil, err
+			}
+			hasFormat = true
+		case "data":
+			if !hasFormat {
+				return nil, errors.New("data chunk before fmt chunk")
+			}
+
+			wr.remaining = int64(size)
+			if size == unknownSize || size == 0 {
+				wr.remaining = -1
+			}
+
+			return wr, nil
+		default:
+			// Chunks are padded to an even size.
+			if _, err := br.Discard(int(size + size%2)); err != nil {
+				return nil, fmt.Errorf("skipping %q chunk: %w", id, err)
+			}
+		}
+	}
+}
+
+func (r *Reader) readFormat(size uint32) error {
+	if size < 16 {
+		return fmt.Errorf("fmt chunk of %d bytes is too small", size)
+	}
+
+	chunk := make([]byte, size+size%2)
+	if _, err := io.ReadFull(r.r, chunk); err != nil {
+		return fmt.Errorf("reading fmt chunk: %w", err)
+	}
+
+	tag := binary.LittleEndian.Uint16(chunk[0:2])
+	r.Format = Format{
+		Channels:      int(binary.LittleEndian.Uint16(chunk[2:4])),
+		SampleRate:    int(binary.LittleEndian.Uint32(chunk[4:8])),
+		BitsPerSample: int(binary.LittleEndian.Uint16(chunk[14:16])),
+	}
+
+	// WAVE_FORMAT_EXTENSIBLE has the actual format tag at the start of its sub format GUID.
+	if tag == 0xFFFE && size >= 26 {
+		tag = binary.LittleEndian.Uint16(chunk[24:26])
+	}
+
+	switch {
+	case tag == 1 && r.Format.BitsPerSample%8 == 0 && r.Format.BitsPerSample >= 8 && r.Format.BitsPerSample <= 32:
+	case tag == 3 && (r.Format.BitsPerSample == 32 || r.Format.BitsPerSample == 64):
+		r.Format.Float = true
+	default:
+		return fmt.Errorf("unsupported WAV format %d with %d bits per sample", tag, r.Format.BitsPerSample)
+	}
+
+	if r.Format.Channels < 1 || r.Format.SampleRate < 1 {
+		return fmt.Errorf("invalid WAV format with %d channels at %d Hz", r.Format.Channels, r.Format.SampleRate)
+	}
+
+	r.frame = make([]byte, r.Format.Channels*r.Format.BitsPerSample/8)
+	return nil
+}
+
+// Read reads samples, the average of the channels from -1 to 1, into samples.
+// It returns the amount of samples read, and io.EOF at the end of the data.
+func (r *Reader) Read(samples []float64) (int, error) {
+	width := r.Format.BitsPerSample / 8
+	for n := range samples {
+		if r.remaining >= 0 && r.remaining < int64(len(r.frame)) {
+			return n, io.EOF
+		}
+
+		if _, err := io.ReadFull(r.r, r.frame); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return n, io.EOF
+			}
+
+			return n, fmt.Errorf("reading samples: %w", err)
+		}
+
+		if r.remaining >= 0 {
+			r.remaining -= int64(len(r.frame))
+		}
+
+		var sum float64
+		for c := 0; c < r.Format.Channels; c++ {
+			sum += r.sample(r.frame[c*width : (c+1)*width])
+		}
+		samples[n] = sum / float64(r.Format.Channels)
+	}
+
+	return len(samples), nil
+}
+
+func (r *Reader) sample(b []byte) float64 {
+	if r.Format.Float {
+		if len(b) == 8 {
+			return math.Float64frombits(binary.LittleEndian.Uint64(b))
+		}
+
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	}
+
+	switch len(b) {
+	case 1: // 8 bit samples are unsigned.
+		return (float64(b[0]) - 128) / 128
+	case 2:
+		return float64(int16(binary.LittleEndian.Uint16(b))) / (1 << 15)
+	case 3:
+		v := int32(b[0]) | int32(b[1])<<8 | int32(int8(b[2]))<<16
+		return float64(v) / (1 << 23)
+	default:
+		return float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31)
+	}
+}
+
+// Writer writes 16 bit mono WAV files, the sizes in the header are written by Close.
+type Writer struct {
+	w       io.WriteSeeker
+	buf     *bufio.Writer
+	samples int64
+}
+
+// NewWriter writes the header of a 16 bit mono WAV file at the sample rate to w.
+func NewWriter(w io.WriteSeeker, sampleRate int) (*Writer, error) {
+	header := make([]byte, 44)
+	copy(header[0:4], "RIFF")
+	copy(header[8:16], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(header[16:20], 16)
+	binary.LittleEndian.PutUint16(header[20:22], 1) // PCM.
+	binary.LittleEndian.PutUint16(header[22:24], 1) // Mono.
+	binary.LittleEndian.PutUint32(header[24:28], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(header[28:32], uint32(sampleRate*2)) // Bytes per second.
+	binary.LittleEndian.PutUint16(header[32:34], 2)                    // Bytes per frame.
+	binary.LittleEndian.PutUint16(header[34:36], 16)                   // Bits per sample.
+	copy(header[36:40], "data")
+
+	buf := bufio.NewWriterSize(w, 64*1024)
+	if _, err := buf.Write(header); err != nil {
+		return nil, fmt.Errorf("writing WAV header: %w", err)
+	}
+
+	return &Writer{w: w, buf: buf}, nil
+}
+
+// Write writes the samples, from -1 to 1, clipping samples outside of that range.
+func (w *Writer) Write(samples []float64) error {
+	var b [2]byte
+	for _, s := range samples {
+		v := math.Round(s * (1 << 15))
+		v = math.Max(math.MinInt16, math.Min(math.MaxInt16, v))
+		binary.LittleEndian.PutUint16(b[:], uint16(int16(v)))
+		if _, err := w.buf.Write(b[:]); err != nil {
+			return fmt.Errorf("writing samples: %w", err)
+		}
+	}
+
+	w.samples += int64(len(samples))
+	return nil
+}
+
+// Close writes the sizes into the header, it does not close the underlying writer.
+func (w *Writer) Close() error {
+	if err := w.buf.Flush(); err != nil {
+		return fmt.Errorf("writing samples: %w", err)
+	}
+
+	dataSize := w.samples * 2
+	if dataSize+36 > math.MaxUint32 {
+		return fmt.Errorf("%d samples are too many for a WAV file", w.samples)
+	}
+
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], uint32(dataSize+36))
+	if err := writeAt(w.w, b[:], 4); err != nil {
+		return err
+	}
+
+	binary.LittleEndian.PutUint32(b[:], uint32(dataSize))
+	return writeAt(w.w, b[:], 40)
+}
+
+func writeAt(w io.WriteSeeker, b []byte, offset int64) error {
+	if _, err := w.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("seeking to WAV header: %w", err)
+	}
+
+	if _, err := w.Write(b); err != nil {
+		return fmt.Errorf("writing WAV header: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package failures
+
+import "errors"
+
+// freeSpace is not supported on this platform, so the disk space is not checked.
+func freeSpace(path string) (int64, error) {
+	return 0, errors.New("checking free disk space is not supported on this platform")
+}
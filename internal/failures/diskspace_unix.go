@@ -0,0 +1,15 @@
+//go:build unix
+
+package failures
+
+import "syscall"
+
+// freeSpace returns the bytes available to unprivileged users on the file system of path.
+func freeSpace(path string) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
@@ -0,0 +1,85 @@
+package failures
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+
+	"github.com/laytan/youtupedia/internal/audio"
+)
+
+// downloadAudio streams the audio of the video from its Source into ffmpeg, which decodes it to WAV,
+// into audio.Resample, which writes it to path at the sample rate of whisper.
+// Only the resampled audio is written to disk.
+func (p *Pipeline) downloadAudio(ctx context.Context, source Source, videoId string, audioURL string, path string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", path, err)
+	}
+	defer out.Close()
+
+	compressed, err := source.Audio(ctx, videoId, audioURL)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		return fmt.Errorf("downloading audio: %w", err)
+	}
+
+	decode := exec.CommandContext(
+		ctx,
+		p.opts.FfmpegBin,
+		"-hide_banner",
+		"-loglevel",
+		"error",
+		"-i",
+		"pipe:0",
+		"-f",
+		"wav",
+		"-c:a",
+		"pcm_s16le",
+		"pipe:1",
+	)
+	decodeStderr := &bytes.Buffer{}
+	decode.Stdin = compressed
+	decode.Stderr = decodeStderr
+	decoded, err := decode.StdoutPipe()
+	if err != nil {
+		compressed.Close()
+		return fmt.Errorf("ffmpeg stdout: %w", err)
+	}
+
+	if err := decode.Start(); err != nil {
+		compressed.Close()
+		return fmt.Errorf("starting ffmpeg: %w", err)
+	}
+
+	resampleErr := audio.Resample(out, decoded, audio.WhisperSampleRate)
+	if resampleErr != nil {
+		// ffmpeg blocks on writing to the pipe when it is not read anymore.
+		decode.Process.Kill()
+	}
+
+	decodeErr := decode.Wait()
+
+	// Stops the download if ffmpeg stopped reading it early.
+	downloadErr := compressed.Close()
+
+	switch {
+	case ctx.Err() != nil:
+		log.Println("[WARN]: downloading audio: context cancelled")
+		return ctx.Err()
+	case resampleErr != nil:
+		return fmt.Errorf("resampling audio: %w", resampleErr)
+	case downloadErr != nil:
+		return fmt.Errorf("downloading audio: %w", downloadErr)
+	case decodeErr != nil:
+		return execErr("ffmpeg", decodeErr, decodeStderr.String())
+	default:
+		return nil
+	}
+}
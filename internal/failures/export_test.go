@@ -0,0 +1,24 @@
+package failures
+
+import "context"
+
+// Stage is a stage started by RunStage, outside of a pipeline.
+type Stage struct {
+	p *Pipeline
+	s *stage
+}
+
+// RunStage starts the workers of a stage, see runStage.
+func RunStage[T any](ctx context.Context, workers int, in <-chan T, work func(item T) bool, stopped func()) *Stage {
+	p := &Pipeline{}
+	s := p.newStage("test", workers, func() int { return len(in) })
+	runStage(ctx, p, s, in, work, stopped)
+	return &Stage{p: p, s: s}
+}
+
+func (s *Stage) Busy() int64 { return s.s.busy.Load() }
+
+func (s *Stage) Queued() int { return s.s.queued() }
+
+// Wait waits for the workers and the stopped callback to return.
+func (s *Stage) Wait() { s.p.stages.Wait() }
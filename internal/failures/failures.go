@@ -1,110 +1,314 @@
 package failures
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
-	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"io/fs"
 	"log"
 	"os"
 	"os/exec"
 	"os/signal"
-	"runtime"
-	"strconv"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/laytan/youtupedia/internal/feed"
+	"github.com/laytan/youtupedia/internal/index"
+	"github.com/laytan/youtupedia/internal/library"
+	"github.com/laytan/youtupedia/internal/quality"
 	"github.com/laytan/youtupedia/internal/stem"
 	"github.com/laytan/youtupedia/internal/store"
+	"github.com/laytan/youtupedia/internal/transcribe"
 	"github.com/laytan/youtupedia/internal/tube"
 )
 
-var (
-	Db      *sql.DB
-	Queries *store.Queries
-	Yt      *tube.Client
+// YouTube is the part of the YouTube API the Pipeline uses, implemented by *tube.Client.
+type YouTube interface {
+	Video(id string) (*tube.ResVideo, error)
+	index.Prober
+}
 
-	WhisperModelPath  = "../whisper.cpp/models/ggml-base.en.bin"
-	WhisperThreads    = "1"
-	WhisperProcessors = strconv.Itoa(
-		runtime.NumCPU() - 1,
-	) // Keep 1 processor for non-whisper stuff.
-	BinWhisperCpp = "../whisper.cpp/main"
+type Options struct {
+	// Transcriber transcribes the downloaded audio, defaults to the whisper.cpp binary with its default options.
+	Transcriber transcribe.Transcriber
 
-	BinFfmpeg = "ffmpeg"
-	BinYtDlp  = "yt-dlp"
-)
+	FfmpegBin string // Decodes the downloaded audio, defaults to "ffmpeg".
+	YtDlpBin  string // Downloads the audio of YouTube videos, defaults to "yt-dlp".
+
+	// Feeds reads the feeds of podcast channels, and the audio of their episodes, see store.SourcePodcast.
+	// Defaults to a feed.Client with its defaults.
+	Feeds *feed.Client
+
+	// WorkDir holds a directory per failure that is processed, for the downloaded audio,
+	// defaults to "youtupedia" in the temporary directory. Workers on the same machine can share it.
+	WorkDir string
+
+	// MinFreeSpace is the amount of bytes kept free in the WorkDir, defaults to 1 GB.
+	// Failures are postponed when downloading their audio would go below it.
+	MinFreeSpace int64
+
+	MaxAttempts  int           // Attempts at a failure before it is marked dead, defaults to 5.
+	RetryBackoff time.Duration // Time before the second attempt, doubled for each next one, see Backoff. Defaults to 30 minutes.
+
+	// PollInterval is how often the queue is checked in watch mode when there are no notifications, defaults to a minute.
+	// This also picks up failures that became due because their backoff passed.
+	PollInterval time.Duration
+
+	// WorkerID identifies this worker in the claims on failures, defaults to the hostname and process ID.
+	// Workers that run against the same database need a unique ID.
+	WorkerID string
 
-func init() {
-    if wp, ok := os.LookupEnv("WHISPER_BIN"); ok {
-        BinWhisperCpp = wp
-    }
+	// Download, Transcribe and Index configure the workers of the stages, and how many videos can wait for them.
+	// For example, more downloaders keep the transcribers busy when downloading is slow.
+	Download   StageOptions
+	Transcribe StageOptions
+	Index      StageOptions
 
-    if mp, ok := os.LookupEnv("WHISPER_MODEL"); ok {
-        WhisperModelPath = mp
-    }
+	// ReportInterval is how often the progress of the pipeline is logged, defaults to a minute.
+	ReportInterval time.Duration
+
+	// Lease is how long a claim on a failure lasts before other workers may claim it, defaults to an hour, at least a minute.
+	// Claims are renewed while the failure is processed, so this is only reached when a worker stops unexpectedly.
+	Lease time.Duration
+}
+
+// Pipeline downloads, transcribes and indexes the videos in the failures table.
+type Pipeline struct {
+	store store.Store
+	yt    YouTube
+	feeds *feedSource
+	opts  Options
+
+	// inFlight holds the IDs of the failures claimed by this worker that are being processed,
+	// their claims are renewed until they are done.
+	inFlight sync.Map
+
+	// stages tracks the goroutines of the stages, so shutting down can wait for them to finish.
+	stages sync.WaitGroup
+
+	stageMu   sync.Mutex
+	stageList []*stage // The started stages, for the report.
+	progress  progress
 }
 
-func WhisperNoCaptionFailures(ctx context.Context) (err error) {
+func New(s store.Store, yt YouTube, opts Options) *Pipeline {
+	if opts.Transcriber == nil {
+		opts.Transcriber = transcribe.NewWhisperCpp(transcribe.WhisperCppOptions{})
+	}
+
+	if opts.FfmpegBin == "" {
+		opts.FfmpegBin = "ffmpeg"
+	}
+
+	if opts.YtDlpBin == "" {
+		opts.YtDlpBin = "yt-dlp"
+	}
+
+	if opts.Feeds == nil {
+		opts.Feeds = &feed.Client{}
+	}
+
+	if opts.WorkDir == "" {
+		opts.WorkDir = filepath.Join(os.TempDir(), "youtupedia")
+	}
+
+	if opts.MinFreeSpace <= 0 {
+		opts.MinFreeSpace = 1_000_000_000
+	}
+
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = 30 * time.Minute
+	}
+
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Minute
+	}
+
+	opts.Download.setDefaults()
+	opts.Transcribe.setDefaults()
+	opts.Index.setDefaults()
+
+	if opts.ReportInterval <= 0 {
+		opts.ReportInterval = time.Minute
+	}
+
+	if opts.WorkerID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "unknown"
+		}
+
+		opts.WorkerID = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+
+	if opts.Lease <= 0 {
+		opts.Lease = time.Hour
+	} else if opts.Lease < time.Minute {
+		opts.Lease = time.Minute
+	}
+
+	return &Pipeline{
+		store: s,
+		yt:    yt,
+		feeds: &feedSource{client: opts.Feeds},
+		opts:  opts,
+	}
+}
+
+// RunOptions select the failures the pipeline processes, the zero value processes all due failures.
+type RunOptions struct {
+	ChannelID string // Only process failures of this channel.
+	Limit     int    // Stop after this amount of failures, 0 means no limit.
+
+	// Watch keeps the pipeline running when there are no due failures left,
+	// checking again every Options.PollInterval, or when a notification is received.
+	Watch bool
+
+	// Notifications wake the pipeline in watch mode, the payload is the type of the failure that became due,
+	// an empty payload wakes it regardless of type. See store.Listen and store.FailuresChannel.
+	Notifications <-chan string
+}
+
+// WhisperNoCaptionFailures transcribes and indexes the videos of the no captions, retranscribe, upgrade, episode and file failures that are due.
+// Videos are transcribed with the whisper model and language in the settings of their channel,
+// their details and audio come from the Source of their channel.
+// When processing a failure fails, the attempt is recorded on the failure and it is retried later, see Options.MaxAttempts.
+// Returns when all selected failures have been attempted, or in watch mode, when ctx is done or the limit is reached.
+// Before returning, it waits for every stage to stop, and releases the claims on the failures that were not finished.
+//
+// Failures are claimed before processing them, see Options.Lease, so multiple workers can run against the same database.
+func (p *Pipeline) WhisperNoCaptionFailures(ctx context.Context, opts RunOptions) (err error) {
+	log.Printf("[INFO]: processing failures as worker %q in %s", p.opts.WorkerID, p.opts.WorkDir)
+	if err := p.sweep(ctx); err != nil {
+		return err
+	}
+	defer p.releaseClaims()
+
 	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, os.Interrupt)
+	defer signal.Stop(signals)
 
 	errs := make(chan error, 5)
-	fc := Failures(ctx, errs, store.FailureTypeNoCaptions)
-	dc := DownloadFailures(ctx, errs, fc)
-	wc := WhisperDownloads(ctx, errs, dc)
-	IndexWhispers(ctx, errs, wc)
+	types := []store.FailureType{
+		store.FailureTypeNoCaptions,
+		store.FailureTypeRetranscribe,
+		store.FailureTypeUpgrade,
+		store.FailureTypeEpisode,
+		store.FailureTypeFile,
+	}
+	p.progress.start = time.Now()
+	fc := p.Failures(ctx, errs, types, opts)
+	dc := p.DownloadFailures(ctx, errs, fc)
+	wc := p.WhisperDownloads(ctx, errs, dc)
+	done := p.IndexWhispers(ctx, errs, wc)
 
-	reportTicker := time.NewTicker(time.Minute)
+	reportTicker := time.NewTicker(p.opts.ReportInterval)
 	defer reportTicker.Stop()
+	renewTicker := time.NewTicker(p.opts.Lease / 3)
+	defer renewTicker.Stop()
+Loop:
 	for {
 		select {
 		case <-ctx.Done():
-			cancel()
-			// Sleep so cleanup can finish.
-			// Don't @ me!
-			time.Sleep(time.Second)
-			return
+			break Loop
+		case <-done:
+			break Loop
 		case <-signals:
 			signal.Stop(signals)
+			log.Println("[INFO]: interrupted, waiting for the stages to stop...")
 			cancel()
 		case nerr := <-errs:
 			err = errors.Join(err, nerr)
 			cancel()
 		case <-reportTicker.C:
-			count, err := Queries.CountFailures(
-				ctx,
-				store.CountFailuresParams{Type: string(store.FailureTypeNoCaptions)},
-			)
-			if err != nil {
-				errs <- fmt.Errorf("counting failures: %w", err)
+			if err := p.report(ctx, types, opts.ChannelID); err != nil {
+				log.Printf("[WARN]: reporting progress: %v", err)
 			}
-			log.Printf("[INFO]: %d failures in the queue", count)
+		case <-renewTicker.C:
+			p.renewClaims(ctx)
 		}
 	}
+
+	cancel()
+	return errors.Join(err, p.drain(errs))
 }
 
-func Failures(ctx context.Context, errs chan<- error, typ store.FailureType) <-chan *store.Failure {
-	c := make(chan *store.Failure)
-	var last int64
+// drain waits for the stages to stop, after their context is cancelled,
+// returning the errors they send in the meantime, except those caused by the cancellation.
+func (p *Pipeline) drain(errs <-chan error) (err error) {
+	stopped := make(chan struct{})
 	go func() {
+		p.stages.Wait()
+		close(stopped)
+	}()
+
+	for {
+		select {
+		case <-stopped:
+			return err
+		case nerr := <-errs:
+			if !errors.Is(nerr, context.Canceled) {
+				err = errors.Join(err, nerr)
+			}
+		}
+	}
+}
+
+// Failures claims and sends the due failures of the types, highest priority first, see index.Priority.
+// In watch mode, it waits for failures to become due when there are none left, see RunOptions.Watch.
+func (p *Pipeline) Failures(
+	ctx context.Context,
+	errs chan<- error,
+	types []store.FailureType,
+	opts RunOptions,
+) <-chan *store.Failure {
+	typeStrings := make([]string, 0, len(types))
+	for _, typ := range types {
+		typeStrings = append(typeStrings, string(typ))
+	}
+
+	c := make(chan *store.Failure, p.opts.Download.Queue)
+	p.stages.Add(1)
+	go func() {
+		defer p.stages.Done()
 		defer close(c)
-		for {
-			log.Println("[INFO]: querying next failure to process...")
-			failure, err := Queries.NextFailure(ctx, store.NextFailureParams{
-				ID:   last,
-				Type: string(typ),
+		for n := 0; opts.Limit <= 0 || n < opts.Limit; {
+			log.Println("[INFO]: claiming next failure to process...")
+			failure, err := p.store.ClaimFailure(ctx, store.ClaimFailureParams{
+				WorkerID:     p.opts.WorkerID,
+				LeaseSeconds: int32(p.opts.Lease.Seconds()),
+				Types:        typeStrings,
+				ChannelID:    sql.NullString{String: opts.ChannelID, Valid: opts.ChannelID != ""},
 			})
 			if err != nil {
-				errs <- fmt.Errorf("getting next failure: %w", err)
-				return
+				if !errors.Is(err, sql.ErrNoRows) {
+					errs <- fmt.Errorf("claiming next failure: %w", err)
+					return
+				}
+
+				if !opts.Watch {
+					log.Println("[INFO]: no more failures to process")
+					return
+				}
+
+				log.Println("[INFO]: no failures to process, waiting for new ones...")
+				if !p.wait(ctx, types, opts.Notifications) {
+					return
+				}
+
+				continue
 			}
-			last = failure.ID
+			p.inFlight.Store(failure.ID, struct{}{})
+			n++
 
 			log.Println("[INFO]: sending next failure...")
 			select {
@@ -113,416 +317,584 @@ func Failures(ctx context.Context, errs chan<- error, typ store.FailureType) <-c
 			case c <- &failure:
 			}
 		}
+
+		log.Printf("[INFO]: reached limit of %d failures", opts.Limit)
 	}()
 
 	return c
 }
 
+// wait blocks until a notification for one of the types is received or Options.PollInterval passes,
+// returning false if ctx is done first.
+func (p *Pipeline) wait(ctx context.Context, types []store.FailureType, notifications <-chan string) bool {
+	poll := time.NewTimer(p.opts.PollInterval)
+	defer poll.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-poll.C:
+			return true
+		case payload := <-notifications:
+			if payload == "" {
+				return true
+			}
+
+			for _, typ := range types {
+				if payload == string(typ) {
+					return true
+				}
+			}
+		}
+	}
+}
+
+// complete deletes the processed failure, if it is still claimed by this worker, otherwise errLostClaim is returned.
+// The claim is lost when the lease expired and another worker claimed the failure, or it was removed.
+func (p *Pipeline) complete(ctx context.Context, q store.Querier, id int64) error {
+	n, err := q.CompleteFailure(ctx, store.CompleteFailureParams{ID: id, WorkerID: p.opts.WorkerID})
+	if err != nil {
+		return fmt.Errorf("deleting failure %d: %w", id, err)
+	}
+
+	if n == 0 {
+		return fmt.Errorf("failure %d: %w", id, errLostClaim)
+	}
+
+	return nil
+}
+
+// done removes the failure from the failures in flight, and its job directory, after it has been processed.
+func (p *Pipeline) done(id int64) {
+	p.inFlight.Delete(id)
+	p.removeJobDir(id)
+}
+
+// renewClaims extends the leases on the failures in flight.
+func (p *Pipeline) renewClaims(ctx context.Context) {
+	p.inFlight.Range(func(key, _ any) bool {
+		id := key.(int64)
+		n, err := p.store.RenewClaim(ctx, store.RenewClaimParams{
+			LeaseSeconds: int32(p.opts.Lease.Seconds()),
+			ID:           id,
+			WorkerID:     p.opts.WorkerID,
+		})
+		if err != nil {
+			log.Printf("[WARN]: renewing claim on failure %d: %v", id, err)
+		} else if n == 0 {
+			log.Printf("[WARN]: lost claim on failure %d, its lease expired or it was removed", id)
+		}
+
+		return true
+	})
+}
+
+// releaseClaims makes the failures in flight due again, so other workers can claim them right away,
+// and removes their job directories.
+func (p *Pipeline) releaseClaims() {
+	p.inFlight.Range(func(key, _ any) bool {
+		id := key.(int64)
+		if err := p.store.ReleaseClaim(context.Background(), store.ReleaseClaimParams{
+			ID:       id,
+			WorkerID: p.opts.WorkerID,
+		}); err != nil {
+			log.Printf("[WARN]: releasing claim on failure %d: %v", id, err)
+		}
+
+		p.done(id)
+		return true
+	})
+}
+
 type Download struct {
-	FailureId int64
-	VideoId   string
-	Path      string
-	Video     *tube.ResVideo
+	attempt
+	VideoId      string
+	Path         string // The 16 kHz audio, in the job directory of the failure.
+	Video        *tube.ResVideo
+	AudioURL     string                 // Where the audio of podcast episodes is played, empty for YouTube videos.
+	Transcriber  transcribe.Transcriber // Configured with the whisper settings of the channel.
+	Retranscribe bool                   // Whether the video is indexed already, and its transcript is replaced.
+	Upgrade      bool                   // Whether the transcript only replaces automatic captions it is better than.
 }
 
-func DownloadFailures(
+func (p *Pipeline) DownloadFailures(
 	ctx context.Context,
 	errs chan<- error,
 	failures <-chan *store.Failure,
 ) <-chan *Download {
-	c := make(chan *Download)
-	go func() {
-		defer close(c)
-		for {
-			// Using an inner function so each loop iteration runs the defer/cleanup.
-			cont := func() bool {
-				select {
-				case <-ctx.Done():
-					return false
-				case failure, ok := <-failures:
-					if !ok {
-						return false
-					}
-
-					log.Println("[INFO]: retrieved failure, downloading...")
-
-					videoId := failure.Data
-
-					log.Println("[INFO]: checking if video does does not already exist")
-					if _, err := Queries.Video(ctx, videoId); err == nil {
-						log.Println("[WARN]: video already in database, removing failure")
-
-						if err := Queries.DeleteFailure(ctx, failure.ID); err != nil {
-							errs <- fmt.Errorf("deleting indexed failure: %w", err)
-							return false
-						}
-
-						return true
-					}
-
-					log.Printf("[INFO]: getting video %q info from API", videoId)
-					video, err := Yt.Video(videoId)
-					if err != nil {
-						errs <- fmt.Errorf("getting youtube video info: %w", err)
-						return false
-					}
-
-					if video.IsBroadcast() {
-						log.Println("[WARN]: video is a broadcast, can't index, skipping")
-						return true
-					}
-
-					defer cleanGlob(videoId+".*", videoId+".16k.wav")
-
-					log.Printf(
-						"[INFO]: downloading audio from video titled %q",
-						video.Snippet.Title,
-					)
-					cmd := exec.CommandContext(
-						ctx,
-						BinYtDlp,
-                        "-f",
-                        "bestaudio",
-						"--ignore-config",
-						"--no-progress",
-						"--output",
-						videoId+".wav",
-						"--extract-audio",
-						"--audio-format",
-						"wav",
-                        "https://youtube.com/watch?v=" + videoId,
-					)
-					dlStdout := &bytes.Buffer{}
-					cmd.Stdout = dlStdout // Need to capture stdout for error messages, for some reasons errors are shown on stdout.
-					if err := cmd.Run(); err != nil {
-						handleExecErr("yt-dlp", err, errs, dlStdout.String())
-						return false
-					}
-
-					log.Println("[INFO]: converting audio to 16 KHz")
-					cmd = exec.CommandContext(
-						ctx,
-						BinFfmpeg,
-						"-i",
-						videoId+".wav",
-						"-ar",
-						"16000",
-						"-ac",
-						"1",
-						"-c:a",
-						"pcm_s16le",
-						"--",
-						videoId+".16k.wav",
-					)
-					dlStdout.Reset()
-					cmd.Stdout = dlStdout // Need to capture stdout for error messages, for some reasons errors are shown on stdout.
-					if err := cmd.Run(); err != nil {
-						handleExecErr("ffmpeg", err, errs, dlStdout.String())
-						return false
-					}
-
-					log.Println("[INFO]: sending downloaded video...")
-
-					select {
-					case <-ctx.Done():
-						return false
-					case c <- &Download{
-						FailureId: failure.ID,
-						Path:      videoId + ".16k.wav", // TODO: we sent the message, but it might not be received, so there might not be a cleanup for this file.
-						VideoId:   videoId,
-						Video:     video,
-					}:
-						return true
-					}
-				}
-			}()
-			if !cont {
-				return
+	c := make(chan *Download, p.opts.Transcribe.Queue)
+	s := p.newStage("download", p.opts.Download.Workers, func() int { return len(failures) })
+	runStage(ctx, p, s, failures, func(failure *store.Failure) bool {
+		log.Println("[INFO]: retrieved failure, downloading...")
+
+		videoId := failure.Data
+		typ := store.FailureType(failure.Type)
+		upgrade := typ == store.FailureTypeUpgrade
+		retranscribe := typ == store.FailureTypeRetranscribe || upgrade
+		a := attempt{FailureId: failure.ID, Attempts: failure.Attempts}
+		fail := func(record func(context.Context, attempt, error) error, cause error) bool {
+			if err := record(ctx, a, cause); err != nil {
+				errs <- err
+				return false
 			}
+
+			return true
 		}
-	}()
+		drop := func(reason string) bool {
+			log.Printf("[INFO]: %s, removing failure", reason)
+
+			if err := p.complete(ctx, p.store, failure.ID); err != nil && !errors.Is(err, errLostClaim) {
+				errs <- err
+				return false
+			} else if err != nil {
+				log.Printf("[WARN]: %v, leaving it to the other worker", err)
+			}
+			p.done(failure.ID)
+
+			return true
+		}
+
+		log.Println("[INFO]: checking if video does does not already exist")
+		existing, err := p.store.Video(ctx, videoId)
+		if exists := err == nil; exists && !retranscribe {
+			return drop("video already in database")
+		} else if !exists && retranscribe {
+			return drop("video to retranscribe is not in the database")
+		} else if upgrade && store.TranscriptType(existing.TranscriptType) != store.TubeAuto {
+			return drop(fmt.Sprintf("video to upgrade has %s captions instead of automatic captions", existing.TranscriptType))
+		}
+
+		channel, err := p.store.Channel(ctx, failure.ChannelID)
+		if err != nil {
+			return fail(p.retry, fmt.Errorf("retrieving channel %q: %w", failure.ChannelID, err))
+		}
+		source := p.source(&channel)
+
+		log.Printf("[INFO]: getting video %q info from %s", videoId, channel.Source)
+		video, audioURL, err := source.Video(ctx, &channel, videoId)
+		if err != nil {
+			if errors.Is(err, tube.ErrQuotaExceeded) {
+				errs <- fmt.Errorf("getting youtube video info: %w", err)
+				return false
+			}
+
+			if errors.Is(err, tube.ErrNotFound) || errors.Is(err, feed.ErrNotFound) || errors.Is(err, library.ErrNotFound) {
+				return fail(p.kill, fmt.Errorf("video is unavailable, it may be deleted or private: %w", err))
+			}
+
+			return fail(p.retry, fmt.Errorf("getting video info: %w", err))
+		}
+
+		if video.IsBroadcast() {
+			return fail(p.postpone, fmt.Errorf(
+				"video is a broadcast (%s), can't index it yet",
+				video.Snippet.LiveBroadcastContent,
+			))
+		}
+
+		settings, err := store.ChannelSettingsOrDefault(ctx, p.store, video.Snippet.ChannelId)
+		if err != nil {
+			return fail(p.retry, fmt.Errorf("retrieving settings of channel %q: %w", video.Snippet.ChannelId, err))
+		}
+
+		// Retranscribes are requested explicitly, so the filters of the channel don't apply.
+		if !retranscribe {
+			reason, err := p.skip(settings, &channel, source, video)
+			if err != nil {
+				return fail(p.retry, err)
+			}
+
+			if reason != "" {
+				return drop("skipping video: " + reason)
+			}
+		}
+
+		transcriber, err := p.opts.Transcriber.With(transcribe.Params{
+			Model:    settings.WhisperModel,
+			Language: settings.WhisperLanguage,
+			Diarize:  settings.Diarize,
+		})
+		if err != nil {
+			return fail(p.retry, fmt.Errorf("whisper settings of channel %q: %w", video.Snippet.ChannelId, err))
+		}
+
+		if retranscribe && store.TranscriptType(existing.TranscriptType) == transcriber.Type() {
+			return drop(fmt.Sprintf("video is already transcribed by %s", transcriber.Type()))
+		}
+
+		// Durations of live streams and premieres can be missing, only checking the minimum then.
+		duration, _ := video.Duration()
+		if err := p.checkDiskSpace(duration); err != nil {
+			return fail(p.postpone, err)
+		}
+
+		dir, err := p.createJobDir(failure.ID)
+		if err != nil {
+			return fail(p.retry, err)
+		}
+		converted := filepath.Join(dir, "audio.16k.wav")
+
+		log.Printf(
+			"[INFO]: downloading audio from video titled %q",
+			video.Snippet.Title,
+		)
+		if err := p.downloadAudio(ctx, source, videoId, audioURL, converted); err != nil {
+			if ctx.Err() != nil {
+				return false
+			}
+
+			return fail(p.retry, err)
+		}
+
+		log.Println("[INFO]: sending downloaded video...")
+
+		select {
+		case <-ctx.Done():
+			return false
+		case c <- &Download{
+			attempt:      a,
+			Path:         converted,
+			VideoId:      videoId,
+			Video:        video,
+			AudioURL:     audioURL,
+			Transcriber:  transcriber,
+			Retranscribe: retranscribe,
+			Upgrade:      upgrade,
+		}:
+			return true
+		}
+	}, func() { close(c) })
 
 	return c
 }
 
+// skip returns why the video should not be transcribed according to the settings of its channel,
+// or an empty string if it should. Podcasts and local files have no captions, whisper is not a fallback for them.
+func (p *Pipeline) skip(settings store.ChannelSetting, channel *store.Channel, source Source, video *tube.ResVideo) (string, error) {
+	fallback := settings.WhisperFallback || store.CaptionSource(settings.CaptionSource) == store.CaptionSourceWhisper
+	if !fallback && store.Source(channel.Source) == store.SourceYouTube {
+		return "whisper fallback is disabled for the channel", nil
+	}
+
+	filter, err := index.NewFilter(source, settings)
+	if err != nil {
+		return "", err
+	}
+
+	return filter.Skip(video)
+}
+
 type Whisper struct {
-	FailureId int64
-	VideoId   string
-	Path      string
-	Video     *tube.ResVideo
+	attempt
+	VideoId      string
+	Video        *tube.ResVideo
+	AudioURL     string // Where the audio of podcast episodes is played, empty for YouTube videos.
+	Segments     []transcribe.Segment
+	Type         store.TranscriptType // The backend and model that transcribed the segments.
+	Retranscribe bool                 // Whether the video is indexed already, and its transcript is replaced.
+	Upgrade      bool                 // Whether the transcript only replaces automatic captions it is better than.
 }
 
-func WhisperDownloads(
+func (p *Pipeline) WhisperDownloads(
 	ctx context.Context,
 	errs chan<- error,
 	downloads <-chan *Download,
 ) <-chan *Whisper {
-	c := make(chan *Whisper)
-	go func() {
-		defer close(c)
-		for {
-			log.Println("[INFO]: waiting for next download...")
-			cont := func() bool { // Inner function so each iteration runs the defer.
-				select {
-				case <-ctx.Done():
-					return false
-				case download, ok := <-downloads:
-					if !ok {
-						return false
-					}
-
-					log.Println("[INFO]: retrieved download, running whisper...")
-					videoId := download.VideoId
-
-					defer func() {
-						log.Printf("[INFO]: deleting file %s (cleanup)", download.Path)
-						if err := os.Remove(download.Path); err != nil {
-							log.Printf("cleaning download after whisper: %v", err)
-						}
-					}()
-
-					log.Println("[INFO]: running whisper on the audio")
-					cmd := exec.CommandContext(
-						ctx,
-						BinWhisperCpp,
-						"-m",
-						WhisperModelPath,
-						"-f",
-						download.Path,
-						"-ocsv",
-						"-t",
-						WhisperThreads,
-						"-p",
-						WhisperProcessors,
-					)
-					dlStdout := bytes.Buffer{}
-					cmd.Stdout = &dlStdout // Need to capture stdout for error messages, for some reasons errors are shown on stdout.
-					if err := cmd.Run(); err != nil {
-						handleExecErr("whisper.cpp", err, errs, dlStdout.String())
-						return false
-					}
-
-					log.Println("[INFO]: sending whisper csv...")
-					select {
-					case <-ctx.Done():
-						return false
-					case c <- &Whisper{
-						FailureId: download.FailureId,
-						VideoId:   videoId,
-						Path:      download.Path + ".csv",
-						Video:     download.Video,
-					}:
-						return true
-					}
-				}
-			}()
-			if !cont {
-				return
+	c := make(chan *Whisper, p.opts.Index.Queue)
+	s := p.newStage("transcribe", p.opts.Transcribe.Workers, func() int { return len(downloads) })
+	runStage(ctx, p, s, downloads, func(download *Download) bool {
+		log.Println("[INFO]: retrieved download, running whisper...")
+		videoId := download.VideoId
+
+		log.Printf("[INFO]: transcribing the audio using %s", download.Transcriber.Type())
+		segments, err := download.Transcriber.Transcribe(ctx, download.Path)
+		if err != nil {
+			if ctx.Err() != nil {
+				log.Printf("[WARN]: transcribing: context cancelled")
+				return false
+			}
+
+			if err := p.retry(ctx, download.attempt, fmt.Errorf("transcribing: %w", err)); err != nil {
+				errs <- err
+				return false
 			}
+
+			return true
 		}
-	}()
+
+		log.Println("[INFO]: sending whisper segments...")
+		select {
+		case <-ctx.Done():
+			return false
+		case c <- &Whisper{
+			attempt:      download.attempt,
+			VideoId:      videoId,
+			Video:        download.Video,
+			AudioURL:     download.AudioURL,
+			Segments:     segments,
+			Type:         download.Transcriber.Type(),
+			Retranscribe: download.Retranscribe,
+			Upgrade:      download.Upgrade,
+		}:
+			return true
+		}
+	}, func() { close(c) })
 
 	return c
 }
 
-func IndexWhispers(ctx context.Context, errs chan<- error, whispers <-chan *Whisper) {
-	go func() {
-		for {
-			cont := func() bool {
-				log.Println("[INFO]: waiting for next whisper to index...")
-				select {
-				case <-ctx.Done():
+// IndexWhispers indexes the whispers, the returned channel is closed when whispers is closed.
+func (p *Pipeline) IndexWhispers(ctx context.Context, errs chan<- error, whispers <-chan *Whisper) <-chan struct{} {
+	done := make(chan struct{})
+	s := p.newStage("index", p.opts.Index.Workers, func() int { return len(whispers) })
+	runStage(ctx, p, s, whispers, func(whisper *Whisper) bool {
+		log.Println("[INFO]: retrieved whisper to index...")
+
+		if err := p.indexWhisper(ctx, whisper); err != nil {
+			if errors.Is(err, errLostClaim) {
+				log.Printf("[WARN]: %v, discarding the transcript", err)
+				p.done(whisper.FailureId)
+				return true
+			}
+
+			var aerr *attemptError
+			if errors.As(err, &aerr) {
+				if err := p.retry(ctx, whisper.attempt, aerr); err != nil {
+					errs <- err
 					return false
-				case whisper, ok := <-whispers:
-					if !ok {
-						return false
-					}
-					log.Println("[INFO]: retrieved whisper to index...")
-
-					defer func() {
-						log.Printf("[INFO]: deleting file %s (cleanup)", whisper.Path)
-						if err := os.Remove(whisper.Path); err != nil {
-							errs <- fmt.Errorf("cleaning %s: %w", whisper.Path, err)
-						}
-					}()
-
-					log.Println("[INFO]: parsing output captions csv")
-					fh, err := os.Open(whisper.Path)
-					if err != nil {
-						errs <- fmt.Errorf("could not open %s: %w", whisper.Path, err)
-						return false
-					}
-					defer fh.Close()
-					r := csv.NewReader(fh)
-					r.ReuseRecord = true
-					r.FieldsPerRecord = 3
-					r.LazyQuotes = true
-
-					// Read and discard header row.
-					if _, err := r.Read(); err != nil {
-						errs <- fmt.Errorf("reading header row of csv: %w", err)
-						return false
-					}
-
-					tx, err := Db.Begin()
-					if err != nil {
-						errs <- fmt.Errorf("starting transaction: %w", err)
-						return false
-					}
-					defer tx.Rollback() // Rollback, ignore error which is returned if tx is committed.
-					qtx := Queries.WithTx(tx)
-
-					published, err := tube.ParsePublishedTime(whisper.Video.Snippet.PublishedAt)
-					if err != nil {
-						errs <- fmt.Errorf("parsing video published: %w", err)
-						return false
-					}
-
-					if err = qtx.CreateVideo(ctx, store.CreateVideoParams{
-						ID:                   whisper.VideoId,
-						ChannelID:            whisper.Video.Snippet.ChannelId,
-						PublishedAt:          published,
-						Title:                whisper.Video.Snippet.Title,
-						Description:          whisper.Video.Snippet.Description,
-						ThumbnailUrl:         tube.HighestResThumbnail(whisper.Video.Snippet.Thumbnails).Url,
-						SearchableTranscript: "",
-						TranscriptType:       string(store.WhisperBase),
-					}); err != nil {
-						errs <- fmt.Errorf("creating video: %w", err)
-						return false
-					}
-
-					searchable := strings.Builder{}
-					for {
-						row, err := r.Read()
-						if err != nil {
-							if errors.Is(err, io.EOF) {
-								break
-							}
-
-							log.Printf(
-								"[WARN]: reading csv failed, writing failed csv to failed-%s.csv and skipping this failure: %v",
-								whisper.VideoId,
-								err,
-							)
-							fh, err := os.Open(whisper.Path)
-							if err != nil {
-								errs <- fmt.Errorf("could not open %s: %w", whisper.Path, err)
-								return false
-							}
-							defer fh.Close()
-							csv, err := io.ReadAll(fh)
-							if err != nil {
-								errs <- fmt.Errorf("could not read full csv into memory: %w", err)
-								return false
-							}
-							err = os.WriteFile(
-								fmt.Sprintf("failed-%s.csv", whisper.VideoId),
-								csv,
-								0666,
-							)
-							if err != nil {
-								errs <- fmt.Errorf("writing failed csv: %w", err)
-								return false
-							}
-
-							return true
-						}
-
-						startMs, err := strconv.Atoi(row[0])
-						if err != nil {
-							errs <- fmt.Errorf(
-								"reading start ms from string %q in row %v: %w",
-								row[0],
-								row,
-								err,
-							)
-							return false
-						}
-
-						txt := strings.TrimSpace(row[2])
-
-						id, err := qtx.CreateTranscript(ctx, store.CreateTranscriptParams{
-							VideoID: whisper.VideoId,
-							Start:   int32((time.Duration(startMs) * time.Millisecond) / time.Second),
-							Text:    txt,
-						})
-						if err != nil {
-							errs <- fmt.Errorf("creating transcript entry for row %v: %w", row, err)
-							return false
-						}
-
-						searchable.WriteString(fmt.Sprintf("~%d~", id))
-						searchable.WriteString(stem.StemLine(txt))
-					}
-
-                    if err := qtx.SetSearchableTranscript(ctx, store.SetSearchableTranscriptParams{
-                    	ID:                   whisper.VideoId,
-                    	SearchableTranscript: searchable.String(),
-                    }); err != nil {
-                            errs <- fmt.Errorf("updating transcript: %w", err)
-                    }
-
-					if err := qtx.DeleteFailure(ctx, whisper.FailureId); err != nil {
-						errs <- fmt.Errorf("deleting indexed failure: %w", err)
-						return false
-					}
-
-					log.Println("[INFO]: saving to the database")
-					if err := tx.Commit(); err != nil {
-						errs <- fmt.Errorf("committing transaction: %w", err)
-						return false
-					}
-
-					log.Println("[INFO]: finished index...")
-					return true
 				}
-			}()
-			if !cont {
-				return
+
+				return true
 			}
+
+			errs <- err
+			return false
 		}
-	}()
+
+		p.done(whisper.FailureId)
+		p.progress.indexed.Add(1)
+		if duration, err := whisper.Video.Duration(); err == nil {
+			p.progress.audio.Add(int64(duration.Seconds()))
+		}
+		log.Println("[INFO]: finished index...")
+		return true
+	}, func() { close(done) })
+
+	return done
 }
 
-func cleanGlob(glob string, exceptions ...string) {
-	matches, err := fs.Glob(os.DirFS("."), glob)
+func (p *Pipeline) indexWhisper(ctx context.Context, whisper *Whisper) error {
+	published, err := tube.ParsePublishedTime(whisper.Video.Snippet.PublishedAt)
 	if err != nil {
-		log.Printf("[ERROR]: glob for files to delete failed: %v", err)
+		return &attemptError{fmt.Errorf("parsing video published: %w", err)}
 	}
 
-Outer:
-	for _, match := range matches {
-		for _, exception := range exceptions {
-			if match == exception {
-				continue Outer
+	return p.store.Tx(ctx, func(qtx store.Querier) error {
+		if whisper.Retranscribe {
+			replaced, err := archiveTranscript(ctx, qtx, whisper)
+			if err != nil {
+				return err
+			}
+
+			if !replaced {
+				return p.complete(ctx, qtx, whisper.FailureId)
+			}
+
+			log.Printf("[INFO]: replacing the transcript of %q", whisper.VideoId)
+			if err := qtx.DeleteTranscriptsOfVideo(ctx, whisper.VideoId); err != nil {
+				return fmt.Errorf("deleting old transcript: %w", err)
+			}
+
+			if err := qtx.SetTranscriptType(ctx, store.SetTranscriptTypeParams{
+				ID:             whisper.VideoId,
+				TranscriptType: string(whisper.Type),
+			}); err != nil {
+				return fmt.Errorf("updating transcript type: %w", err)
+			}
+		} else if err := qtx.CreateVideo(ctx, store.CreateVideoParams{
+			ID:                    whisper.VideoId,
+			ChannelID:             whisper.Video.Snippet.ChannelId,
+			PublishedAt:           published,
+			Title:                 whisper.Video.Snippet.Title,
+			Description:           whisper.Video.Snippet.Description,
+			ThumbnailUrl:          tube.HighestResThumbnail(whisper.Video.Snippet.Thumbnails).Url,
+			SearchableTranscript:  "",
+			TranscriptType:        string(whisper.Type),
+			SearchableTitle:       stem.StemText(whisper.Video.Snippet.Title),
+			SearchableDescription: stem.StemText(whisper.Video.Snippet.Description),
+			StemVersion:           stem.Version,
+			AudioUrl:              whisper.AudioURL,
+		}); err != nil {
+			return fmt.Errorf("creating video: %w", err)
+		}
+
+		searchable := strings.Builder{}
+		for _, segment := range whisper.Segments {
+			id, err := qtx.CreateTranscript(ctx, store.CreateTranscriptParams{
+				VideoID: whisper.VideoId,
+				Start:   int32(segment.Start / time.Second),
+				Text:    segment.Text,
+			})
+			if err != nil {
+				return fmt.Errorf("creating transcript entry for segment %v: %w", segment, err)
+			}
+
+			if err := createTranscriptDetails(ctx, qtx, id, segment); err != nil {
+				return err
 			}
+
+			searchable.WriteString(fmt.Sprintf("~%d~", id))
+			searchable.WriteString(stem.StemLine(segment.Text))
+		}
+
+		if err := qtx.SetSearchableTranscript(ctx, store.SetSearchableTranscriptParams{
+			ID:                   whisper.VideoId,
+			SearchableTranscript: searchable.String(),
+		}); err != nil {
+			return fmt.Errorf("updating transcript: %w", err)
+		}
+
+		if err := p.complete(ctx, qtx, whisper.FailureId); err != nil {
+			return err
+		}
+
+		log.Println("[INFO]: saving to the database")
+		return nil
+	})
+}
+
+// archiveTranscript stores the current transcript of the video as a store.TranscriptVersionReplaced version,
+// so it can be audited after it is replaced.
+//
+// For upgrades, the segments are compared to the current transcript first, see quality.Better,
+// if they are not better they are stored as a store.TranscriptVersionRejected version instead, and false is returned.
+func archiveTranscript(ctx context.Context, qtx store.Querier, whisper *Whisper) (bool, error) {
+	video, err := qtx.Video(ctx, whisper.VideoId)
+	if err != nil {
+		return false, fmt.Errorf("retrieving video: %w", err)
+	}
+
+	current, err := qtx.TranscriptsOfVideo(ctx, whisper.VideoId)
+	if err != nil {
+		return false, fmt.Errorf("retrieving old transcript: %w", err)
+	}
+
+	currentLines := make([]store.VersionLine, 0, len(current))
+	for _, transcript := range current {
+		currentLines = append(currentLines, store.VersionLine{Start: transcript.Start, Text: transcript.Text})
+	}
+
+	reason := fmt.Sprintf("retranscribed by %s", whisper.Type)
+	if whisper.Upgrade {
+		candidateLines := make([]store.VersionLine, 0, len(whisper.Segments))
+		for _, segment := range whisper.Segments {
+			candidateLines = append(candidateLines, store.VersionLine{
+				Start: int32(segment.Start / time.Second),
+				Text:  segment.Text,
+			})
 		}
 
-		log.Printf("[INFO]: deleting file %q (cleanup)", match)
-		if err := os.Remove(match); err != nil {
-			log.Printf("[ERROR]: could not delete %q: %v", match, err)
+		better, why := quality.Better(measure(currentLines), measure(candidateLines))
+		if !better {
+			log.Printf("[INFO]: keeping the automatic captions of %q: %s", whisper.VideoId, why)
+			return false, createTranscriptVersion(ctx, qtx, store.CreateTranscriptVersionParams{
+				VideoID:        whisper.VideoId,
+				TranscriptType: string(whisper.Type),
+				Status:         string(store.TranscriptVersionRejected),
+				Reason:         why,
+			}, candidateLines)
 		}
+
+		log.Printf("[INFO]: upgrading the automatic captions of %q: %s", whisper.VideoId, why)
+		reason = fmt.Sprintf("upgraded to %s: %s", whisper.Type, why)
+	}
+
+	return true, createTranscriptVersion(ctx, qtx, store.CreateTranscriptVersionParams{
+		VideoID:        whisper.VideoId,
+		TranscriptType: video.TranscriptType,
+		Status:         string(store.TranscriptVersionReplaced),
+		Reason:         reason,
+	}, currentLines)
+}
+
+func measure(lines []store.VersionLine) quality.Metrics {
+	texts := make([]string, 0, len(lines))
+	for _, line := range lines {
+		texts = append(texts, line.Text)
+	}
+
+	return quality.Measure(texts)
+}
+
+func createTranscriptVersion(
+	ctx context.Context,
+	qtx store.Querier,
+	arg store.CreateTranscriptVersionParams,
+	lines []store.VersionLine,
+) error {
+	encoded, err := json.Marshal(lines)
+	if err != nil {
+		return fmt.Errorf("marshalling transcript version: %w", err)
+	}
+	arg.Lines = encoded
+
+	if err := qtx.CreateTranscriptVersion(ctx, arg); err != nil {
+		return fmt.Errorf("creating %s transcript version: %w", arg.Status, err)
+	}
+
+	return nil
+}
+
+// createTranscriptDetails stores the confidence, words and speaker of the segment, if the transcriber reported them.
+func createTranscriptDetails(ctx context.Context, qtx store.Querier, id int64, segment transcribe.Segment) error {
+	if segment.Confidence == 0 && segment.Words == nil && segment.Speaker == "" {
+		return nil
 	}
+
+	words := make([]store.Word, 0, len(segment.Words))
+	for _, word := range segment.Words {
+		words = append(words, store.Word{
+			Start: int32(word.Start / time.Millisecond),
+			End:   int32(word.End / time.Millisecond),
+			Text:  word.Text,
+		})
+	}
+
+	encoded, err := json.Marshal(words)
+	if err != nil {
+		return fmt.Errorf("marshalling words of transcript %d: %w", id, err)
+	}
+
+	if err := qtx.CreateTranscriptDetails(ctx, store.CreateTranscriptDetailsParams{
+		TranscriptID: id,
+		Confidence:   float32(segment.Confidence),
+		Words:        encoded,
+		Speaker:      segment.Speaker,
+	}); err != nil {
+		return fmt.Errorf("creating details of transcript %d: %w", id, err)
+	}
+
+	return nil
 }
 
-func handleExecErr(id string, err error, errs chan<- error, extra ...string) {
+// execErr describes the error of running the command id, returning nil if the context was cancelled.
+func execErr(id string, err error, extra ...string) error {
 	var exitErr *exec.ExitError
 	if errors.As(err, &exitErr) {
 		if exitErr.ExitCode() == -1 { // context cancelled.
 			log.Printf("[WARN]: %s: context cancelled", id)
-			return
+			return nil
 		}
 
-		errs <- fmt.Errorf(
+		return fmt.Errorf(
 			id+": exit code %d and stderr %q and extra: %q: %w",
 			exitErr.ExitCode(),
 			string(exitErr.Stderr),
 			strings.Join(extra, ", "),
 			err,
 		)
-		return
 	}
 
-	errs <- fmt.Errorf(id+": unexpected err: %w", err)
+	return fmt.Errorf(id+": unexpected err: %w", err)
 }
@@ -0,0 +1,73 @@
+package failures
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/store"
+)
+
+// progress counts what the pipeline did since it started.
+type progress struct {
+	start   time.Time
+	indexed atomic.Int64
+	audio   atomic.Int64 // Seconds of audio of the indexed videos.
+	failed  atomic.Int64 // Attempts that failed or were postponed.
+}
+
+// report logs the throughput of the pipeline, the failures of the types that are left,
+// of the channel if one is selected, an estimate of when they are done, and what each stage is doing.
+func (p *Pipeline) report(ctx context.Context, types []store.FailureType, channelID string) error {
+	var left int64
+	for _, typ := range types {
+		count, err := p.store.CountFailures(ctx, store.CountFailuresParams{
+			Type:      string(typ),
+			ChannelID: sql.NullString{String: channelID, Valid: channelID != ""},
+		})
+		if err != nil {
+			return fmt.Errorf("counting failures: %w", err)
+		}
+
+		left += count
+	}
+
+	elapsed := time.Since(p.progress.start)
+	indexed := p.progress.indexed.Load()
+	audio := time.Duration(p.progress.audio.Load()) * time.Second
+	perHour := float64(indexed) / elapsed.Hours()
+
+	// The failures that are left include the ones that are waiting for their next attempt,
+	// so this is an estimate of when the queue is empty at the current rate.
+	eta := "unknown"
+	if indexed > 0 {
+		eta = time.Duration(float64(left) / perHour * float64(time.Hour)).Round(time.Minute).String()
+	}
+
+	log.Printf(
+		"[INFO]: indexed %d videos in %s (%.1f per hour) with %s of audio (%.1fx realtime), %d failed attempts; %d failures left, ETA %s",
+		indexed,
+		elapsed.Round(time.Second),
+		perHour,
+		audio,
+		audio.Seconds()/elapsed.Seconds(),
+		p.progress.failed.Load(),
+		left,
+		eta,
+	)
+
+	p.stageMu.Lock()
+	defer p.stageMu.Unlock()
+
+	stages := make([]string, 0, len(p.stageList))
+	for _, s := range p.stageList {
+		stages = append(stages, fmt.Sprintf("%s %d/%d busy %d queued", s.name, s.busy.Load(), s.workers, s.queued()))
+	}
+	log.Printf("[INFO]: stages: %s", strings.Join(stages, ", "))
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package failures
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/store"
+)
+
+// maxBackoff caps the time between attempts at a failure.
+const maxBackoff = 7 * 24 * time.Hour
+
+// Backoff returns how long to wait after the given amount of failed attempts,
+// base is doubled for each attempt after the first.
+func Backoff(base time.Duration, attempts int32) time.Duration {
+	d := base
+	for n := int32(1); n < attempts && d < maxBackoff; n++ {
+		d *= 2
+	}
+
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+
+	return d
+}
+
+// errLostClaim is returned when the failure is no longer claimed by this worker.
+var errLostClaim = errors.New("lost claim")
+
+// attempt identifies a failure that is being processed by the pipeline.
+type attempt struct {
+	FailureId int64
+	Attempts  int32 // Amount of failed attempts before this one.
+}
+
+// attemptError is an error with a single failure, it is recorded on the failure
+// and the failure is retried, instead of stopping the pipeline.
+type attemptError struct {
+	err error
+}
+
+func (e *attemptError) Error() string {
+	return e.err.Error()
+}
+
+func (e *attemptError) Unwrap() error {
+	return e.err
+}
+
+// retry records the failed attempt, scheduling the next one using Backoff.
+// After Options.MaxAttempts attempts, the failure is marked dead instead.
+func (p *Pipeline) retry(ctx context.Context, a attempt, cause error) error {
+	attempts := a.Attempts + 1
+	if int(attempts) >= p.opts.MaxAttempts {
+		log.Printf("[WARN]: attempt %d at failure %d failed, giving up: %v", attempts, a.FailureId, cause)
+		return p.failAttempt(ctx, a.FailureId, attempts, cause, 0, true)
+	}
+
+	backoff := Backoff(p.opts.RetryBackoff, attempts)
+	log.Printf(
+		"[WARN]: attempt %d at failure %d failed, retrying in %s: %v",
+		attempts,
+		a.FailureId,
+		backoff,
+		cause,
+	)
+	return p.failAttempt(ctx, a.FailureId, attempts, cause, backoff, false)
+}
+
+// postpone schedules the next attempt after Options.RetryBackoff, without counting this one,
+// for causes that resolve themselves, like a live stream that has not ended.
+func (p *Pipeline) postpone(ctx context.Context, a attempt, cause error) error {
+	log.Printf("[INFO]: postponing failure %d for %s: %v", a.FailureId, p.opts.RetryBackoff, cause)
+	return p.failAttempt(ctx, a.FailureId, a.Attempts, cause, p.opts.RetryBackoff, false)
+}
+
+// kill marks the failure dead, for causes that another attempt won't fix.
+func (p *Pipeline) kill(ctx context.Context, a attempt, cause error) error {
+	log.Printf("[WARN]: failure %d can't be processed, marking it dead: %v", a.FailureId, cause)
+	return p.failAttempt(ctx, a.FailureId, a.Attempts+1, cause, 0, true)
+}
+
+// failAttempt records the attempt, the next attempt is backoff after now, by the clock of the database,
+// which is also what claims are compared against.
+// The attempt is only recorded if the failure is still claimed by this worker.
+func (p *Pipeline) failAttempt(
+	ctx context.Context,
+	id int64,
+	attempts int32,
+	cause error,
+	backoff time.Duration,
+	dead bool,
+) error {
+	n, err := p.store.FailAttempt(ctx, store.FailAttemptParams{
+		Attempts:       attempts,
+		LastError:      cause.Error(),
+		BackoffSeconds: int32(backoff.Seconds()),
+		Dead:           dead,
+		ID:             id,
+		WorkerID:       p.opts.WorkerID,
+	})
+	if err != nil {
+		return fmt.Errorf("recording attempt at failure %d: %w", id, err)
+	}
+
+	p.done(id)
+	if n == 0 {
+		log.Printf("[WARN]: lost claim on failure %d, not recording the attempt", id)
+		return nil
+	}
+
+	p.progress.failed.Add(1)
+	return nil
+}
@@ -0,0 +1,31 @@
+package failures_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/failures"
+)
+
+func TestBackoff(t *testing.T) {
+	cases := []struct {
+		base     time.Duration
+		attempts int32
+		want     time.Duration
+	}{
+		{time.Hour, 0, time.Hour},
+		{time.Hour, 1, time.Hour},
+		{time.Hour, 2, 2 * time.Hour},
+		{time.Hour, 4, 8 * time.Hour},
+		{time.Hour, 8, 128 * time.Hour},
+		{time.Hour, 9, 7 * 24 * time.Hour}, // 256 hours is capped at a week.
+		{time.Hour, 1000, 7 * 24 * time.Hour},
+		{10 * 24 * time.Hour, 1, 7 * 24 * time.Hour},
+	}
+
+	for _, c := range cases {
+		if got := failures.Backoff(c.base, c.attempts); got != c.want {
+			t.Errorf("Backoff(%s, %d) = %s, want %s", c.base, c.attempts, got, c.want)
+		}
+	}
+}
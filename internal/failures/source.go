@@ -0,0 +1,203 @@
+package failures
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/feed"
+	"github.com/laytan/youtupedia/internal/index"
+	"github.com/laytan/youtupedia/internal/library"
+	"github.com/laytan/youtupedia/internal/store"
+	"github.com/laytan/youtupedia/internal/tube"
+)
+
+// Source retrieves the videos of a channel and their audio, depending on the store.Source of the channel.
+type Source interface {
+	// Video retrieves the details of the video, and where its audio is played, which is empty for YouTube videos.
+	// Podcast episodes and local files are described like YouTube videos, so they are filtered and indexed alike.
+	Video(ctx context.Context, channel *store.Channel, id string) (video *tube.ResVideo, audioURL string, err error)
+
+	// Audio streams the audio of the video, in any format ffmpeg decodes.
+	// Closing it waits for the download to stop, returning why it failed.
+	Audio(ctx context.Context, id string, audioURL string) (io.ReadCloser, error)
+
+	// Prober tells apart Shorts, live streams and premieres, for the filters of the channel.
+	index.Prober
+}
+
+// source returns the Source of the videos of the channel.
+func (p *Pipeline) source(channel *store.Channel) Source {
+	switch store.Source(channel.Source) {
+	case store.SourcePodcast:
+		return p.feeds
+	case store.SourceDirectory:
+		return &dirSource{store: p.store, channelID: channel.ID}
+	}
+
+	return &youtubeSource{YouTube: p.yt, ytDlpBin: p.opts.YtDlpBin}
+}
+
+// youtubeSource retrieves videos from the YouTube API, and their audio using yt-dlp.
+type youtubeSource struct {
+	YouTube
+	ytDlpBin string
+}
+
+func (s *youtubeSource) Video(ctx context.Context, channel *store.Channel, id string) (*tube.ResVideo, string, error) {
+	video, err := s.YouTube.Video(id)
+	return video, "", err
+}
+
+func (s *youtubeSource) Audio(ctx context.Context, id string, audioURL string) (io.ReadCloser, error) {
+	download := exec.CommandContext(
+		ctx,
+		s.ytDlpBin,
+		"-f",
+		"bestaudio",
+		"--ignore-config",
+		"--no-progress",
+		"--quiet",
+		"--output",
+		"-",
+		"https://youtube.com/watch?v="+id,
+	)
+	stderr := &bytes.Buffer{}
+	download.Stderr = stderr
+	stdout, err := download.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("yt-dlp stdout: %w", err)
+	}
+
+	if err := download.Start(); err != nil {
+		return nil, fmt.Errorf("starting yt-dlp: %w", err)
+	}
+
+	return &commandReader{ReadCloser: stdout, name: "yt-dlp", cmd: download, stderr: stderr}, nil
+}
+
+// commandReader reads the stdout of a started command.
+type commandReader struct {
+	io.ReadCloser
+	name   string
+	cmd    *exec.Cmd
+	stderr *bytes.Buffer
+}
+
+// Close stops reading, which stops the command if it is still writing, and waits for it to exit.
+func (r *commandReader) Close() error {
+	r.ReadCloser.Close()
+	if err := r.cmd.Wait(); err != nil {
+		return execErr(r.name, err, r.stderr.String())
+	}
+
+	return nil
+}
+
+// feedTTL is how long a fetched feed is used for the episodes in it,
+// episodes of the same podcast are often processed together.
+const feedTTL = 10 * time.Minute
+
+// feedSource retrieves podcast episodes from the feed of their channel, and their audio from the enclosure.
+type feedSource struct {
+	index.NoProber
+	client *feed.Client
+
+	mu      sync.Mutex
+	fetched map[string]fetchedFeed // By the URL of the feed.
+}
+
+type fetchedFeed struct {
+	feed *feed.Feed
+	at   time.Time
+}
+
+func (s *feedSource) Video(ctx context.Context, channel *store.Channel, id string) (*tube.ResVideo, string, error) {
+	podcast, err := s.fetch(ctx, channel.FeedUrl)
+	if err != nil {
+		return nil, "", err
+	}
+
+	episode, err := podcast.Episode(id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return episode.Video(channel.ID), episode.AudioURL, nil
+}
+
+func (s *feedSource) Audio(ctx context.Context, id string, audioURL string) (io.ReadCloser, error) {
+	return s.client.Open(ctx, audioURL)
+}
+
+// fetch returns the feed at url, fetching it if it was not fetched in the last feedTTL.
+func (s *feedSource) fetch(ctx context.Context, url string) (*feed.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if fetched, ok := s.fetched[url]; ok && time.Since(fetched.at) < feedTTL {
+		return fetched.feed, nil
+	}
+
+	podcast, err := s.client.Fetch(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.fetched == nil {
+		s.fetched = make(map[string]fetchedFeed)
+	}
+	s.fetched[url] = fetchedFeed{feed: podcast, at: time.Now()}
+
+	return podcast, nil
+}
+
+// dirSource retrieves the files of a media library by the store.MediaFile of the video.
+type dirSource struct {
+	index.NoProber
+	store     store.Store
+	channelID string
+}
+
+func (s *dirSource) Video(ctx context.Context, channel *store.Channel, id string) (*tube.ResVideo, string, error) {
+	file, err := s.file(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return file.Video(channel.ID, id), store.PlayerPath(id), nil
+}
+
+func (s *dirSource) Audio(ctx context.Context, id string, audioURL string) (io.ReadCloser, error) {
+	file, err := s.file(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Open(file.Path)
+}
+
+func (s *dirSource) file(ctx context.Context, id string) (*library.File, error) {
+	mf, err := s.store.MediaFile(ctx, store.MediaFileParams{ChannelID: s.channelID, VideoID: id})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("media file of %q: %w", id, library.ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("retrieving media file of %q: %w", id, err)
+	}
+
+	file, err := library.Stat(mf.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &file, nil
+}
@@ -0,0 +1,80 @@
+package failures
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// StageOptions configure a stage of the pipeline.
+type StageOptions struct {
+	Workers int // Videos processed at the same time, defaults to 1.
+	Queue   int // Videos that can wait for a worker of the stage, 0 hands every video directly to a worker.
+}
+
+func (o *StageOptions) setDefaults() {
+	if o.Workers <= 0 {
+		o.Workers = 1
+	}
+
+	if o.Queue < 0 {
+		o.Queue = 0
+	}
+}
+
+// stage is a running stage of the pipeline, its counters are logged by report.
+type stage struct {
+	name    string
+	workers int
+	busy    atomic.Int64 // Workers that are processing a video.
+	queued  func() int   // Videos waiting for a worker.
+}
+
+// newStage registers a stage, so it is included in the report.
+func (p *Pipeline) newStage(name string, workers int, queued func() int) *stage {
+	s := &stage{name: name, workers: workers, queued: queued}
+
+	p.stageMu.Lock()
+	defer p.stageMu.Unlock()
+	p.stageList = append(p.stageList, s)
+
+	return s
+}
+
+// runStage starts the workers of the stage, calling work for each item of in, until in is closed or ctx is done.
+// A worker stops when work returns false. When all workers stopped, stopped is called.
+func runStage[T any](ctx context.Context, p *Pipeline, s *stage, in <-chan T, work func(item T) bool, stopped func()) {
+	var workers sync.WaitGroup
+	for i := 0; i < s.workers; i++ {
+		workers.Add(1)
+		p.stages.Add(1)
+		go func() {
+			defer p.stages.Done()
+			defer workers.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case item, ok := <-in:
+					if !ok {
+						return
+					}
+
+					s.busy.Add(1)
+					cont := work(item)
+					s.busy.Add(-1)
+					if !cont {
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	p.stages.Add(1)
+	go func() {
+		defer p.stages.Done()
+		workers.Wait()
+		stopped()
+	}()
+}
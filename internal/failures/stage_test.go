@@ -0,0 +1,126 @@
+package failures_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/failures"
+)
+
+// waitFor polls cond until it is true, failing the test after a second.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRunStageWorkers(t *testing.T) {
+	in := make(chan int, 5)
+	for i := 0; i < 5; i++ {
+		in <- i
+	}
+	close(in)
+
+	release := make(chan struct{})
+	var processed, stopped atomic.Int64
+	s := failures.RunStage(context.Background(), 3, in, func(int) bool {
+		<-release
+		processed.Add(1)
+		return true
+	}, func() { stopped.Add(1) })
+
+	waitFor(t, "3 busy workers", func() bool { return s.Busy() == 3 })
+	if q := s.Queued(); q != 2 {
+		t.Errorf("Queued() = %d with 3 busy workers, want 2", q)
+	}
+
+	close(release)
+	s.Wait()
+
+	if n := processed.Load(); n != 5 {
+		t.Errorf("processed %d items, want 5", n)
+	}
+
+	if n := s.Busy(); n != 0 {
+		t.Errorf("Busy() = %d after stopping, want 0", n)
+	}
+
+	if n := stopped.Load(); n != 1 {
+		t.Errorf("stopped called %d times, want 1", n)
+	}
+}
+
+func TestRunStageStop(t *testing.T) {
+	cases := []struct {
+		name      string
+		workers   int
+		stopAt    int // The item that work returns false for.
+		processed int64
+		queued    int
+	}{
+		{"single worker stops", 1, 1, 2, 2},
+		{"every worker stops", 2, -1, 2, 2},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			in := make(chan int, 4)
+			for i := 0; i < 4; i++ {
+				in <- i
+			}
+			defer close(in)
+
+			var processed, stopped atomic.Int64
+			started := make(chan struct{})
+			s := failures.RunStage(context.Background(), c.workers, in, func(item int) bool {
+				n := processed.Add(1)
+				if c.stopAt < 0 {
+					// Every worker stops after its first item, once both are busy.
+					if n == int64(c.workers) {
+						close(started)
+					}
+					<-started
+					return false
+				}
+
+				return item != c.stopAt
+			}, func() { stopped.Add(1) })
+			s.Wait()
+
+			if n := processed.Load(); n != c.processed {
+				t.Errorf("processed %d items, want %d", n, c.processed)
+			}
+
+			if q := s.Queued(); q != c.queued {
+				t.Errorf("Queued() = %d after stopping, want %d", q, c.queued)
+			}
+
+			if n := stopped.Load(); n != 1 {
+				t.Errorf("stopped called %d times, want 1", n)
+			}
+		})
+	}
+}
+
+func TestRunStageCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan int)
+	defer close(in)
+
+	var stopped atomic.Int64
+	s := failures.RunStage(ctx, 2, in, func(int) bool { return true }, func() { stopped.Add(1) })
+	cancel()
+	s.Wait()
+
+	if n := stopped.Load(); n != 1 {
+		t.Errorf("stopped called %d times, want 1", n)
+	}
+}
@@ -0,0 +1,160 @@
+package failures_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/failures"
+	"github.com/laytan/youtupedia/internal/store"
+	"github.com/laytan/youtupedia/internal/transcribe"
+	"github.com/laytan/youtupedia/internal/tube"
+)
+
+// upgradeStore is an in memory store.Store with the queries used to index an upgrade,
+// the embedded store.Store is nil, so other queries panic.
+type upgradeStore struct {
+	store.Store
+
+	video       store.Video
+	transcripts []store.Transcript
+	versions    []store.CreateTranscriptVersionParams
+	failures    map[int64]string // Failure ID to the worker that claimed it.
+}
+
+func (s *upgradeStore) Tx(ctx context.Context, f func(q store.Querier) error) error {
+	return f(s)
+}
+
+func (s *upgradeStore) Video(ctx context.Context, id string) (store.Video, error) {
+	return s.video, nil
+}
+
+func (s *upgradeStore) TranscriptsOfVideo(ctx context.Context, videoID string) ([]store.Transcript, error) {
+	return s.transcripts, nil
+}
+
+func (s *upgradeStore) CreateTranscriptVersion(ctx context.Context, arg store.CreateTranscriptVersionParams) error {
+	s.versions = append(s.versions, arg)
+	return nil
+}
+
+func (s *upgradeStore) DeleteTranscriptsOfVideo(ctx context.Context, videoID string) error {
+	s.transcripts = nil
+	return nil
+}
+
+func (s *upgradeStore) SetTranscriptType(ctx context.Context, arg store.SetTranscriptTypeParams) error {
+	s.video.TranscriptType = arg.TranscriptType
+	return nil
+}
+
+func (s *upgradeStore) CreateTranscript(ctx context.Context, arg store.CreateTranscriptParams) (int64, error) {
+	s.transcripts = append(s.transcripts, store.Transcript{
+		ID:      int64(len(s.transcripts) + 1),
+		VideoID: arg.VideoID,
+		Start:   arg.Start,
+		Text:    arg.Text,
+	})
+	return int64(len(s.transcripts)), nil
+}
+
+func (s *upgradeStore) SetSearchableTranscript(ctx context.Context, arg store.SetSearchableTranscriptParams) error {
+	s.video.SearchableTranscript = arg.SearchableTranscript
+	return nil
+}
+
+func (s *upgradeStore) CompleteFailure(ctx context.Context, arg store.CompleteFailureParams) (int64, error) {
+	if s.failures[arg.ID] != arg.WorkerID {
+		return 0, nil
+	}
+
+	delete(s.failures, arg.ID)
+	return 1, nil
+}
+
+func TestIndexUpgrade(t *testing.T) {
+	captions := "so today we are going to talk about dogs and why they like to run"
+	cases := []struct {
+		name      string
+		candidate string
+		want      store.TranscriptVersionStatus
+		wantType  store.TranscriptType // Of the video after indexing.
+		wantText  string
+	}{
+		{"better", "So today, we are going to talk about dogs. And why they like to run!", store.TranscriptVersionReplaced, "fake:fake", "So today, we are going to talk about dogs. And why they like to run!"},
+		{"worse", "dogs", store.TranscriptVersionRejected, store.TubeAuto, captions},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := &upgradeStore{
+				video:       store.Video{ID: "video", ChannelID: "channel", TranscriptType: string(store.TubeAuto)},
+				transcripts: []store.Transcript{{ID: 1, VideoID: "video", Text: captions}},
+				failures:    map[int64]string{1: "worker"},
+			}
+
+			p := failures.New(s, nil, failures.Options{WorkerID: "worker", WorkDir: t.TempDir()})
+
+			video := &tube.ResVideo{Id: "video"}
+			video.Snippet.ChannelId = "channel"
+			video.Snippet.PublishedAt = "2023-05-20T10:00:00Z"
+			whisper := &failures.Whisper{
+				VideoId:      "video",
+				Video:        video,
+				Segments:     []transcribe.Segment{{End: 5 * time.Second, Text: c.candidate}},
+				Type:         (&transcribe.Fake{}).Type(),
+				Retranscribe: true,
+				Upgrade:      true,
+			}
+			whisper.FailureId = 1
+
+			whispers := make(chan *failures.Whisper, 1)
+			whispers <- whisper
+			close(whispers)
+
+			errs := make(chan error, 1)
+			<-p.IndexWhispers(context.Background(), errs, whispers)
+			select {
+			case err := <-errs:
+				t.Fatal(err)
+			default:
+			}
+
+			if len(s.versions) != 1 || s.versions[0].Status != string(c.want) {
+				t.Fatalf("created versions %+v, want one %s version", s.versions, c.want)
+			}
+
+			// A replaced version keeps the captions, a rejected one the candidate.
+			var lines []store.VersionLine
+			if err := json.Unmarshal(s.versions[0].Lines, &lines); err != nil {
+				t.Fatal(err)
+			}
+
+			wantLines := []store.VersionLine{{Text: captions}}
+			wantVersionType := store.TubeAuto
+			if c.want == store.TranscriptVersionRejected {
+				wantLines = []store.VersionLine{{Text: c.candidate}}
+				wantVersionType = "fake:fake"
+			}
+
+			if !reflect.DeepEqual(lines, wantLines) || s.versions[0].TranscriptType != string(wantVersionType) {
+				t.Errorf("%s version of %s = %+v, want %+v of %s", c.want, s.versions[0].TranscriptType, lines, wantLines, wantVersionType)
+			}
+
+			if s.video.TranscriptType != string(c.wantType) {
+				t.Errorf("video has transcript type %q, want %q", s.video.TranscriptType, c.wantType)
+			}
+
+			if len(s.transcripts) != 1 || s.transcripts[0].Text != c.wantText {
+				t.Errorf("video has transcripts %+v, want %q", s.transcripts, c.wantText)
+			}
+
+			if len(s.failures) != 0 {
+				t.Errorf("failures %v are left, want the failure to be deleted", s.failures)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package failures
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/audio"
+	"github.com/laytan/youtupedia/internal/store"
+)
+
+// bytesPerSecond of the resampled audio, used to estimate the disk space a download needs.
+const bytesPerSecond = audio.WhisperSampleRate * 2
+
+// jobDir is the directory the files of processing the failure are written to.
+func (p *Pipeline) jobDir(id int64) string {
+	return filepath.Join(p.opts.WorkDir, strconv.FormatInt(id, 10))
+}
+
+// createJobDir creates an empty job directory for the failure.
+func (p *Pipeline) createJobDir(id int64) (string, error) {
+	dir := p.jobDir(id)
+	if err := os.RemoveAll(dir); err != nil {
+		return "", fmt.Errorf("removing old job directory %s: %w", dir, err)
+	}
+
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return "", fmt.Errorf("creating job directory %s: %w", dir, err)
+	}
+
+	return dir, nil
+}
+
+// removeJobDir removes the job directory of the failure, if there is one.
+func (p *Pipeline) removeJobDir(id int64) {
+	dir := p.jobDir(id)
+	if err := os.RemoveAll(dir); err != nil {
+		log.Printf("[WARN]: removing job directory %s: %v", dir, err)
+	}
+}
+
+// sweep creates the work directory, and removes the job directories that were left behind by workers that stopped unexpectedly.
+// Directories of failures that are claimed by another worker, with a lease that has not expired, are kept.
+func (p *Pipeline) sweep(ctx context.Context) error {
+	if err := os.MkdirAll(p.opts.WorkDir, 0o755); err != nil {
+		return fmt.Errorf("creating work directory %s: %w", p.opts.WorkDir, err)
+	}
+
+	entries, err := os.ReadDir(p.opts.WorkDir)
+	if err != nil {
+		return fmt.Errorf("reading work directory %s: %w", p.opts.WorkDir, err)
+	}
+
+	for _, entry := range entries {
+		id, err := strconv.ParseInt(entry.Name(), 10, 64)
+		if err != nil || !entry.IsDir() {
+			log.Printf("[WARN]: unexpected %q in work directory %s, leaving it", entry.Name(), p.opts.WorkDir)
+			continue
+		}
+
+		claimed, err := p.store.ClaimedByOther(ctx, store.ClaimedByOtherParams{ID: id, WorkerID: p.opts.WorkerID})
+		if err != nil {
+			return fmt.Errorf("checking claim on failure %d of job directory: %w", id, err)
+		}
+
+		if claimed {
+			continue
+		}
+
+		log.Printf("[INFO]: removing orphaned job directory of failure %d", id)
+		p.removeJobDir(id)
+	}
+
+	return nil
+}
+
+// checkDiskSpace returns an error if the work directory does not have room for
+// the audio of a video of the given duration, while keeping Options.MinFreeSpace free.
+func (p *Pipeline) checkDiskSpace(duration time.Duration) error {
+	free, err := freeSpace(p.opts.WorkDir)
+	if err != nil {
+		log.Printf("[WARN]: checking free disk space: %v", err)
+		return nil
+	}
+
+	needed := int64(duration.Seconds()*bytesPerSecond) + p.opts.MinFreeSpace
+	if free < needed {
+		return fmt.Errorf(
+			"not enough disk space in %s, %d MB free but %d MB needed",
+			p.opts.WorkDir,
+			free/1_000_000,
+			needed/1_000_000,
+		)
+	}
+
+	return nil
+}
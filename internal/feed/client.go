@@ -0,0 +1,83 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// Client reads feeds and their audio from http and https URLs, file URLs, and paths.
+type Client struct {
+	HTTP *http.Client // Defaults to http.DefaultClient.
+}
+
+// Fetch reads and parses the feed at location.
+func (c *Client) Fetch(ctx context.Context, location string) (*Feed, error) {
+	body, err := c.Open(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return Parse(body, location)
+}
+
+// Open opens the feed or audio file at location.
+func (c *Client) Open(ctx context.Context, location string) (io.ReadCloser, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("parsing location %q: %w", location, err)
+	}
+
+	switch u.Scheme {
+	case "":
+		return openFile(location)
+	case "file":
+		return openFile(u.Path)
+	case "http", "https":
+	default:
+		return nil, fmt.Errorf("location %q: unsupported scheme %q", location, u.Scheme)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request for %q: %w", location, err)
+	}
+
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("requesting %q: %w", location, err)
+	}
+
+	switch {
+	case res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusGone:
+		res.Body.Close()
+		return nil, fmt.Errorf("requesting %q: status %d: %w", location, res.StatusCode, ErrNotFound)
+	case res.StatusCode != http.StatusOK:
+		res.Body.Close()
+		return nil, fmt.Errorf("requesting %q: unexpected status %d", location, res.StatusCode)
+	}
+
+	return res.Body, nil
+}
+
+func openFile(path string) (io.ReadCloser, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("opening %s: %w", path, ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+
+	return fh, nil
+}
@@ -0,0 +1,270 @@
+// Package feed reads podcast RSS feeds and their audio, from the web or from disk.
+package feed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/tube"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// Feed is a podcast.
+type Feed struct {
+	URL         string // Where the feed was read from.
+	Title       string
+	Description string
+	Image       string
+	Episodes    []Episode // Newest first, only episodes with audio.
+}
+
+// Episode is an item of a Feed.
+type Episode struct {
+	ID          string // See EpisodeID.
+	GUID        string // The enclosure URL if the item has no GUID.
+	Title       string
+	Description string
+	Published   time.Time
+	AudioURL    string        // The enclosure, relative to the feed if it is relative in the feed.
+	Duration    time.Duration // Zero if the feed does not specify it.
+	Image       string        // The image of the feed if the episode has none.
+}
+
+// Episode returns the episode with the ID, see EpisodeID.
+func (f *Feed) Episode(id string) (*Episode, error) {
+	for n := range f.Episodes {
+		if f.Episodes[n].ID == id {
+			return &f.Episodes[n], nil
+		}
+	}
+
+	return nil, fmt.Errorf("episode %q in feed %q: %w", id, f.URL, ErrNotFound)
+}
+
+// ChannelID returns the ID of the channel of the feed, feeds are identified by their URL.
+func ChannelID(feedURL string) string {
+	return "feed-" + hash(feedURL)
+}
+
+// EpisodeID returns the video ID of the episode, GUIDs are only unique within their feed.
+func EpisodeID(feedURL string, guid string) string {
+	return "episode-" + hash(feedURL+"\n"+guid)
+}
+
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Video describes the episode like a YouTube video, so it is filtered and indexed like one.
+func (e *Episode) Video(channelID string) *tube.ResVideo {
+	video := &tube.ResVideo{Id: e.ID}
+	video.Snippet.ChannelId = channelID
+	video.Snippet.Title = e.Title
+	video.Snippet.Description = e.Description
+	video.Snippet.PublishedAt = e.Published.UTC().Format("2006-01-02T15:04:05Z")
+	video.Snippet.LiveBroadcastContent = "none"
+	if e.Image != "" {
+		video.Snippet.Thumbnails = map[string]tube.Thumbnail{"high": {Url: e.Image}}
+	}
+
+	if e.Duration > 0 {
+		video.ContentDetails.Duration = fmt.Sprintf("PT%dS", int(e.Duration.Seconds()))
+	}
+
+	return video
+}
+
+const itunes = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+
+// rss is an RSS 2.0 feed with iTunes extensions, only the fields that are used.
+// The namespaced fields come first, fields without a namespace match elements of any namespace.
+type rss struct {
+	Channel struct {
+		ItunesImage image  `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+		Title       string `xml:"title"`
+		Description string `xml:"description"`
+		Image       struct {
+			URL string `xml:"url"`
+		} `xml:"image"`
+		Items []item `xml:"item"`
+	} `xml:"channel"`
+}
+
+type item struct {
+	ItunesImage    image  `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+	ItunesDuration string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	GUID           string `xml:"guid"`
+	Title          string `xml:"title"`
+	Description    string `xml:"description"`
+	PubDate        string `xml:"pubDate"`
+	Enclosure      struct {
+		URL string `xml:"url,attr"`
+	} `xml:"enclosure"`
+}
+
+type image struct {
+	Href string `xml:"href,attr"`
+}
+
+// Parse reads the RSS feed, location is where it was read from, an URL or a path,
+// relative URLs in the feed are resolved against it.
+// Feeds from the web can only refer to http and https URLs, episodes with other enclosures are skipped,
+// paths and file URLs are only followed in feeds on disk.
+func Parse(r io.Reader, location string) (*Feed, error) {
+	var doc rss
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parsing feed %q: %w", location, err)
+	}
+
+	feed := &Feed{
+		URL:         location,
+		Title:       strings.TrimSpace(doc.Channel.Title),
+		Description: strings.TrimSpace(doc.Channel.Description),
+		Image:       resolve(location, firstNonEmpty(doc.Channel.ItunesImage.Href, doc.Channel.Image.URL)),
+	}
+
+	for _, it := range doc.Channel.Items {
+		audio := resolve(location, it.Enclosure.URL)
+		if audio == "" {
+			continue
+		}
+
+		published, err := parseDate(it.PubDate)
+		if err != nil {
+			return nil, fmt.Errorf("episode %q of feed %q: %w", it.Title, location, err)
+		}
+
+		duration, err := parseDuration(it.ItunesDuration)
+		if err != nil {
+			return nil, fmt.Errorf("episode %q of feed %q: %w", it.Title, location, err)
+		}
+
+		guid := firstNonEmpty(strings.TrimSpace(it.GUID), it.Enclosure.URL)
+		feed.Episodes = append(feed.Episodes, Episode{
+			ID:          EpisodeID(location, guid),
+			GUID:        guid,
+			Title:       strings.TrimSpace(it.Title),
+			Description: strings.TrimSpace(it.Description),
+			Published:   published,
+			AudioURL:    audio,
+			Duration:    duration,
+			Image:       firstNonEmpty(resolve(location, it.ItunesImage.Href), feed.Image),
+		})
+	}
+
+	sort.SliceStable(feed.Episodes, func(i, j int) bool {
+		return feed.Episodes[i].Published.After(feed.Episodes[j].Published)
+	})
+
+	return feed, nil
+}
+
+// resolve returns ref relative to base, which are URLs or paths.
+// If base is an http or https URL, so is the result, an empty string is returned for other refs.
+func resolve(base string, ref string) string {
+	if ref == "" {
+		return ""
+	}
+
+	// Feeds from the web can't refer to files on this machine.
+	if isWeb(base) {
+		baseURL, _ := url.Parse(base)
+		refURL, err := url.Parse(ref)
+		if err != nil {
+			return ""
+		}
+
+		resolved := baseURL.ResolveReference(refURL).String()
+		if !isWeb(resolved) {
+			return ""
+		}
+
+		return resolved
+	}
+
+	refURL, err := url.Parse(ref)
+	if err != nil || refURL.IsAbs() {
+		return ref
+	}
+
+	baseURL, err := url.Parse(base)
+	if err != nil || baseURL.Scheme == "" {
+		if filepath.IsAbs(ref) {
+			return ref
+		}
+
+		return filepath.Join(filepath.Dir(base), filepath.FromSlash(ref))
+	}
+
+	return baseURL.ResolveReference(refURL).String()
+}
+
+// isWeb reports whether location is an http or https URL.
+func isWeb(location string) bool {
+	u, err := url.Parse(location)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC3339,
+}
+
+// parseDate parses the RFC 822 date of an item, and the variations of it that are common in feeds.
+func parseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("parse date %q: unknown format", value)
+}
+
+// parseDuration parses an itunes:duration, which is in seconds, or in [[HH:]MM:]SS, empty is zero.
+func parseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	var seconds int
+	for _, part := range strings.Split(value, ":") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("parse duration %q: invalid number %q", value, part)
+		}
+
+		seconds = seconds*60 + n
+	}
+
+	return time.Duration(seconds) * time.Second, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,206 @@
+package feed_test
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/feed"
+)
+
+const podcast = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+	<channel>
+		<title>The Podcast</title>
+		<description>About things.</description>
+		<itunes:image href="cover.jpg"/>
+		<item>
+			<guid>episode-1</guid>
+			<title>First</title>
+			<description>The first episode.</description>
+			<pubDate>Mon, 01 May 2023 10:00:00 +0000</pubDate>
+			<enclosure url="audio/first.mp3" type="audio/mpeg" length="5"/>
+			<itunes:duration>1:02:03</itunes:duration>
+		</item>
+		<item>
+			<title>Second</title>
+			<pubDate>Tue, 2 May 2023 10:00:00 +0200</pubDate>
+			<enclosure url="https://example.com/second.mp3" type="audio/mpeg" length="5"/>
+			<itunes:duration>90</itunes:duration>
+			<itunes:image href="https://example.com/second.jpg"/>
+		</item>
+		<item>
+			<title>Trailer without audio</title>
+			<pubDate>Sun, 30 Apr 2023 10:00:00 +0000</pubDate>
+		</item>
+	</channel>
+</rss>
+`
+
+// writeFeed writes the podcast and the audio of its first episode to a temporary directory,
+// returning the path of the feed.
+func writeFeed(t *testing.T) string {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "audio"), 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "audio", "first.mp3"), []byte("audio"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "feed.xml")
+	if err := os.WriteFile(path, []byte(podcast), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestFetch(t *testing.T) {
+	path := writeFeed(t)
+	dir := filepath.Dir(path)
+
+	got, err := (&feed.Client{}).Fetch(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &feed.Feed{
+		URL:         path,
+		Title:       "The Podcast",
+		Description: "About things.",
+		Image:       filepath.Join(dir, "cover.jpg"),
+		Episodes: []feed.Episode{
+			{
+				ID:        feed.EpisodeID(path, "https://example.com/second.mp3"),
+				GUID:      "https://example.com/second.mp3",
+				Title:     "Second",
+				Published: time.Date(2023, 5, 2, 8, 0, 0, 0, time.UTC),
+				AudioURL:  "https://example.com/second.mp3",
+				Duration:  90 * time.Second,
+				Image:     "https://example.com/second.jpg",
+			},
+			{
+				ID:          feed.EpisodeID(path, "episode-1"),
+				GUID:        "episode-1",
+				Title:       "First",
+				Description: "The first episode.",
+				Published:   time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC),
+				AudioURL:    filepath.Join(dir, "audio", "first.mp3"),
+				Duration:    time.Hour + 2*time.Minute + 3*time.Second,
+				Image:       filepath.Join(dir, "cover.jpg"),
+			},
+		},
+	}
+
+	// The time zones of the parsed dates differ, only the instants have to match.
+	for n := range got.Episodes {
+		got.Episodes[n].Published = got.Episodes[n].Published.UTC()
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Fetch() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseWebFeed(t *testing.T) {
+	const webPodcast = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+	<channel>
+		<title>The Podcast</title>
+		<itunes:image href="file:///etc/cover.jpg"/>
+		<item>
+			<title>Relative</title>
+			<pubDate>Mon, 01 May 2023 10:00:00 +0000</pubDate>
+			<enclosure url="audio/first.mp3"/>
+		</item>
+		<item>
+			<title>Absolute path</title>
+			<pubDate>Mon, 01 May 2023 09:00:00 +0000</pubDate>
+			<enclosure url="/audio/second.mp3"/>
+		</item>
+		<item>
+			<title>File URL</title>
+			<pubDate>Mon, 01 May 2023 08:00:00 +0000</pubDate>
+			<enclosure url="file:///etc/passwd"/>
+		</item>
+		<item>
+			<title>Other scheme</title>
+			<pubDate>Mon, 01 May 2023 07:00:00 +0000</pubDate>
+			<enclosure url="ftp://example.com/third.mp3"/>
+		</item>
+	</channel>
+</rss>
+`
+
+	got, err := feed.Parse(strings.NewReader(webPodcast), "https://example.com/podcast/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var audio []string
+	for _, episode := range got.Episodes {
+		audio = append(audio, episode.AudioURL)
+	}
+
+	want := []string{"https://example.com/podcast/audio/first.mp3", "https://example.com/audio/second.mp3"}
+	if !reflect.DeepEqual(audio, want) {
+		t.Errorf("Parse() has episodes with audio %q, want %q", audio, want)
+	}
+
+	if got.Image != "" {
+		t.Errorf("Parse() has image %q, want none", got.Image)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	path := writeFeed(t)
+	audio := filepath.Join(filepath.Dir(path), "audio", "first.mp3")
+	fileURL := (&url.URL{Scheme: "file", Path: filepath.ToSlash(audio)}).String()
+
+	for _, location := range []string{audio, fileURL} {
+		body, err := (&feed.Client{}).Open(context.Background(), location)
+		if err != nil {
+			t.Fatalf("Open(%q): %v", location, err)
+		}
+
+		got, err := io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(got) != "audio" {
+			t.Errorf("Open(%q) read %q, want %q", location, got, "audio")
+		}
+	}
+}
+
+func TestEpisodeVideo(t *testing.T) {
+	episode := feed.Episode{
+		ID:        "episode-1",
+		Title:     "First",
+		Published: time.Date(2023, 5, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60)),
+		Duration:  time.Hour + 2*time.Minute + 3*time.Second,
+	}
+
+	video := episode.Video("feed-1")
+	if video.Snippet.PublishedAt != "2023-05-01T10:00:00Z" {
+		t.Errorf("PublishedAt = %q, want %q", video.Snippet.PublishedAt, "2023-05-01T10:00:00Z")
+	}
+
+	if duration, err := video.Duration(); err != nil || duration != episode.Duration {
+		t.Errorf("Duration() = %s, %v, want %s", duration, err, episode.Duration)
+	}
+
+	if video.IsBroadcast() || video.IsLive() {
+		t.Errorf("episode is described as a broadcast or live stream")
+	}
+}
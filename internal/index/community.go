@@ -0,0 +1,191 @@
+package index
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/stem"
+	"github.com/laytan/youtupedia/internal/store"
+	"github.com/laytan/youtupedia/internal/subtitles"
+	"github.com/laytan/youtupedia/internal/tube"
+)
+
+// ErrNotSubmittable is returned when a transcript is submitted for a video that is not in the no captions queue.
+var ErrNotSubmittable = errors.New("only videos without captions that are waiting for whisper accept transcripts")
+
+// ParseSubmission parses the content of a submission into lines, see the subtitles package.
+func ParseSubmission(format store.SubmissionFormat, content string) ([]subtitles.Line, error) {
+	switch format {
+	case store.SubmissionSRT:
+		return subtitles.ParseSRT(content)
+	case store.SubmissionVTT:
+		return subtitles.ParseVTT(content)
+	case store.SubmissionText:
+		return subtitles.ParseText(content)
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// Submit stores a store.SubmissionPending submission of the transcript by the contributor.
+// The video needs a store.FailureTypeNoCaptions failure, otherwise ErrNotSubmittable is returned.
+func Submit(
+	ctx context.Context,
+	q store.Querier,
+	contributor store.Contributor,
+	videoId string,
+	format store.SubmissionFormat,
+	content string,
+) (store.Submission, error) {
+	failure, err := q.NoCaptionsFailureOfVideo(ctx, videoId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.Submission{}, ErrNotSubmittable
+	} else if err != nil {
+		return store.Submission{}, fmt.Errorf("retrieving failure of %q: %w", videoId, err)
+	}
+
+	lines, err := ParseSubmission(format, content)
+	if err != nil {
+		return store.Submission{}, fmt.Errorf("parsing %s transcript: %w", format, err)
+	}
+
+	versionLines := make([]store.VersionLine, 0, len(lines))
+	for _, line := range lines {
+		versionLines = append(versionLines, store.VersionLine{Start: int32(line.Start / time.Second), Text: line.Text})
+	}
+
+	encoded, err := json.Marshal(versionLines)
+	if err != nil {
+		return store.Submission{}, fmt.Errorf("marshalling lines: %w", err)
+	}
+
+	submission, err := q.CreateSubmission(ctx, store.CreateSubmissionParams{
+		VideoID:       videoId,
+		ChannelID:     failure.ChannelID,
+		ContributorID: contributor.ID,
+		Format:        string(format),
+		Content:       content,
+		Lines:         encoded,
+	})
+	if err != nil {
+		return store.Submission{}, fmt.Errorf("creating submission: %w", err)
+	}
+
+	log.Printf("[INFO]: %q submitted a transcript of %d lines for %q", contributor.Name, len(lines), videoId)
+	return submission, nil
+}
+
+// ApproveSubmission indexes the pending submission as the store.Community transcript of its video,
+// deletes the store.FailureTypeNoCaptions failures of the video, and marks the submission approved, in one transaction.
+//
+// It refuses when the video is indexed already, when a worker is transcribing it,
+// or when the transcript goes on after the end of the video.
+func (i *Indexer) ApproveSubmission(ctx context.Context, id int64) error {
+	submission, err := i.store.Submission(ctx, id)
+	if err != nil {
+		return fmt.Errorf("retrieving submission %d: %w", id, err)
+	}
+
+	if store.SubmissionStatus(submission.Status) != store.SubmissionPending {
+		return fmt.Errorf("submission %d is %s already", id, submission.Status)
+	}
+
+	if _, err := i.store.Video(ctx, submission.VideoID); err == nil {
+		return fmt.Errorf("video %q is indexed already, reject the submission instead", submission.VideoID)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("retrieving video %q: %w", submission.VideoID, err)
+	}
+
+	lines, err := ParseSubmission(store.SubmissionFormat(submission.Format), submission.Content)
+	if err != nil {
+		return fmt.Errorf("parsing submission %d: %w", id, err)
+	}
+
+	videos, err := i.yt.Videos([]string{submission.VideoID})
+	if err != nil {
+		return fmt.Errorf("retrieving video %q: %w", submission.VideoID, err)
+	}
+
+	if len(videos) == 0 {
+		return fmt.Errorf("video %q: %w", submission.VideoID, tube.ErrNotFound)
+	}
+	video := videos[0]
+
+	if duration, err := video.Duration(); err == nil && duration > 0 && lines[len(lines)-1].Start > duration {
+		return fmt.Errorf(
+			"the transcript has a line at %s, after the end of the video at %s",
+			lines[len(lines)-1].Start,
+			duration,
+		)
+	}
+
+	published, err := tube.ParsePublishedTime(video.Snippet.PublishedAt)
+	if err != nil {
+		return err
+	}
+
+	cues := make([]Cue, 0, len(lines))
+	for _, line := range lines {
+		cue := Cue{Start: line.Start.Seconds(), Text: line.Text}
+		if line.End > line.Start {
+			cue.Dur = (line.End - line.Start).Seconds()
+		}
+		cues = append(cues, cue)
+	}
+
+	return i.store.Tx(ctx, func(qtx store.Querier) error {
+		// Locking the failures keeps workers from claiming them until the transaction is done,
+		// a worker that claimed one already would create the video too, see failures.Pipeline.
+		failures, err := qtx.LockNoCaptionsFailuresOfVideo(ctx, submission.VideoID)
+		if err != nil {
+			return fmt.Errorf("locking failures of %q: %w", submission.VideoID, err)
+		}
+
+		for _, failure := range failures {
+			if failure.ClaimedBy != "" && failure.Leased {
+				return fmt.Errorf("video %q is being transcribed by %s, try again later", submission.VideoID, failure.ClaimedBy)
+			}
+		}
+
+		if err := qtx.CreateVideo(ctx, store.CreateVideoParams{
+			ID:                    submission.VideoID,
+			ChannelID:             submission.ChannelID,
+			PublishedAt:           published,
+			Title:                 video.Snippet.Title,
+			Description:           video.Snippet.Description,
+			ThumbnailUrl:          tube.HighestResThumbnail(video.Snippet.Thumbnails).Url,
+			SearchableTranscript:  "",
+			TranscriptType:        string(store.Community),
+			SearchableTitle:       stem.StemText(video.Snippet.Title),
+			SearchableDescription: stem.StemText(video.Snippet.Description),
+			StemVersion:           stem.Version,
+		}); err != nil {
+			return fmt.Errorf("creating video %q: %w", submission.VideoID, err)
+		}
+
+		if err := StoreTranscripts(ctx, qtx, submission.VideoID, cues); err != nil {
+			return err
+		}
+
+		if err := qtx.DeleteFailuresOfVideo(ctx, store.DeleteFailuresOfVideoParams{
+			VideoID: submission.VideoID,
+			Type:    string(store.FailureTypeNoCaptions),
+		}); err != nil {
+			return fmt.Errorf("deleting failures of %q: %w", submission.VideoID, err)
+		}
+
+		if err := qtx.SetSubmissionStatus(ctx, store.SetSubmissionStatusParams{
+			ID:     id,
+			Status: string(store.SubmissionApproved),
+		}); err != nil {
+			return fmt.Errorf("approving submission %d: %w", id, err)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,81 @@
+package index
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MinOverlap is the least amount of words that have to overlap before Dedupe strips them,
+// a single word is too often repeated on purpose ("that. That is").
+const MinOverlap = 2
+
+// DedupeStats reports how much text Dedupe removed.
+type DedupeStats struct {
+	Cues    int // Cues that had overlapping text.
+	Removed int // Cues that were removed entirely because all of their text overlapped.
+	Words   int // Total amount of words removed.
+}
+
+// Dedupe strips text at the start of a cue that repeats the end of the previous cue.
+// YouTube's automatic captions often roll the tail of a cue over into the next one,
+// which would otherwise be stored, and matched by searches, twice.
+//
+// Words are compared case-insensitively, ignoring punctuation.
+// Cues that are left without text are dropped.
+func Dedupe(cues []Cue) ([]Cue, DedupeStats) {
+	var stats DedupeStats
+	res := make([]Cue, 0, len(cues))
+
+	var prev []string
+	for _, cue := range cues {
+		words := strings.Fields(cue.Text)
+		overlap := overlapping(prev, words)
+		prev = words
+
+		if overlap == 0 {
+			res = append(res, cue)
+			continue
+		}
+
+		stats.Cues++
+		stats.Words += overlap
+
+		if overlap == len(words) {
+			stats.Removed++
+			continue
+		}
+
+		cue.Text = strings.Join(words[overlap:], " ")
+		res = append(res, cue)
+	}
+
+	return res, stats
+}
+
+// overlapping returns the length of the longest suffix of prev that is a prefix of curr,
+// or 0 if that is shorter than MinOverlap.
+func overlapping(prev []string, curr []string) int {
+	n := len(prev)
+	if len(curr) < n {
+		n = len(curr)
+	}
+
+Outer:
+	for ; n >= MinOverlap; n-- {
+		for i := 0; i < n; i++ {
+			if normalizeWord(prev[len(prev)-n+i]) != normalizeWord(curr[i]) {
+				continue Outer
+			}
+		}
+
+		return n
+	}
+
+	return 0
+}
+
+func normalizeWord(word string) string {
+	return strings.ToLower(strings.TrimFunc(word, func(r rune) bool {
+		return unicode.IsPunct(r)
+	}))
+}
@@ -0,0 +1,35 @@
+package index_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/laytan/youtupedia/internal/index"
+)
+
+func TestDedupe(t *testing.T) {
+	cues := []index.Cue{
+		{Start: 0, Text: "so what we want to"},
+		{Start: 2, Text: "want to do is"},
+		{Start: 4, Text: "Do is."},
+		{Start: 5, Text: "is that it"},
+		{Start: 6, Text: "it works"},
+	}
+
+	got, stats := index.Dedupe(cues)
+
+	want := []index.Cue{
+		{Start: 0, Text: "so what we want to"},
+		{Start: 2, Text: "do is"},
+		{Start: 5, Text: "is that it"},
+		{Start: 6, Text: "it works"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Dedupe() = %+v, want %+v", got, want)
+	}
+
+	wantStats := index.DedupeStats{Cues: 2, Removed: 1, Words: 4}
+	if stats != wantStats {
+		t.Errorf("Dedupe() stats = %+v, want %+v", stats, wantStats)
+	}
+}
@@ -0,0 +1,218 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/library"
+	"github.com/laytan/youtupedia/internal/store"
+)
+
+// AddDirectory creates the channel of the media library at dir, see store.SourceDirectory.
+// The handle is the custom url of the channel, defaults to the name of the directory, like "@internal-talks".
+// If the channel of the directory exists already, it is returned.
+func (i *Indexer) AddDirectory(ctx context.Context, dir string, handle string) (*store.Channel, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("absolute path of %q: %w", dir, err)
+	}
+
+	if ch, err := i.store.Channel(ctx, library.ChannelID(dir)); err == nil {
+		return &ch, nil
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%q is not a directory", dir)
+	}
+
+	title := filepath.Base(dir)
+	if handle == "" {
+		handle = slug(title)
+	}
+
+	if handle == "" {
+		handle = library.ChannelID(dir)
+	}
+
+	if !strings.HasPrefix(handle, "@") {
+		handle = "@" + handle
+	}
+
+	ch, err := i.store.CreateChannel(ctx, store.CreateChannelParams{
+		ID:           library.ChannelID(dir),
+		Title:        title,
+		VideosListID: "",
+		ThumbnailUrl: thumbnail(""),
+		CustomUrl:    handle,
+		Source:       string(store.SourceDirectory),
+		Directory:    dir,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating channel in database: %w", err)
+	}
+
+	return &ch, nil
+}
+
+// IndexDirectory creates a store.FailureTypeFile failure for each new media file in the directory of the channel,
+// so the failures pipeline transcribes them.
+//
+// Files are identified by a hash of their content, see library.VideoID, which is stored as a store.MediaFile by path,
+// files are only hashed again when their size or modification time changes.
+// Media files of paths that are not in the directory anymore are deleted.
+// Files are filtered like videos, by their title, see Filter, and scored with Priority,
+// files that are indexed, or have a failure, are skipped.
+func (i *Indexer) IndexDirectory(ctx context.Context, channel *store.Channel, settings store.ChannelSetting, opts ChannelOptions) error {
+	files, err := library.Scan(channel.Directory)
+	if err != nil {
+		return err
+	}
+
+	filter, err := NewFilter(NoProber{}, settings)
+	if err != nil {
+		return err
+	}
+
+	hashed := make(map[string]store.MediaFile)
+	mediaFiles, err := i.store.MediaFilesOfChannel(ctx, channel.ID)
+	if err != nil {
+		return fmt.Errorf("retrieving media files: %w", err)
+	}
+
+	for _, mf := range mediaFiles {
+		hashed[mf.Path] = mf
+	}
+
+	if !opts.DryRun {
+		if err := i.deleteMissingMediaFiles(ctx, channel.ID, files, hashed); err != nil {
+			return err
+		}
+	}
+
+	known := make(map[string]bool)
+	indexed, err := i.store.VideoIDsOfChannel(ctx, channel.ID)
+	if err != nil {
+		return fmt.Errorf("retrieving indexed files: %w", err)
+	}
+
+	queued, err := i.store.FailureDataOfChannel(ctx, store.FailureDataOfChannelParams{
+		ChannelID: channel.ID,
+		Type:      string(store.FailureTypeFile),
+	})
+	if err != nil {
+		return fmt.Errorf("retrieving queued files: %w", err)
+	}
+
+	for _, id := range append(indexed, queued...) {
+		known[id] = true
+	}
+
+	var count int
+	for _, file := range files {
+		// Postgres stores timestamps in microseconds, without the time zone.
+		modified := file.Modified.UTC().Truncate(time.Microsecond)
+
+		id := ""
+		if mf, ok := hashed[file.Path]; ok && mf.Size == file.Size && mf.ModifiedAt.Equal(modified) {
+			id = mf.VideoID
+		} else {
+			log.Printf("[INFO]: hashing %q", file.Path)
+			if id, err = library.VideoID(file.Path); err != nil {
+				return err
+			}
+
+			if !opts.DryRun {
+				if err := i.store.UpsertMediaFile(ctx, store.UpsertMediaFileParams{
+					VideoID:    id,
+					ChannelID:  channel.ID,
+					Path:       file.Path,
+					Size:       file.Size,
+					ModifiedAt: modified,
+				}); err != nil {
+					return fmt.Errorf("storing media file %q: %w", file.Path, err)
+				}
+			}
+		}
+
+		if known[id] {
+			continue
+		}
+		known[id] = true // Copies of the file have the same ID.
+
+		if !opts.Since.IsZero() && file.Modified.Before(opts.Since) {
+			log.Printf("[INFO]: skipping %q: modified before %s", file.Path, opts.Since.Format(time.DateOnly))
+			continue
+		}
+
+		video := file.Video(channel.ID, id)
+		reason, err := filter.Skip(video)
+		if err != nil {
+			return err
+		}
+
+		if reason != "" {
+			log.Printf("[INFO]: skipping %q: %s", file.Path, reason)
+			continue
+		}
+
+		if opts.Limit > 0 && count >= opts.Limit {
+			log.Printf("[INFO]: reached limit of %d files", opts.Limit)
+			break
+		}
+		count++
+
+		if opts.DryRun {
+			log.Printf("[INFO]: would queue %q - %q", id, file.Path)
+			continue
+		}
+
+		if err := i.store.CreateFailure(ctx, store.CreateFailureParams{
+			ChannelID: channel.ID,
+			Data:      id,
+			Type:      string(store.FailureTypeFile),
+			Priority:  Priority(settings, file.Modified, video),
+		}); err != nil {
+			return fmt.Errorf("creating failure for file %q: %w", file.Path, err)
+		}
+
+		log.Printf("[INFO]: queued %q - %q to be transcribed", id, file.Path)
+	}
+
+	return nil
+}
+
+// deleteMissingMediaFiles deletes the media files of the channel that were not found by the scan.
+func (i *Indexer) deleteMissingMediaFiles(
+	ctx context.Context,
+	channelID string,
+	files []library.File,
+	hashed map[string]store.MediaFile,
+) error {
+	found := make(map[string]bool, len(files))
+	for _, file := range files {
+		found[file.Path] = true
+	}
+
+	for path := range hashed {
+		if found[path] {
+			continue
+		}
+
+		log.Printf("[INFO]: %q is gone, forgetting it", path)
+		if err := i.store.DeleteMediaFile(ctx, store.DeleteMediaFileParams{ChannelID: channelID, Path: path}); err != nil {
+			return fmt.Errorf("deleting media file %q: %w", path, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/laytan/youtupedia/internal/feed"
+	"github.com/laytan/youtupedia/internal/store"
+	"github.com/laytan/youtupedia/internal/tube"
+)
+
+// AddFeed reads the podcast feed at location, an URL or a path, and creates its channel, see store.SourcePodcast.
+// The handle is the custom url of the channel, defaults to the title of the podcast, like "@the-podcast".
+// If the channel of the feed exists already, it is returned.
+func (i *Indexer) AddFeed(ctx context.Context, location string, handle string) (*store.Channel, error) {
+	if ch, err := i.store.Channel(ctx, feed.ChannelID(location)); err == nil {
+		return &ch, nil
+	}
+
+	podcast, err := i.opts.Feeds.Fetch(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+
+	if handle == "" {
+		handle = slug(podcast.Title)
+	}
+
+	if handle == "" {
+		handle = feed.ChannelID(location)
+	}
+
+	if !strings.HasPrefix(handle, "@") {
+		handle = "@" + handle
+	}
+
+	ch, err := i.store.CreateChannel(ctx, store.CreateChannelParams{
+		ID:           feed.ChannelID(location),
+		Title:        podcast.Title,
+		VideosListID: "",
+		ThumbnailUrl: thumbnail(podcast.Image),
+		CustomUrl:    handle,
+		Source:       string(store.SourcePodcast),
+		FeedUrl:      location,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating channel in database: %w", err)
+	}
+
+	return &ch, nil
+}
+
+// RefreshFeed updates the title and thumbnail of the podcast channel from its feed.
+func (i *Indexer) RefreshFeed(ctx context.Context, channel *store.Channel) (*store.Channel, error) {
+	podcast, err := i.opts.Feeds.Fetch(ctx, channel.FeedUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	ch, err := i.store.UpdateChannel(ctx, store.UpdateChannelParams{
+		ID:           channel.ID,
+		Title:        podcast.Title,
+		VideosListID: channel.VideosListID,
+		ThumbnailUrl: thumbnail(podcast.Image),
+		CustomUrl:    channel.CustomUrl,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("updating channel in database: %w", err)
+	}
+
+	return &ch, nil
+}
+
+// IndexFeed creates a store.FailureTypeEpisode failure for each new episode in the feed of the podcast channel,
+// so the failures pipeline transcribes them, podcasts have no captions.
+//
+// Episodes are filtered like videos, by their title and duration, see Filter, and scored with Priority.
+// Episodes that are indexed, or have a failure, are skipped, older episodes are still checked,
+// feeds are small compared to channels and can add episodes in any order.
+func (i *Indexer) IndexFeed(ctx context.Context, channel *store.Channel, settings store.ChannelSetting, opts ChannelOptions) error {
+	podcast, err := i.opts.Feeds.Fetch(ctx, channel.FeedUrl)
+	if err != nil {
+		return err
+	}
+
+	filter, err := NewFilter(NoProber{}, settings)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]bool)
+	indexed, err := i.store.VideoIDsOfChannel(ctx, channel.ID)
+	if err != nil {
+		return fmt.Errorf("retrieving indexed episodes: %w", err)
+	}
+
+	queued, err := i.store.FailureDataOfChannel(ctx, store.FailureDataOfChannelParams{
+		ChannelID: channel.ID,
+		Type:      string(store.FailureTypeEpisode),
+	})
+	if err != nil {
+		return fmt.Errorf("retrieving queued episodes: %w", err)
+	}
+
+	for _, id := range append(indexed, queued...) {
+		known[id] = true
+	}
+
+	var count int
+	for _, episode := range podcast.Episodes {
+		if known[episode.ID] {
+			continue
+		}
+
+		if !opts.Since.IsZero() && episode.Published.Before(opts.Since) {
+			log.Printf("[INFO]: episode %q is published before %s, stopping", episode.Title, opts.Since.Format(time.DateOnly))
+			break
+		}
+
+		video := episode.Video(channel.ID)
+		reason, err := filter.Skip(video)
+		if err != nil {
+			return err
+		}
+
+		if reason != "" {
+			log.Printf("[INFO]: skipping %q - %q: %s", episode.ID, episode.Title, reason)
+			continue
+		}
+
+		if opts.Limit > 0 && count >= opts.Limit {
+			log.Printf("[INFO]: reached limit of %d episodes", opts.Limit)
+			break
+		}
+		count++
+
+		if opts.DryRun {
+			log.Printf("[INFO]: would queue %q - %q", episode.ID, episode.Title)
+			continue
+		}
+
+		if err := i.store.CreateFailure(ctx, store.CreateFailureParams{
+			ChannelID: channel.ID,
+			Data:      episode.ID,
+			Type:      string(store.FailureTypeEpisode),
+			Priority:  Priority(settings, episode.Published, video),
+		}); err != nil {
+			return fmt.Errorf("creating failure for episode %q: %w", episode.ID, err)
+		}
+
+		log.Printf("[INFO]: queued %q - %q to be transcribed", episode.ID, episode.Title)
+	}
+
+	return nil
+}
+
+// slug returns a handle for the title, "The Podcast!" becomes "the-podcast".
+func slug(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+
+		dash = true
+	}
+
+	return b.String()
+}
+
+// thumbnail returns the image, or the placeholder of videos without a thumbnail if it is empty.
+func thumbnail(image string) string {
+	if image == "" {
+		return tube.HighestResThumbnail(nil).Url
+	}
+
+	return image
+}
@@ -0,0 +1,141 @@
+package index
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/store"
+	"github.com/laytan/youtupedia/internal/tube"
+)
+
+// Prober tells apart the kinds of videos the YouTube API does not, implemented by *tube.Client.
+type Prober interface {
+	IsShort(id string) (bool, error)
+	IsLiveContent(id string) (bool, error)
+}
+
+// NoProber is the Prober of sources without Shorts, live streams and premieres, like podcast feeds.
+type NoProber struct{}
+
+func (NoProber) IsShort(id string) (bool, error)       { return false, nil }
+func (NoProber) IsLiveContent(id string) (bool, error) { return false, nil }
+
+// maxShortDuration is the longest a Short can be,
+// longer videos are not probed with Prober.IsShort.
+const maxShortDuration = 3 * time.Minute
+
+// Filter decides which videos of a channel are skipped, based on its store.ChannelSetting.
+type Filter struct {
+	yt        Prober
+	settings  store.ChannelSetting
+	blocklist *regexp.Regexp
+}
+
+func NewFilter(yt Prober, settings store.ChannelSetting) (*Filter, error) {
+	f := &Filter{yt: yt, settings: settings}
+
+	if settings.TitleBlocklist != "" {
+		blocklist, err := regexp.Compile(settings.TitleBlocklist)
+		if err != nil {
+			return nil, fmt.Errorf("compiling title blocklist of channel %q: %w", settings.ChannelID, err)
+		}
+
+		f.blocklist = blocklist
+	}
+
+	return f, nil
+}
+
+// NeedsDetails returns whether Skip needs the details of the video from the API,
+// if not, SkipTitle is enough.
+func (f *Filter) NeedsDetails() bool {
+	return !f.settings.IncludeShorts ||
+		!f.settings.IncludeLive ||
+		!f.settings.IncludePremieres ||
+		f.settings.MinDuration > 0
+}
+
+// SkipTitle returns why a video with the given title is skipped, or an empty string if it is not.
+func (f *Filter) SkipTitle(title string) string {
+	if f.blocklist != nil && f.blocklist.MatchString(title) {
+		return fmt.Sprintf("title matches blocklist %q", f.settings.TitleBlocklist)
+	}
+
+	return ""
+}
+
+// Skip returns why the video is skipped, or an empty string if it is not.
+//
+// The Prober is only used when the details of the video are not conclusive,
+// for example, only videos of up to 3 minutes are checked to be a Short.
+func (f *Filter) Skip(video *tube.ResVideo) (string, error) {
+	if reason := f.SkipTitle(video.Snippet.Title); reason != "" {
+		return reason, nil
+	}
+
+	if video.IsLive() {
+		switch {
+		case f.settings.IncludeLive && f.settings.IncludePremieres:
+		case !f.settings.IncludeLive && !f.settings.IncludePremieres:
+			return "live stream or premiere", nil
+		default:
+			live, err := f.yt.IsLiveContent(video.Id)
+			if err != nil {
+				return "", fmt.Errorf("checking if %q is a live stream: %w", video.Id, err)
+			}
+
+			if live && !f.settings.IncludeLive {
+				return "live stream", nil
+			}
+
+			if !live && !f.settings.IncludePremieres {
+				return "premiere", nil
+			}
+		}
+	}
+
+	if f.settings.MinDuration <= 0 && f.settings.IncludeShorts {
+		return "", nil
+	}
+
+	// Podcast episodes and local files do not always have a known duration, they are not filtered by it.
+	if video.ContentDetails.Duration == "" {
+		return "", nil
+	}
+
+	duration, err := video.Duration()
+	if err != nil {
+		return "", fmt.Errorf("duration of %q: %w", video.Id, err)
+	}
+
+	minDuration := time.Duration(f.settings.MinDuration) * time.Second
+	if duration < minDuration {
+		return fmt.Sprintf("shorter than %s", minDuration), nil
+	}
+
+	if !f.settings.IncludeShorts && !video.IsLive() && duration <= maxShortDuration {
+		short, err := f.yt.IsShort(video.Id)
+		if err != nil {
+			return "", fmt.Errorf("checking if %q is a short: %w", video.Id, err)
+		}
+
+		if short {
+			return "short", nil
+		}
+	}
+
+	return "", nil
+}
+
+// TrackPreference returns the caption track to prefer for the store.CaptionSource.
+func TrackPreference(source store.CaptionSource) tube.TrackPreference {
+	switch source {
+	case store.CaptionSourceManual:
+		return tube.OnlyManual
+	case store.CaptionSourceAuto:
+		return tube.PreferAuto
+	default:
+		return tube.PreferManual
+	}
+}
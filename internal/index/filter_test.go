@@ -0,0 +1,135 @@
+package index_test
+
+import (
+	"testing"
+
+	"github.com/laytan/youtupedia/internal/index"
+	"github.com/laytan/youtupedia/internal/store"
+	"github.com/laytan/youtupedia/internal/tube"
+)
+
+// fakeProber knows which videos are Shorts and live streams, and counts how often it is asked.
+type fakeProber struct {
+	shorts map[string]bool
+	live   map[string]bool
+	probes int
+}
+
+func (p *fakeProber) IsShort(id string) (bool, error) {
+	p.probes++
+	return p.shorts[id], nil
+}
+
+func (p *fakeProber) IsLiveContent(id string) (bool, error) {
+	p.probes++
+	return p.live[id], nil
+}
+
+func video(id string, title string, duration string, live bool) *tube.ResVideo {
+	v := &tube.ResVideo{Id: id}
+	v.Snippet.Title = title
+	v.Snippet.LiveBroadcastContent = "none"
+	v.ContentDetails.Duration = duration
+	if live {
+		v.LiveStreamingDetails = &struct {
+			ActualStartTime    string
+			ScheduledStartTime string
+		}{}
+	}
+
+	return v
+}
+
+func TestFilterSkip(t *testing.T) {
+	defaults := store.DefaultChannelSettings("channel")
+	settings := func(f func(s *store.ChannelSetting)) store.ChannelSetting {
+		s := defaults
+		f(&s)
+		return s
+	}
+
+	noShorts := settings(func(s *store.ChannelSetting) { s.IncludeShorts = false })
+	noLive := settings(func(s *store.ChannelSetting) { s.IncludeLive = false })
+	noBroadcasts := settings(func(s *store.ChannelSetting) { s.IncludeLive, s.IncludePremieres = false, false })
+	minDuration := settings(func(s *store.ChannelSetting) { s.MinDuration = 300 })
+	blocklist := settings(func(s *store.ChannelSetting) { s.TitleBlocklist = `(?i)\bpodcast\b` })
+
+	cases := []struct {
+		name       string
+		settings   store.ChannelSetting
+		video      *tube.ResVideo
+		want       string
+		wantProbes int
+	}{
+		{"defaults include everything", defaults, video("short", "A short", "PT30S", false), "", 0},
+		{"blocklisted title", blocklist, video("v", "The Podcast #12", "PT1H", false), `title matches blocklist "(?i)\\bpodcast\\b"`, 0},
+		{"title not blocklisted", blocklist, video("v", "Podcasting tips", "PT1H", false), "", 0},
+		{"short", noShorts, video("short", "A short", "PT30S", false), "short", 1},
+		{"short video that is not a short", noShorts, video("video", "A video", "PT2M", false), "", 1},
+		{"long videos are not probed", noShorts, video("video", "A video", "PT10M", false), "", 0},
+		{"too short", minDuration, video("v", "A video", "PT4M", false), "shorter than 5m0s", 0},
+		{"long enough", minDuration, video("v", "A video", "PT5M", false), "", 0},
+		{"unknown duration", minDuration, video("v", "An episode", "", false), "", 0},
+		{"no broadcasts", noBroadcasts, video("live", "A stream", "PT2H", true), "live stream or premiere", 0},
+		{"live stream", noLive, video("live", "A stream", "PT2H", true), "live stream", 1},
+		{"premiere", noLive, video("premiere", "A premiere", "PT2H", true), "", 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			prober := &fakeProber{
+				shorts: map[string]bool{"short": true},
+				live:   map[string]bool{"live": true},
+			}
+
+			filter, err := index.NewFilter(prober, c.settings)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := filter.Skip(c.video)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != c.want || prober.probes != c.wantProbes {
+				t.Errorf("Skip() = %q with %d probes, want %q with %d probes", got, prober.probes, c.want, c.wantProbes)
+			}
+		})
+	}
+}
+
+func TestFilterNeedsDetails(t *testing.T) {
+	defaults := store.DefaultChannelSettings("channel")
+	withBlocklist := defaults
+	withBlocklist.TitleBlocklist = "podcast"
+	noShorts := defaults
+	noShorts.IncludeShorts = false
+	noPremieres := defaults
+	noPremieres.IncludePremieres = false
+	minDuration := defaults
+	minDuration.MinDuration = 60
+
+	cases := []struct {
+		name     string
+		settings store.ChannelSetting
+		want     bool
+	}{
+		{"defaults", defaults, false},
+		{"blocklist only needs the title", withBlocklist, false},
+		{"shorts", noShorts, true},
+		{"premieres", noPremieres, true},
+		{"minimum duration", minDuration, true},
+	}
+
+	for _, c := range cases {
+		filter, err := index.NewFilter(&fakeProber{}, c.settings)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := filter.NeedsDetails(); got != c.want {
+			t.Errorf("%s: NeedsDetails() = %t, want %t", c.name, got, c.want)
+		}
+	}
+}
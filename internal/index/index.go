@@ -2,62 +2,162 @@ package index
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
 	"html"
 	"log"
 	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/laytan/youtupedia/internal/feed"
 	"github.com/laytan/youtupedia/internal/stem"
 	"github.com/laytan/youtupedia/internal/store"
 	"github.com/laytan/youtupedia/internal/tube"
 	"golang.org/x/sync/errgroup"
 )
 
-var (
-	Queries *store.Queries
-	Yt      *tube.Client
-	Db      *sql.DB
+var ErrAlreadyIndexed = errors.New("already indexed")
 
-	ErrAlreadyIndexed = errors.New("already indexed")
-)
+// YouTube is the part of the YouTube API the Indexer uses, implemented by *tube.Client.
+type YouTube interface {
+	ChannelInfo(id string) (*tube.ChannelInfo, error)
+	EachPlaylistItemPage(
+		playlistId string,
+		f func(page *tube.ResPlaylistItems, token string, e error) (cont bool, err error),
+	) error
+	Videos(ids []string) ([]tube.ResVideo, error)
+	Captions(videoId string, pref tube.TrackPreference) (*tube.Transcript, tube.TranscriptType, error)
+	Prober
+}
+
+type Options struct {
+	// Routines is the amount of videos indexed concurrently, defaults to 2.
+	// Have to be careful with this so we don't get banned/blocked by YouTube.
+	Routines int
+
+	// Segment configures how automatic captions are grouped, defaults to DefaultSegmentOptions.
+	Segment SegmentOptions
+
+	// Feeds reads the feeds of podcast channels, see store.SourcePodcast, defaults to a feed.Client with its defaults.
+	Feeds *feed.Client
+}
+
+// Indexer retrieves channels and their videos' captions from YouTube and stores them,
+// and queues the episodes of podcast feeds and the files of media libraries to be transcribed.
+type Indexer struct {
+	store store.Store
+	yt    YouTube
+	opts  Options
+}
+
+func New(s store.Store, yt YouTube, opts Options) *Indexer {
+	if opts.Routines <= 0 {
+		opts.Routines = 2
+	}
+
+	if opts.Segment == (SegmentOptions{}) {
+		opts.Segment = DefaultSegmentOptions
+	}
+
+	if opts.Feeds == nil {
+		opts.Feeds = &feed.Client{}
+	}
+
+	return &Indexer{
+		store: s,
+		yt:    yt,
+		opts:  opts,
+	}
+}
+
+// ChannelOptions limit what IndexChannel indexes, the zero value indexes everything new.
+type ChannelOptions struct {
+	Since  time.Time // Stop at videos published before this time.
+	Limit  int       // Stop after this amount of videos, 0 means no limit.
+	DryRun bool      // Only log the videos that would be indexed.
+}
+
+// errStop is returned from within the iteration of IndexChannel to stop iterating.
+var errStop = errors.New("stopping")
 
 // IndexChannel iterates through all videos of the given channel.
 // Calling IndexVideo on each of them.
 //
 // If the iteration gets to a video that is already indexed, ErrAlreadyIndexed is returned.
+// The iteration also stops when it reaches opts.Since or opts.Limit.
+//
+// Videos are skipped according to the store.ChannelSetting of the channel, see Filter.
+// When the filter needs the details of the videos, they are retrieved per page, costing 1 quota per page.
 //
 // If during this process, the YouTube quota is exceeded,
 // a store.Failure is created with type store.FailureTypePageQuota and the token of the failed page in its Data.
 //
-// Indexing is done using 2 goroutines for increased speed, this could be higher (the process is not very taxing).
+// Indexing is done using Options.Routines goroutines for increased speed, this could be higher (the process is not very taxing).
 // But we might get banned/blocked by YouTube.
-func IndexChannel(ctx context.Context, channel *store.Channel) error {
-	lastVideo, err := Queries.LastVideo(ctx, channel.ID)
+//
+// Podcast channels are indexed with IndexFeed, and directory channels with IndexDirectory, instead.
+func (i *Indexer) IndexChannel(ctx context.Context, channel *store.Channel, opts ChannelOptions) error {
+	settings, err := store.ChannelSettingsOrDefault(ctx, i.store, channel.ID)
+	if err != nil {
+		return fmt.Errorf("retrieving settings of channel: %w", err)
+	}
+
+	switch store.Source(channel.Source) {
+	case store.SourcePodcast:
+		return i.IndexFeed(ctx, channel, settings, opts)
+	case store.SourceDirectory:
+		return i.IndexDirectory(ctx, channel, settings, opts)
+	}
+
+	filter, err := NewFilter(i.yt, settings)
+	if err != nil {
+		return err
+	}
+
+	quotaExceeded := func(token string) (bool, error) {
+		log.Println(
+			"[WARN]: quota exceeded, adding page we left off at to the failures table",
+		)
+		if err := i.store.CreateFailure(ctx, store.CreateFailureParams{
+			ChannelID: channel.ID,
+			Data:      token,
+			Type:      string(store.FailureTypePageQuota),
+		}); err != nil {
+			return false, fmt.Errorf("creating quota failure: %w", err)
+		}
+
+		return false, nil
+	}
+
+	lastVideo, err := i.store.LastVideo(ctx, channel.ID)
 	hasLastVideo := err == nil
-	err = Yt.EachPlaylistItemPage(
+	var count atomic.Int64
+	err = i.yt.EachPlaylistItemPage(
 		channel.VideosListID,
 		func(pi *tube.ResPlaylistItems, token string, err error) (bool, error) {
 			if err != nil {
-				if errors.Is(err, tube.ErrQuotaExceeded) {
-					log.Println(
-						"[WARN]: quota exceeded, adding page we left off at to the failures table",
-					)
-					if err := Queries.CreateFailure(ctx, store.CreateFailureParams{
-						ChannelID: channel.ID,
-						Data:      token,
-						Type:      string(store.FailureTypePageQuota),
-					}); err != nil {
-						return false, fmt.Errorf("creating quota failure: %w", err)
-					}
+				if errors.Is(err, tube.ErrQuotaExceeded) && !opts.DryRun {
+					return quotaExceeded(token)
 				} else {
 					return false, fmt.Errorf("unexpected error page: %w", err)
 				}
 			}
 
+			var details map[string]*tube.ResVideo
+			if filter.NeedsDetails() {
+				details, err = i.videoDetails(pi.Items)
+				if err != nil {
+					if errors.Is(err, tube.ErrQuotaExceeded) && !opts.DryRun {
+						return quotaExceeded(token)
+					}
+
+					return false, fmt.Errorf("retrieving video details: %w", err)
+				}
+			}
+
 			group, ctx := errgroup.WithContext(ctx)
-			group.SetLimit(2) // Have to be careful with this so we don't get banned/blocked.
+			group.SetLimit(i.opts.Routines)
 
 			for _, vid := range pi.Items {
 				vid := vid
@@ -67,17 +167,60 @@ func IndexChannel(ctx context.Context, channel *store.Channel) error {
 						return fmt.Errorf("video %s: %w", lastVideo.ID, ErrAlreadyIndexed)
 					}
 
+					if !opts.Since.IsZero() {
+						published, err := tube.ParsePublishedTime(vid.ContentDetails.VideoPublishedAt)
+						if err == nil && published.Before(opts.Since) {
+							return fmt.Errorf(
+								"video %s is published before %s: %w",
+								vid.ContentDetails.VideoId,
+								opts.Since.Format(time.DateOnly),
+								errStop,
+							)
+						}
+					}
+
+					reason := filter.SkipTitle(vid.Snippet.Title)
+					if video, ok := details[vid.ContentDetails.VideoId]; ok {
+						var err error
+						if reason, err = filter.Skip(video); err != nil {
+							return err
+						}
+					}
+
+					if reason != "" {
+						log.Printf(
+							"[INFO]: skipping %q - %q: %s",
+							vid.ContentDetails.VideoId,
+							vid.Snippet.Title,
+							reason,
+						)
+						return nil
+					}
+
+					if opts.Limit > 0 && count.Add(1) > int64(opts.Limit) {
+						return fmt.Errorf("reached limit of %d videos: %w", opts.Limit, errStop)
+					}
+
 					// Check if the errgroup has gotten an error, in that case don't index.
 					select {
 					case <-ctx.Done():
 						return nil
 					default:
+						if opts.DryRun {
+							log.Printf(
+								"[INFO]: would index %q - %q",
+								vid.ContentDetails.VideoId,
+								vid.Snippet.Title,
+							)
+							return nil
+						}
+
 						log.Printf(
 							"[INFO]: indexing %q - %q",
 							vid.ContentDetails.VideoId,
 							vid.Snippet.Title,
 						)
-						if err := IndexVideo(ctx, channel.ID, vid); err != nil {
+						if err := i.IndexVideo(ctx, channel.ID, vid, details[vid.ContentDetails.VideoId], settings); err != nil {
 							return fmt.Errorf(
 								"indexing %s failed: %w",
 								vid.ContentDetails.VideoId,
@@ -100,6 +243,9 @@ func IndexChannel(ctx context.Context, channel *store.Channel) error {
 					// TODO: this also cancels in progress indexing of non-indexed items.
 					log.Printf("[INFO]: found already indexed video, stopping this: %v", err)
 					return false, nil
+				} else if errors.Is(err, errStop) {
+					log.Printf("[INFO]: %v", err)
+					return false, nil
 				} else {
 					return false, err
 				}
@@ -115,48 +261,103 @@ func IndexChannel(ctx context.Context, channel *store.Channel) error {
 	return nil
 }
 
+// videoDetails retrieves the videos of the playlist items from the API, keyed by their ID.
+func (i *Indexer) videoDetails(items []tube.PlaylistItem) (map[string]*tube.ResVideo, error) {
+	ids := make([]string, len(items))
+	for n, item := range items {
+		ids[n] = item.ContentDetails.VideoId
+	}
+
+	videos, err := i.yt.Videos(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	details := make(map[string]*tube.ResVideo, len(videos))
+	for n := range videos {
+		details[videos[n].Id] = &videos[n]
+	}
+
+	return details, nil
+}
+
 // IndexVideo retrieves YouTube captions for the given video and parses it.
 // A store.Video is created in the database, with multiple store.Transcript entries connected.
 //
 // If the video has captions disabled, or they can't be found, a store.Failure is created
 // of type store.FailureTypeNoCaptions and no error is returned.
+// Unless settings.WhisperFallback is false, then the video is skipped.
+// With store.CaptionSourceWhisper, the failure is created without looking for captions.
+// If the video is unavailable, the failure is created dead, recording why.
+// Failures are scored with Priority, using the details of the video if they were retrieved, details can be nil.
 //
-// The store.Video has either tube.TypeManual or tube.TypeAuto (preferring manual captions).
-func IndexVideo(ctx context.Context, channelId string, video tube.PlaylistItem) error {
+// The store.Video has either tube.TypeManual or tube.TypeAuto, which one is preferred depends on settings.CaptionSource.
+// Automatic captions have rolling duplicate text removed, see Dedupe, and are grouped into segments, see Segment.
+// Automatic captions are queued to be upgraded by whisper, depending on settings.UpgradeCaptions, see UpgradeReason.
+func (i *Indexer) IndexVideo(
+	ctx context.Context,
+	channelId string,
+	video tube.PlaylistItem,
+	details *tube.ResVideo,
+	settings store.ChannelSetting,
+) error {
 	videoId := video.ContentDetails.VideoId
-	captions, typ, err := Yt.Captions(videoId)
+	source := store.CaptionSource(settings.CaptionSource)
+
+	// Unavailable videos can miss the published time, they still get a failure, the error is returned when indexing.
+	published, publishedErr := tube.ParsePublishedTime(video.ContentDetails.VideoPublishedAt)
+	priority := Priority(settings, published, details)
+
+	var captions *tube.Transcript
+	var typ tube.TranscriptType
+	var err error
+	if source == store.CaptionSourceWhisper {
+		err = fmt.Errorf("channel is set to whisper %q: %w", videoId, tube.ErrNoCaptions)
+	} else {
+		captions, typ, err = i.yt.Captions(videoId, TrackPreference(source))
+	}
+
 	if err != nil {
 		if errors.Is(err, tube.ErrNoCaptions) {
+			if !settings.WhisperFallback && source != store.CaptionSourceWhisper {
+				log.Printf("[INFO]: no captions for %q and whisper fallback is disabled, skipping", videoId)
+				return nil
+			}
+
 			log.Printf("[WARN]: no captions for %q, adding to failures: %v", videoId, err)
 
-			if err := Queries.CreateFailure(ctx, store.CreateFailureParams{
+			if err := i.store.CreateFailure(ctx, store.CreateFailureParams{
 				ChannelID: channelId,
 				Data:      videoId,
 				Type:      string(store.FailureTypeNoCaptions),
+				Priority:  priority,
 			}); err != nil {
 				return fmt.Errorf("can't create failure for video %q: %w", videoId, err)
 			}
 
 			return nil
 		} else if errors.Is(err, tube.ErrUnavailable) {
-			log.Printf("[WARN]: %v", err)
+			log.Printf("[WARN]: %v, adding dead failure", err)
+
+			if err := i.store.CreateFailure(ctx, store.CreateFailureParams{
+				ChannelID: channelId,
+				Data:      videoId,
+				Type:      string(store.FailureTypeNoCaptions),
+				LastError: err.Error(),
+				Dead:      true,
+				Priority:  priority,
+			}); err != nil {
+				return fmt.Errorf("can't create failure for video %q: %w", videoId, err)
+			}
+
 			return nil
 		} else {
 			return fmt.Errorf("retrieving captions for %q: %w", videoId, err)
 		}
 	}
 
-	tx, err := Db.Begin()
-	if err != nil {
-		return fmt.Errorf("starting transaction: %w", err)
-	}
-	defer tx.Rollback() // Rollback, ignore error which is returned if tx is committed.
-
-	qtx := Queries.WithTx(tx)
-
-	published, err := tube.ParsePublishedTime(video.ContentDetails.VideoPublishedAt)
-	if err != nil {
-		return err
+	if publishedErr != nil {
+		return publishedErr
 	}
 
 	var t store.TranscriptType
@@ -169,67 +370,199 @@ func IndexVideo(ctx context.Context, channelId string, video tube.PlaylistItem)
 		panic("unreachable")
 	}
 
-	if err = qtx.CreateVideo(ctx, store.CreateVideoParams{
-		ID:                   videoId,
-		ChannelID:            channelId,
-		PublishedAt:          published,
-		Title:                video.Snippet.Title,
-		Description:          video.Snippet.Description,
-		ThumbnailUrl:         tube.HighestResThumbnail(video.Snippet.Thumbnails).Url,
-		SearchableTranscript: "",
-		TranscriptType:       string(t),
-	}); err != nil {
-		return fmt.Errorf("creating video %q: %w", videoId, err)
+	cues := make([]Cue, 0, len(captions.Entries))
+	for _, entry := range captions.Entries {
+		cues = append(cues, Cue{
+			Start: entry.Start,
+			Dur:   float64(entry.Dur),
+			Text:  html.UnescapeString(entry.Text),
+		})
+	}
+
+	if t == store.TubeAuto {
+		var stats DedupeStats
+		cues, stats = Dedupe(cues)
+		logDedupe(videoId, stats)
+
+		cues = Segment(cues, i.opts.Segment)
 	}
 
+	return i.store.Tx(ctx, func(qtx store.Querier) error {
+		if err := qtx.CreateVideo(ctx, store.CreateVideoParams{
+			ID:                    videoId,
+			ChannelID:             channelId,
+			PublishedAt:           published,
+			Title:                 video.Snippet.Title,
+			Description:           video.Snippet.Description,
+			ThumbnailUrl:          tube.HighestResThumbnail(video.Snippet.Thumbnails).Url,
+			SearchableTranscript:  "",
+			TranscriptType:        string(t),
+			SearchableTitle:       stem.StemText(video.Snippet.Title),
+			SearchableDescription: stem.StemText(video.Snippet.Description),
+			StemVersion:           stem.Version,
+		}); err != nil {
+			return fmt.Errorf("creating video %q: %w", videoId, err)
+		}
+
+		if err := StoreTranscripts(ctx, qtx, videoId, cues); err != nil {
+			return err
+		}
+
+		if t != store.TubeAuto {
+			return nil
+		}
+
+		lines := make([]string, 0, len(cues))
+		for _, cue := range cues {
+			lines = append(lines, cue.Text)
+		}
+
+		reason := UpgradeReason(store.UpgradeCaptions(settings.UpgradeCaptions), lines)
+		if reason == "" {
+			return nil
+		}
+
+		log.Printf("[INFO]: queueing upgrade of the automatic captions of %q: %s", videoId, reason)
+		if _, err := QueueUpgrade(ctx, qtx, channelId, videoId, priority); err != nil {
+			return fmt.Errorf("queueing upgrade of %q: %w", videoId, err)
+		}
+
+		return nil
+	})
+}
+
+// StoreTranscripts creates a store.Transcript for each cue and sets the searchable transcript of the video.
+func StoreTranscripts(ctx context.Context, q store.Querier, videoId string, cues []Cue) error {
 	searchable := strings.Builder{}
-	for _, entry := range captions.Entries {
-		txt := html.UnescapeString(entry.Text)
-		id, err := qtx.CreateTranscript(ctx, store.CreateTranscriptParams{
+	for _, cue := range cues {
+		id, err := q.CreateTranscript(ctx, store.CreateTranscriptParams{
 			VideoID: videoId,
-			Start:   int32(entry.Start),
-			Text:    txt,
+			Start:   int32(cue.Start),
+			Text:    cue.Text,
 		})
 		if err != nil {
-			return fmt.Errorf("inserting caption %v: %w", entry, err)
+			return fmt.Errorf("inserting caption %v: %w", cue, err)
 		}
 
 		searchable.WriteString(fmt.Sprintf("~%d~", id))
-		searchable.WriteString(stem.StemLine(txt))
+		searchable.WriteString(stem.StemLine(cue.Text))
 	}
 
-	if err = qtx.SetSearchableTranscript(ctx, store.SetSearchableTranscriptParams{
+	if err := q.SetSearchableTranscript(ctx, store.SetSearchableTranscriptParams{
 		ID:                   videoId,
 		SearchableTranscript: searchable.String(),
 	}); err != nil {
 		return fmt.Errorf("setting searchable transcript: %w", err)
 	}
 
-	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("committing transaction: %w", err)
+	return nil
+}
+
+// ResegmentVideos calls ResegmentVideo on every video with store.TubeAuto captions that is not segmented,
+// this groups the captions of videos that were indexed before segmenting was introduced.
+func (i *Indexer) ResegmentVideos(ctx context.Context) error {
+	ids, err := i.store.VideoIDsToResegment(ctx, string(store.TubeAuto))
+	if err != nil {
+		return fmt.Errorf("retrieving auto captioned videos: %w", err)
+	}
+
+	for n, id := range ids {
+		before, after, err := i.ResegmentVideo(ctx, id)
+		if err != nil {
+			return fmt.Errorf("resegmenting %q: %w", id, err)
+		}
+
+		log.Printf("[INFO]: (%d/%d) resegmented %q from %d to %d lines", n+1, len(ids), id, before, after)
 	}
 
 	return nil
 }
 
+// ResegmentVideo replaces the stored transcripts of the video with deduplicated segments of them.
+//
+// Stored transcripts have no duration, so each line is assumed to last until the next one starts.
+// This means only the length, duration and sentence bounds apply, not the pause bound.
+//
+// Segments would be merged and deduplicated again, so videos that are segmented already are left as is.
+func (i *Indexer) ResegmentVideo(ctx context.Context, videoId string) (before int, after int, err error) {
+	err = i.store.Tx(ctx, func(qtx store.Querier) error {
+		video, err := qtx.Video(ctx, videoId)
+		if err != nil {
+			return fmt.Errorf("retrieving video: %w", err)
+		}
+
+		transcripts, err := qtx.TranscriptsOfVideo(ctx, videoId)
+		if err != nil {
+			return fmt.Errorf("retrieving transcripts: %w", err)
+		}
+
+		if video.Segmented {
+			before, after = len(transcripts), len(transcripts)
+			return nil
+		}
+
+		cues := make([]Cue, len(transcripts))
+		for n, t := range transcripts {
+			cues[n] = Cue{Start: float64(t.Start), Text: t.Text}
+			if n+1 < len(transcripts) {
+				cues[n].Dur = float64(transcripts[n+1].Start - t.Start)
+			}
+		}
+		cues, stats := Dedupe(cues)
+		logDedupe(videoId, stats)
+		segments := Segment(cues, i.opts.Segment)
+
+		if err := qtx.DeleteTranscriptsOfVideo(ctx, videoId); err != nil {
+			return fmt.Errorf("deleting transcripts: %w", err)
+		}
+
+		before, after = len(transcripts), len(segments)
+		if err := StoreTranscripts(ctx, qtx, videoId, segments); err != nil {
+			return err
+		}
+
+		if err := qtx.SetSegmented(ctx, videoId); err != nil {
+			return fmt.Errorf("marking video as segmented: %w", err)
+		}
+
+		return nil
+	})
+	return before, after, err
+}
+
+func logDedupe(videoId string, stats DedupeStats) {
+	if stats.Cues == 0 {
+		return
+	}
+
+	log.Printf(
+		"[INFO]: deduplicated %d words in %d lines of %q, removing %d lines entirely",
+		stats.Words,
+		stats.Cues,
+		videoId,
+		stats.Removed,
+	)
+}
+
 // Channel fetches the channel from the database,
 // If it does not exists, the YouTube API is used to retrieve it
 // and create a new channel in the database.
-func Channel(ctx context.Context, id string) (*store.Channel, error) {
-	if ch, err := Queries.Channel(ctx, id); err == nil {
+func (i *Indexer) Channel(ctx context.Context, id string) (*store.Channel, error) {
+	if ch, err := i.store.Channel(ctx, id); err == nil {
 		return &ch, nil
 	}
 
-	info, err := Yt.ChannelInfo(id)
+	info, err := i.yt.ChannelInfo(id)
 	if err != nil {
 		return nil, fmt.Errorf("getting channel info through API: %w", err)
 	}
 
-	ch, err := Queries.CreateChannel(ctx, store.CreateChannelParams{
+	ch, err := i.store.CreateChannel(ctx, store.CreateChannelParams{
 		ID:           info.Id,
 		Title:        info.Snippet.Title,
 		VideosListID: info.ContentDetails.RelatedPlaylists.Uploads,
 		ThumbnailUrl: tube.HighestResThumbnail(info.Snippet.Thumbnails).Url,
+		CustomUrl:    info.Snippet.CustomUrl,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("creating channel in database: %w", err)
@@ -237,3 +570,38 @@ func Channel(ctx context.Context, id string) (*store.Channel, error) {
 
 	return &ch, nil
 }
+
+// RefreshChannel updates the title, thumbnail, custom url and uploads playlist of the channel
+// with the current information from the YouTube API, podcast channels are refreshed with RefreshFeed,
+// directory channels have nothing to refresh.
+func (i *Indexer) RefreshChannel(ctx context.Context, id string) (*store.Channel, error) {
+	channel, err := i.store.Channel(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving channel: %w", err)
+	}
+
+	switch store.Source(channel.Source) {
+	case store.SourcePodcast:
+		return i.RefreshFeed(ctx, &channel)
+	case store.SourceDirectory:
+		return &channel, nil
+	}
+
+	info, err := i.yt.ChannelInfo(id)
+	if err != nil {
+		return nil, fmt.Errorf("getting channel info through API: %w", err)
+	}
+
+	ch, err := i.store.UpdateChannel(ctx, store.UpdateChannelParams{
+		ID:           id,
+		Title:        info.Snippet.Title,
+		VideosListID: info.ContentDetails.RelatedPlaylists.Uploads,
+		ThumbnailUrl: tube.HighestResThumbnail(info.Snippet.Thumbnails).Url,
+		CustomUrl:    info.Snippet.CustomUrl,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("updating channel in database: %w", err)
+	}
+
+	return &ch, nil
+}
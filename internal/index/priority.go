@@ -0,0 +1,44 @@
+package index
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/store"
+	"github.com/laytan/youtupedia/internal/tube"
+)
+
+// ChannelPriorityWeight is the score of a point of channel priority,
+// it outweighs the properties of the video combined, so channel priority decides first.
+const ChannelPriorityWeight = 100
+
+// Priority is the score of a failure for the video, failures with a higher score are processed first.
+//
+// Newer videos, shorter videos and videos with more views score higher, up to 50, 20 and 30 points.
+// Properties that are not known, because published is zero, video is nil, or the API left them out, don't score.
+func Priority(settings store.ChannelSetting, published time.Time, video *tube.ResVideo) float64 {
+	score := float64(settings.Priority) * ChannelPriorityWeight
+
+	if !published.IsZero() {
+		// Halves every year.
+		years := math.Max(0, time.Since(published).Hours()/24/365)
+		score += 50 * math.Pow(0.5, years)
+	}
+
+	if video == nil {
+		return score
+	}
+
+	if duration, err := video.Duration(); err == nil && duration > 0 {
+		// Shorter videos are transcribed sooner, videos of 2 hours and longer don't score.
+		score += 20 * (1 - math.Min(1, duration.Hours()/2))
+	}
+
+	if views, err := strconv.ParseInt(video.Statistics.ViewCount, 10, 64); err == nil && views > 0 {
+		// 5 points per power of ten, full score at a million views.
+		score += 5 * math.Min(6, math.Log10(float64(views)))
+	}
+
+	return score
+}
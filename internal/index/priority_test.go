@@ -0,0 +1,62 @@
+package index_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/index"
+	"github.com/laytan/youtupedia/internal/store"
+	"github.com/laytan/youtupedia/internal/tube"
+)
+
+func TestPriority(t *testing.T) {
+	video := func(duration string, views string) *tube.ResVideo {
+		v := &tube.ResVideo{}
+		v.ContentDetails.Duration = duration
+		v.Statistics.ViewCount = views
+		return v
+	}
+
+	now := time.Now()
+	normal := store.ChannelSetting{}
+	important := store.ChannelSetting{Priority: 1}
+
+	tests := []struct {
+		name          string
+		higher, lower float64
+	}{
+		{
+			name:   "newer",
+			higher: index.Priority(normal, now, nil),
+			lower:  index.Priority(normal, now.AddDate(-2, 0, 0), nil),
+		},
+		{
+			name:   "shorter",
+			higher: index.Priority(normal, now, video("PT10M", "")),
+			lower:  index.Priority(normal, now, video("PT1H30M", "")),
+		},
+		{
+			name:   "more views",
+			higher: index.Priority(normal, now, video("PT10M", "50000")),
+			lower:  index.Priority(normal, now, video("PT10M", "300")),
+		},
+		{
+			name:   "channel priority first",
+			higher: index.Priority(important, now.AddDate(-10, 0, 0), video("PT3H", "1")),
+			lower:  index.Priority(normal, now, video("PT1M", "100000000")),
+		},
+		{
+			name:   "unknown properties don't score",
+			higher: index.Priority(normal, now, nil),
+			lower:  index.Priority(normal, time.Time{}, video("", "")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.higher <= tt.lower {
+				t.Errorf("priority %f should be higher than %f", tt.higher, tt.lower)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package index
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"runtime"
+	"strings"
+	"sync/atomic"
+
+	"github.com/laytan/youtupedia/internal/stem"
+	"github.com/laytan/youtupedia/internal/store"
+	"golang.org/x/sync/errgroup"
+)
+
+type ReindexOptions struct {
+	ChannelID string // Only reindex videos of this channel.
+	VideoID   string // Only reindex this video.
+
+	// Force reindexes videos that are already stemmed with the current stem.Version.
+	Force bool
+
+	BatchSize int // Amount of videos retrieved per query, defaults to 100.
+	Routines  int // Amount of videos reindexed concurrently, defaults to runtime.NumCPU().
+}
+
+// Reindex rebuilds the searchable data of videos that were stemmed with an older stem.Version,
+// from the transcripts stored in the database.
+//
+// Every video is rebuilt in its own transaction, so when Reindex is stopped,
+// calling it again continues with the videos that are still outdated.
+//
+// Returns the amount of reindexed videos.
+func Reindex(ctx context.Context, s store.Store, opts ReindexOptions) (int, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+
+	if opts.Routines <= 0 {
+		opts.Routines = runtime.NumCPU()
+	}
+
+	version := int32(stem.Version)
+	if opts.Force {
+		version = math.MaxInt32
+	}
+
+	var count atomic.Int64
+	var after string
+	for {
+		ids, err := s.StaleVideoIDs(ctx, store.StaleVideoIDsParams{
+			StemVersion: version,
+			ChannelID:   sql.NullString{String: opts.ChannelID, Valid: opts.ChannelID != ""},
+			VideoID:     sql.NullString{String: opts.VideoID, Valid: opts.VideoID != ""},
+			After:       after,
+			BatchSize:   int32(opts.BatchSize),
+		})
+		if err != nil {
+			return int(count.Load()), fmt.Errorf("retrieving outdated videos: %w", err)
+		}
+
+		if len(ids) == 0 {
+			return int(count.Load()), nil
+		}
+
+		group, ctx := errgroup.WithContext(ctx)
+		group.SetLimit(opts.Routines)
+		for _, id := range ids {
+			id := id
+			group.Go(func() error {
+				if err := s.Tx(ctx, func(qtx store.Querier) error {
+					return RebuildSearchable(ctx, qtx, id)
+				}); err != nil {
+					return fmt.Errorf("reindexing %q: %w", id, err)
+				}
+
+				count.Add(1)
+				return nil
+			})
+		}
+
+		if err := group.Wait(); err != nil {
+			return int(count.Load()), err
+		}
+
+		after = ids[len(ids)-1]
+		log.Printf("[INFO]: reindexed %d videos, up to %q", count.Load(), after)
+	}
+}
+
+// RebuildSearchable sets the searchable transcript, title and description of the video,
+// based on its stored transcripts, title and description, stemmed with the current stem.Version.
+func RebuildSearchable(ctx context.Context, q store.Querier, videoId string) error {
+	video, err := q.Video(ctx, videoId)
+	if err != nil {
+		return fmt.Errorf("retrieving video: %w", err)
+	}
+
+	transcripts, err := q.TranscriptsOfVideo(ctx, videoId)
+	if err != nil {
+		return fmt.Errorf("retrieving transcripts: %w", err)
+	}
+
+	searchable := strings.Builder{}
+	for _, t := range transcripts {
+		searchable.WriteString(fmt.Sprintf("~%d~", t.ID))
+		searchable.WriteString(stem.StemLine(t.Text))
+	}
+
+	if err := q.SetSearchable(ctx, store.SetSearchableParams{
+		ID:                    videoId,
+		SearchableTranscript:  searchable.String(),
+		SearchableTitle:       stem.StemText(video.Title),
+		SearchableDescription: stem.StemText(video.Description),
+		StemVersion:           stem.Version,
+	}); err != nil {
+		return fmt.Errorf("setting searchable data: %w", err)
+	}
+
+	return nil
+}
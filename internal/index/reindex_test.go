@@ -0,0 +1,153 @@
+package index_test
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/laytan/youtupedia/internal/index"
+	"github.com/laytan/youtupedia/internal/stem"
+	"github.com/laytan/youtupedia/internal/store"
+)
+
+// reindexStore is an in memory store.Store with the queries used by Reindex,
+// the embedded store.Store is nil, so other queries panic.
+type reindexStore struct {
+	store.Store
+
+	mu          sync.Mutex
+	videos      map[string]store.Video
+	transcripts map[string][]store.Transcript
+}
+
+func (s *reindexStore) Tx(ctx context.Context, f func(q store.Querier) error) error {
+	return f(s)
+}
+
+func (s *reindexStore) StaleVideoIDs(ctx context.Context, arg store.StaleVideoIDsParams) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []string
+	for id, v := range s.videos {
+		if v.StemVersion >= arg.StemVersion || id <= arg.After ||
+			(arg.ChannelID.Valid && v.ChannelID != arg.ChannelID.String) ||
+			(arg.VideoID.Valid && id != arg.VideoID.String) {
+			continue
+		}
+
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+	if len(ids) > int(arg.BatchSize) {
+		ids = ids[:arg.BatchSize]
+	}
+
+	return ids, nil
+}
+
+func (s *reindexStore) Video(ctx context.Context, id string) (store.Video, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.videos[id], nil
+}
+
+func (s *reindexStore) TranscriptsOfVideo(ctx context.Context, videoID string) ([]store.Transcript, error) {
+	return s.transcripts[videoID], nil
+}
+
+func (s *reindexStore) SetSearchable(ctx context.Context, arg store.SetSearchableParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v := s.videos[arg.ID]
+	v.SearchableTranscript = arg.SearchableTranscript
+	v.SearchableTitle = arg.SearchableTitle
+	v.SearchableDescription = arg.SearchableDescription
+	v.StemVersion = arg.StemVersion
+	s.videos[arg.ID] = v
+	return nil
+}
+
+func newReindexStore() *reindexStore {
+	return &reindexStore{
+		videos: map[string]store.Video{
+			"a": {ID: "a", ChannelID: "one", Title: "Running\nDogs", Description: "All about dogs."},
+			"b": {ID: "b", ChannelID: "one"},
+			"c": {ID: "c", ChannelID: "two"},
+			"d": {ID: "d", ChannelID: "one", StemVersion: stem.Version},
+		},
+		transcripts: map[string][]store.Transcript{
+			"a": {
+				{ID: 1, VideoID: "a", Text: "Thanks for watching!"},
+				{ID: 2, VideoID: "a", Text: "the running dogs"},
+			},
+		},
+	}
+}
+
+func TestRebuildSearchable(t *testing.T) {
+	s := newReindexStore()
+	if err := index.RebuildSearchable(context.Background(), s, "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	got := s.videos["a"]
+	want := []string{"~1~thank for watch~2~the run dog", "run dog", "all about dog"}
+	if gotFields := []string{got.SearchableTranscript, got.SearchableTitle, got.SearchableDescription}; !reflect.DeepEqual(gotFields, want) {
+		t.Errorf("RebuildSearchable() set %q, want %q", gotFields, want)
+	}
+
+	if got.StemVersion != stem.Version {
+		t.Errorf("RebuildSearchable() set stem version %d, want %d", got.StemVersion, stem.Version)
+	}
+}
+
+func TestReindex(t *testing.T) {
+	cases := []struct {
+		name string
+		opts index.ReindexOptions
+		want []string // Videos that are reindexed.
+	}{
+		{"outdated", index.ReindexOptions{}, []string{"a", "b", "c"}},
+		{"batches", index.ReindexOptions{BatchSize: 1, Routines: 1}, []string{"a", "b", "c"}},
+		{"channel", index.ReindexOptions{ChannelID: "one"}, []string{"a", "b"}},
+		{"video", index.ReindexOptions{VideoID: "b"}, []string{"b"}},
+		{"force", index.ReindexOptions{Force: true}, []string{"a", "b", "c", "d"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := newReindexStore()
+			// Marks the videos that are rebuilt, an outdated stem version would be overwritten with the current one.
+			for id, v := range s.videos {
+				v.SearchableTitle = "not rebuilt"
+				s.videos[id] = v
+			}
+
+			n, err := index.Reindex(context.Background(), s, c.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for id, v := range s.videos {
+				if v.SearchableTitle != "not rebuilt" {
+					got = append(got, id)
+				}
+
+				if c.opts.ChannelID == "" && c.opts.VideoID == "" && v.StemVersion != stem.Version {
+					t.Errorf("video %q has stem version %d after reindexing, want %d", id, v.StemVersion, stem.Version)
+				}
+			}
+			sort.Strings(got)
+
+			if n != len(c.want) || !reflect.DeepEqual(got, c.want) {
+				t.Errorf("Reindex() = %d, rebuilt %v, want %d, %v", n, got, len(c.want), c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package index
+
+import (
+	"strings"
+)
+
+// Cue is a single timed piece of caption text.
+type Cue struct {
+	Start float64 // Seconds.
+	Dur   float64 // Seconds, 0 if unknown.
+	Text  string
+}
+
+func (c Cue) end() float64 {
+	return c.Start + c.Dur
+}
+
+type SegmentOptions struct {
+	// MaxPause is the longest silence, in seconds, between two cues of the same segment.
+	MaxPause float64
+	// MaxChars is the maximum length of a segment's text, a single longer cue is not split.
+	MaxChars int
+	// MaxDuration is the maximum amount of seconds a segment spans.
+	MaxDuration float64
+}
+
+var DefaultSegmentOptions = SegmentOptions{
+	MaxPause:    1.5,
+	MaxChars:    200,
+	MaxDuration: 15,
+}
+
+// Segment merges consecutive cues into sentence-level segments,
+// this is meant for YouTube's automatic captions, which are a couple of words per cue.
+//
+// A new segment is started when the previous cue ends a sentence, when the pause between cues is
+// longer than opts.MaxPause, or when adding the cue would exceed opts.MaxChars or opts.MaxDuration.
+//
+// A segment starts at the earliest start of its cues, and lasts until the end of its last cue.
+func Segment(cues []Cue, opts SegmentOptions) []Cue {
+	var (
+		segments []Cue
+		curr     Cue
+		text     strings.Builder
+		open     bool
+	)
+
+	flush := func() {
+		if !open {
+			return
+		}
+
+		curr.Text = text.String()
+		segments = append(segments, curr)
+		text.Reset()
+		open = false
+	}
+
+	for _, cue := range cues {
+		txt := strings.Join(strings.Fields(cue.Text), " ")
+		if txt == "" {
+			continue
+		}
+
+		if open {
+			pause := cue.Start - curr.end()
+			if pause > opts.MaxPause ||
+				text.Len()+1+len(txt) > opts.MaxChars ||
+				cue.end()-curr.Start > opts.MaxDuration ||
+				endsSentence(text.String()) {
+				flush()
+			}
+		}
+
+		if !open {
+			curr = Cue{Start: cue.Start, Dur: cue.Dur}
+			open = true
+		} else {
+			text.WriteByte(' ')
+			if cue.Start < curr.Start {
+				curr.Dur += curr.Start - cue.Start
+				curr.Start = cue.Start
+			}
+			if cue.end() > curr.end() {
+				curr.Dur = cue.end() - curr.Start
+			}
+		}
+
+		text.WriteString(txt)
+	}
+
+	flush()
+	return segments
+}
+
+func endsSentence(txt string) bool {
+	return strings.HasSuffix(txt, ".") ||
+		strings.HasSuffix(txt, "?") ||
+		strings.HasSuffix(txt, "!")
+}
@@ -0,0 +1,81 @@
+package index_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/laytan/youtupedia/internal/index"
+)
+
+func TestSegment(t *testing.T) {
+	opts := index.SegmentOptions{MaxPause: 1.5, MaxChars: 30, MaxDuration: 10}
+
+	tests := []struct {
+		name string
+		cues []index.Cue
+		want []index.Cue
+	}{
+		{
+			name: "merges fragments",
+			cues: []index.Cue{
+				{Start: 0, Dur: 1, Text: "so today we"},
+				{Start: 1, Dur: 1, Text: "are going to"},
+				{Start: 2, Dur: 1, Text: "talk"},
+			},
+			want: []index.Cue{{Start: 0, Dur: 3, Text: "so today we are going to talk"}},
+		},
+		{
+			name: "splits on pause",
+			cues: []index.Cue{
+				{Start: 0, Dur: 1, Text: "hello"},
+				{Start: 5, Dur: 1, Text: "world"},
+			},
+			want: []index.Cue{{Start: 0, Dur: 1, Text: "hello"}, {Start: 5, Dur: 1, Text: "world"}},
+		},
+		{
+			name: "splits on max chars",
+			cues: []index.Cue{
+				{Start: 0, Dur: 1, Text: "this is a fairly long"},
+				{Start: 1, Dur: 1, Text: "fragment of text"},
+			},
+			want: []index.Cue{
+				{Start: 0, Dur: 1, Text: "this is a fairly long"},
+				{Start: 1, Dur: 1, Text: "fragment of text"},
+			},
+		},
+		{
+			name: "splits on max duration",
+			cues: []index.Cue{
+				{Start: 0, Dur: 6, Text: "one"},
+				{Start: 6, Dur: 6, Text: "two"},
+			},
+			want: []index.Cue{{Start: 0, Dur: 6, Text: "one"}, {Start: 6, Dur: 6, Text: "two"}},
+		},
+		{
+			name: "splits on sentence end",
+			cues: []index.Cue{
+				{Start: 0, Dur: 1, Text: "Done."},
+				{Start: 1, Dur: 1, Text: "Next"},
+			},
+			want: []index.Cue{{Start: 0, Dur: 1, Text: "Done."}, {Start: 1, Dur: 1, Text: "Next"}},
+		},
+		{
+			name: "keeps earliest start and normalizes whitespace",
+			cues: []index.Cue{
+				{Start: 2, Dur: 1, Text: "late\n start"},
+				{Start: 1, Dur: 1, Text: " "},
+				{Start: 1.5, Dur: 2, Text: "early"},
+			},
+			want: []index.Cue{{Start: 1.5, Dur: 2, Text: "late start early"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := index.Segment(tt.cues, opts)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Segment() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
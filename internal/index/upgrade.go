@@ -0,0 +1,36 @@
+package index
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/laytan/youtupedia/internal/quality"
+	"github.com/laytan/youtupedia/internal/store"
+)
+
+// UpgradeReason returns why the store.TubeAuto captions should be upgraded according to the setting of the channel,
+// or an empty string if they should not.
+func UpgradeReason(setting store.UpgradeCaptions, lines []string) string {
+	switch setting {
+	case store.UpgradeCaptionsAlways:
+		return "the channel always upgrades automatic captions"
+	case store.UpgradeCaptionsLowQuality:
+		return quality.Measure(lines).Low()
+	default:
+		return ""
+	}
+}
+
+// QueueUpgrade creates a store.FailureTypeUpgrade failure for the video, if it has store.TubeAuto captions,
+// and is not queued already. Returns whether a failure was created.
+// Captions that were kept over a whisper transcript before, see store.TranscriptVersionRejected, are not queued again.
+func QueueUpgrade(ctx context.Context, q store.Querier, channelId string, videoId string, priority float64) (bool, error) {
+	n, err := q.QueueRetranscribes(ctx, store.QueueRetranscribesParams{
+		FailureType:    string(store.FailureTypeUpgrade),
+		Priority:       priority,
+		ChannelID:      channelId,
+		TranscriptType: sql.NullString{String: string(store.TubeAuto), Valid: true},
+		VideoID:        sql.NullString{String: videoId, Valid: true},
+	})
+	return n > 0, err
+}
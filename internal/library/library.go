@@ -0,0 +1,149 @@
+// Package library reads local directories of media files, files are identified by a hash of their content.
+package library
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/tube"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// extensions are the audio and video formats that are transcribed, anything ffmpeg decodes would work,
+// but directories of recordings tend to have notes, slides and images next to them.
+var extensions = map[string]bool{
+	".aac":  true,
+	".avi":  true,
+	".flac": true,
+	".m4a":  true,
+	".m4v":  true,
+	".mkv":  true,
+	".mov":  true,
+	".mp3":  true,
+	".mp4":  true,
+	".oga":  true,
+	".ogg":  true,
+	".opus": true,
+	".wav":  true,
+	".webm": true,
+	".wma":  true,
+	".wmv":  true,
+}
+
+// File is a media file of a library.
+type File struct {
+	Path     string // Absolute.
+	Size     int64
+	Modified time.Time
+}
+
+// IsMedia reports whether the path has the extension of an audio or video format.
+func IsMedia(path string) bool {
+	return extensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// Scan returns the media files in dir and its subdirectories, sorted by path.
+// Hidden files and directories, starting with a dot, are skipped.
+func Scan(dir string) ([]File, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("absolute path of %q: %w", dir, err)
+	}
+
+	var files []File
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !d.Type().IsRegular() || !IsMedia(path) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		files = append(files, File{Path: path, Size: info.Size(), Modified: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning %q: %w", dir, err)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// Stat returns the media file at path.
+func Stat(path string) (File, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return File{}, fmt.Errorf("media file %q: %w", path, ErrNotFound)
+		}
+
+		return File{}, fmt.Errorf("media file %q: %w", path, err)
+	}
+
+	return File{Path: path, Size: info.Size(), Modified: info.ModTime()}, nil
+}
+
+// ChannelID returns the ID of the channel of the library at dir, an absolute path.
+func ChannelID(dir string) string {
+	sum := sha256.Sum256([]byte(dir))
+	return "dir-" + hex.EncodeToString(sum[:8])
+}
+
+// VideoID returns the video ID of the file at path, from the SHA-256 of its content,
+// so files keep their video when they are moved or renamed, and copies are transcribed once.
+func VideoID(path string) (string, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("opening %q: %w", path, err)
+	}
+	defer fh.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, fh); err != nil {
+		return "", fmt.Errorf("hashing %q: %w", path, err)
+	}
+
+	return "file-" + hex.EncodeToString(h.Sum(nil)[:8]), nil
+}
+
+// Title returns the title of the file, its name without the extension.
+func (f *File) Title() string {
+	name := filepath.Base(f.Path)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// Video describes the file like a YouTube video, so it is filtered and indexed like one.
+// It is published when it was last modified, its duration is not known.
+func (f *File) Video(channelID string, id string) *tube.ResVideo {
+	video := &tube.ResVideo{Id: id}
+	video.Snippet.ChannelId = channelID
+	video.Snippet.Title = f.Title()
+	video.Snippet.PublishedAt = f.Modified.UTC().Format("2006-01-02T15:04:05Z")
+	video.Snippet.LiveBroadcastContent = "none"
+	return video
+}
@@ -0,0 +1,92 @@
+package library_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/laytan/youtupedia/internal/library"
+)
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"talk.mp4":             "video",
+		"notes.txt":            "notes",
+		"2023/keynote.MP3":     "audio",
+		"2023/slides.pdf":      "slides",
+		".cache/thumbnail.mp4": "hidden",
+		".hidden.wav":          "hidden",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := library.Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	for _, f := range got {
+		paths = append(paths, f.Path)
+	}
+
+	want := []string{
+		filepath.Join(dir, "2023", "keynote.MP3"),
+		filepath.Join(dir, "talk.mp4"),
+	}
+
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("Scan() = %v, want %v", paths, want)
+	}
+}
+
+func TestVideoID(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+
+		return path
+	}
+
+	tests := []struct {
+		name  string
+		a     string
+		b     string
+		equal bool
+	}{
+		{name: "copy", a: write("a.mp3", "audio"), b: write("copy of a.mp3", "audio"), equal: true},
+		{name: "different content", a: write("c.mp3", "audio"), b: write("d.mp3", "other audio"), equal: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := library.VideoID(tt.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := library.VideoID(tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if (a == b) != tt.equal {
+				t.Errorf("VideoID() = %q and %q, want equal: %t", a, b, tt.equal)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+// Package quality measures heuristics of transcripts, to find automatic captions that whisper would improve.
+package quality
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	// MinWords is the amount of words a transcript needs before its metrics say anything.
+	MinWords = 50
+
+	// MinPunctuation is the punctuation per word below which a transcript is low quality,
+	// speech recognition that doesn't punctuate also tends to get sentences wrong.
+	MinPunctuation = 0.02
+
+	// MaxRepetition is the repetition above which a transcript is low quality,
+	// speech recognition that goes wrong tends to repeat itself.
+	MaxRepetition = 0.25
+
+	// RepetitionMargin is how much more a transcript can repeat itself than the one it replaces.
+	RepetitionMargin = 0.1
+)
+
+// Metrics are heuristics for the quality of a transcript.
+type Metrics struct {
+	Words       int
+	Punctuation float64 // Sentence punctuation marks per word.
+	Repetition  float64 // Fraction of the word trigrams that occurred before in the transcript.
+}
+
+// Measure calculates the metrics of the lines of a transcript.
+// Annotations like [Music] are ignored.
+func Measure(lines []string) Metrics {
+	var words []string
+	var punctuation int
+	for _, line := range lines {
+		for _, field := range strings.Fields(line) {
+			if strings.HasPrefix(field, "[") && strings.HasSuffix(field, "]") {
+				continue
+			}
+
+			punctuation += strings.Count(field, ".") +
+				strings.Count(field, ",") +
+				strings.Count(field, "?") +
+				strings.Count(field, "!") +
+				strings.Count(field, ";") +
+				strings.Count(field, ":")
+
+			word := strings.ToLower(strings.TrimFunc(field, func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+			}))
+			if word != "" {
+				words = append(words, word)
+			}
+		}
+	}
+
+	m := Metrics{Words: len(words)}
+	if m.Words == 0 {
+		return m
+	}
+
+	m.Punctuation = float64(punctuation) / float64(m.Words)
+
+	if m.Words >= 3 {
+		seen := make(map[[3]string]struct{}, m.Words)
+		var repeated int
+		for i := 0; i+3 <= len(words); i++ {
+			trigram := [3]string{words[i], words[i+1], words[i+2]}
+			if _, ok := seen[trigram]; ok {
+				repeated++
+			}
+			seen[trigram] = struct{}{}
+		}
+
+		m.Repetition = float64(repeated) / float64(len(words)-2)
+	}
+
+	return m
+}
+
+// Low returns why the transcript is low quality, or an empty string if it is not,
+// or too short to tell.
+func (m Metrics) Low() string {
+	switch {
+	case m.Words < MinWords:
+		return ""
+	case m.Punctuation < MinPunctuation:
+		return fmt.Sprintf("punctuation of %.3f per word is below %.3f", m.Punctuation, MinPunctuation)
+	case m.Repetition > MaxRepetition:
+		return fmt.Sprintf("repetition of %.3f is above %.3f", m.Repetition, MaxRepetition)
+	default:
+		return ""
+	}
+}
+
+// Better returns whether the candidate transcript should replace the current transcript, and why.
+// A candidate that lost most of the words, or repeats itself a lot more, is a transcription gone wrong.
+func Better(current, candidate Metrics) (bool, string) {
+	switch {
+	case candidate.Words == 0:
+		return false, "the candidate is empty"
+	case candidate.Words*2 < current.Words:
+		return false, fmt.Sprintf("the candidate has %d words, the current transcript %d", candidate.Words, current.Words)
+	case candidate.Repetition > current.Repetition+RepetitionMargin:
+		return false, fmt.Sprintf(
+			"the candidate has a repetition of %.3f, the current transcript %.3f",
+			candidate.Repetition,
+			current.Repetition,
+		)
+	case candidate.Punctuation <= current.Punctuation && candidate.Repetition >= current.Repetition:
+		return false, "the candidate is neither better punctuated nor less repetitive"
+	default:
+		return true, fmt.Sprintf(
+			"punctuation %.3f -> %.3f, repetition %.3f -> %.3f",
+			current.Punctuation,
+			candidate.Punctuation,
+			current.Repetition,
+			candidate.Repetition,
+		)
+	}
+}
@@ -0,0 +1,74 @@
+package quality_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/laytan/youtupedia/internal/quality"
+)
+
+func TestMeasure(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  quality.Metrics
+	}{
+		{
+			name:  "empty",
+			lines: nil,
+			want:  quality.Metrics{},
+		},
+		{
+			name:  "punctuation",
+			lines: []string{"Hello, world.", "How are you?"},
+			want:  quality.Metrics{Words: 5, Punctuation: 0.6},
+		},
+		{
+			name:  "ignores annotations",
+			lines: []string{"[Music]", "so today"},
+			want:  quality.Metrics{Words: 2},
+		},
+		{
+			name:  "repetition",
+			lines: []string{"a b c a b c"},
+			want:  quality.Metrics{Words: 6, Repetition: 0.25},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := quality.Measure(tt.lines); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Measure() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBetter(t *testing.T) {
+	auto := quality.Measure([]string{strings.Repeat("so we go there and then ", 10)})
+	whisper := quality.Measure([]string{strings.Repeat("So, we go there. And then? ", 10)})
+	hallucinated := quality.Measure([]string{strings.Repeat("Thank you. ", 60)})
+	short := quality.Measure([]string{"So, we go there."})
+
+	tests := []struct {
+		name      string
+		current   quality.Metrics
+		candidate quality.Metrics
+		want      bool
+	}{
+		{name: "punctuated", current: auto, candidate: whisper, want: true},
+		{name: "empty", current: auto, candidate: quality.Metrics{}, want: false},
+		{name: "lost words", current: auto, candidate: short, want: false},
+		{name: "repeats itself", current: short, candidate: hallucinated, want: false},
+		{name: "same", current: whisper, candidate: whisper, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, reason := quality.Better(tt.current, tt.candidate); got != tt.want {
+				t.Errorf("Better() = %t (%s), want %t", got, reason, tt.want)
+			}
+		})
+	}
+}
@@ -8,49 +8,186 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/laytan/youtupedia/internal/stem"
 	"github.com/laytan/youtupedia/internal/store"
 	"golang.org/x/sync/errgroup"
 )
 
-var (
-	Queries        *store.Queries
-	SearchRoutines = 20
-	MaxResults     = 100
+type Options struct {
+	// Routines is the amount of videos searched concurrently, defaults to 20.
+	Routines int
+	// MaxResults is the maximum amount of videos returned, defaults to 100.
+	MaxResults int
+}
+
+// Searcher searches through the transcripts of indexed videos.
+type Searcher struct {
+	store store.Store
+	opts  Options
+}
+
+func New(s store.Store, opts Options) *Searcher {
+	if opts.Routines <= 0 {
+		opts.Routines = 20
+	}
+
+	if opts.MaxResults <= 0 {
+		opts.MaxResults = 100
+	}
+
+	return &Searcher{
+		store: s,
+		opts:  opts,
+	}
+}
+
+// Mode is how the words of a query match a video.
+type Mode string
+
+const (
+	ModePhrase Mode = "phrase" // The words in order, next to each other, the default.
+	ModeAll    Mode = "all"    // All the words, anywhere in the video.
+	ModeNear   Mode = "near"   // All the words, within NearLines consecutive lines.
+	ModeAny    Mode = "any"    // Any of the words.
 )
 
+// Modes are the modes, in the order they are offered.
+var Modes = []Mode{ModePhrase, ModeAll, ModeNear, ModeAny}
+
+// NearLines is the amount of consecutive lines the words of a ModeNear query are in,
+// captions often split sentences over multiple lines.
+const NearLines = 3
+
+// ParseMode parses the mode, empty is ModePhrase.
+func ParseMode(value string) (Mode, error) {
+	if value == "" {
+		return ModePhrase, nil
+	}
+
+	for _, mode := range Modes {
+		if string(mode) == value {
+			return mode, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown search mode %q", value)
+}
+
+// match returns how the videos that might match the mode are retrieved.
+func (m Mode) match() store.WordMatch {
+	switch m {
+	case ModeAll, ModeNear:
+		return store.WordsAll
+	case ModeAny:
+		return store.WordsAny
+	default:
+		return store.WordsInOrder
+	}
+}
+
+// Query describes what to search for.
+type Query struct {
+	Text string
+	// Mode is how the words of the text match, defaults to ModePhrase.
+	Mode Mode
+	// Metadata also matches the text against the titles and descriptions of videos.
+	Metadata bool
+
+	Since time.Time // Only match videos published at or after this time.
+	Until time.Time // Only match videos published before this time.
+	Limit int       // Maximum amount of videos returned, defaults to Options.MaxResults.
+
+	// Speaker only matches lines labelled with this speaker, which excludes undiarized transcripts and metadata.
+	Speaker string
+}
+
+// SplitSpeaker removes a "speaker:<label>" filter from the text of a query,
+// returning the remaining text and the label, which is empty without a filter.
+func SplitSpeaker(text string) (string, string) {
+	var speaker string
+	fields := strings.Fields(text)
+	rest := fields[:0]
+	for _, field := range fields {
+		if label, ok := strings.CutPrefix(field, "speaker:"); ok && label != "" {
+			speaker = label
+			continue
+		}
+
+		rest = append(rest, field)
+	}
+
+	return strings.Join(rest, " "), speaker
+}
+
+func (q *Query) inRange(published time.Time) bool {
+	if !q.Since.IsZero() && published.Before(q.Since) {
+		return false
+	}
+
+	return q.Until.IsZero() || published.Before(q.Until)
+}
+
 type Result struct {
-	Video   store.Video
-	Results []store.Transcript
-	ids     []int64
+	Video    store.Video
+	Results  []store.Transcript
+	Speakers map[int64]string // Speaker labels of the diarized Results, by transcript ID.
+	ids      []int64
+
+	InTitle       bool // The query matched the title of the video.
+	InDescription bool // The query matched the description of the video.
 }
 
 // Channel retrieves all the videos for the given channel, calling Video on each of them.
-// The results are sorted based on the published time of the video.
-func Channel(ctx context.Context, ch *store.Channel, query string) (res []Result, err error) {
-	// Retrieves the videos that contain all the words we query.
-	// These are optimistic matches, because they have to be in order,
+// Videos stemmed with an outdated stem.Version do not match reliably, see index.Reindex.
+// The results are sorted based on the published time of the video,
+// when matching metadata, videos with a matching title come first.
+func (s *Searcher) Channel(ctx context.Context, ch *store.Channel, query Query) (res []Result, err error) {
+	mode := query.Mode
+	if mode == "" {
+		mode = ModePhrase
+	}
+
+	// Retrieves the videos that contain the words we query, as required by the mode.
+	// These are optimistic matches, because words are matched as substrings, phrases have to be next to each other,
 	// and they can span the metadata boundaries, and we have to return the exact part of the transcripts.
-	stemmedQuery := stem.StemLine(query)
-	videos, err := Queries.VideosOfChannelWithWords(ctx, ch.ID, strings.Split(stemmedQuery, " "))
+	stemmedQuery := stem.StemLine(query.Text)
+	words := strings.Split(stemmedQuery, " ")
+	videos, err := s.store.VideosOfChannelMatching(ctx, ch.ID, words, mode.match(), query.Metadata)
 	if err != nil {
 		return nil, fmt.Errorf("retrieving channel videos: %w", err)
 	}
 
 	log.Printf("[INFO]: searching through %d optimistic video matches", len(videos))
 	var group errgroup.Group
-	group.SetLimit(SearchRoutines)
+	group.SetLimit(s.opts.Routines)
 	var mu sync.Mutex
 	for _, vid := range videos {
 		vid := vid
+		if !query.inRange(vid.PublishedAt) {
+			continue
+		}
+
 		group.Go(func() error {
-			results, err := Video(&vid, stemmedQuery)
+			var results []int64
+			var err error
+			if mode == ModePhrase {
+				results, err = Video(&vid, stemmedQuery)
+			} else {
+				results, err = Words(&vid, words, mode)
+			}
 			if err != nil {
 				return fmt.Errorf("searching: %w", err)
 			}
 
-			if len(results) == 0 {
+			var inTitle, inDescription bool
+			if query.Metadata {
+				inTitle = matchText(vid.SearchableTitle, stemmedQuery, words, mode)
+				inDescription = matchText(vid.SearchableDescription, stemmedQuery, words, mode)
+			}
+
+			if len(results) == 0 && !inTitle && !inDescription {
 				return nil
 			}
 
@@ -58,9 +195,11 @@ func Channel(ctx context.Context, ch *store.Channel, query string) (res []Result
 			defer mu.Unlock()
 
 			res = append(res, Result{
-				Video:   vid,
-				Results: nil,
-				ids:     results,
+				Video:         vid,
+				Results:       nil,
+				ids:           results,
+				InTitle:       inTitle,
+				InDescription: inDescription,
 			})
 			return nil
 		})
@@ -69,13 +208,33 @@ func Channel(ctx context.Context, ch *store.Channel, query string) (res []Result
 		return nil, fmt.Errorf("iterating videos: %w", err)
 	}
 
+	// The speakers are retrieved once, before filtering by them, or else for the results that are returned.
+	var labels map[int64]string
+	if query.Speaker != "" {
+		labels, err = s.speakers(ctx, res)
+		if err != nil {
+			return nil, err
+		}
+
+		res = filterSpeaker(res, query.Speaker, labels)
+	}
+
 	sort.Slice(res, func(i, j int) bool {
+		if res[i].InTitle != res[j].InTitle {
+			return res[i].InTitle
+		}
+
 		return res[j].Video.PublishedAt.Before(res[i].Video.PublishedAt)
 	})
 
-	log.Printf("[INFO]: there were %d actual video matches, capping to %d", len(res), MaxResults)
-	if len(res) > MaxResults {
-		res = res[:MaxResults]
+	limit := s.opts.MaxResults
+	if query.Limit > 0 {
+		limit = query.Limit
+	}
+
+	log.Printf("[INFO]: there were %d actual video matches, capping to %d", len(res), limit)
+	if len(res) > limit {
+		res = res[:limit]
 	}
 
 	// Flatten all resulting transcripts into one slice of ids,
@@ -86,7 +245,7 @@ func Channel(ctx context.Context, ch *store.Channel, query string) (res []Result
 	}
 
 	log.Printf("[INFO]: retrieving %d matched captions/lines", len(all))
-	ts, err := Queries.TranscriptsByIds(ctx, all)
+	ts, err := s.store.TranscriptsByIds(ctx, all)
 	if err != nil {
 		return nil, fmt.Errorf("querying transcripts: %w", err)
 	}
@@ -100,9 +259,78 @@ func Channel(ctx context.Context, ch *store.Channel, query string) (res []Result
 		curr += len(res[i].ids)
 	}
 
+	if labels == nil {
+		labels, err = s.speakers(ctx, res)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range res {
+		for _, id := range res[i].ids {
+			if label, ok := labels[id]; ok {
+				if res[i].Speakers == nil {
+					res[i].Speakers = make(map[int64]string)
+				}
+
+				res[i].Speakers[id] = label
+			}
+		}
+	}
+
 	return res, nil
 }
 
+// Speaker returns the label of the speaker of the transcript, empty if it was not diarized.
+func (r Result) Speaker(id int64) string {
+	return r.Speakers[id]
+}
+
+// speakers returns the labels of the speakers of the matched lines, by transcript ID.
+// Lines that were not diarized have no label.
+func (s *Searcher) speakers(ctx context.Context, res []Result) (map[int64]string, error) {
+	var all []int64
+	for _, r := range res {
+		all = append(all, r.ids...)
+	}
+
+	speakers, err := s.store.SpeakersOfTranscripts(ctx, all)
+	if err != nil {
+		return nil, fmt.Errorf("querying speakers: %w", err)
+	}
+
+	labels := make(map[int64]string, len(speakers))
+	for _, speaker := range speakers {
+		labels[speaker.TranscriptID] = speaker.Speaker
+	}
+
+	return labels, nil
+}
+
+// filterSpeaker keeps the matched lines of the speaker, dropping results without any, labels are from speakers.
+func filterSpeaker(res []Result, speaker string, labels map[int64]string) []Result {
+	filtered := res[:0]
+	for _, r := range res {
+		ids := r.ids[:0]
+		for _, id := range r.ids {
+			if label, ok := labels[id]; ok && strings.EqualFold(label, speaker) {
+				ids = append(ids, id)
+			}
+		}
+
+		if len(ids) == 0 {
+			continue
+		}
+
+		r.ids = ids
+		r.InTitle = false
+		r.InDescription = false
+		filtered = append(filtered, r)
+	}
+
+	return filtered
+}
+
 // Video searches for the query inside the video's searchable_transcript.
 // Returning the IDs of the matching transcripts.
 //
@@ -154,3 +382,147 @@ func Video(vid *store.Video, query string) (res []int64, err error) {
 
 	return res, nil
 }
+
+// Words searches for the words inside the video's searchable_transcript, as required by the mode,
+// returning the IDs of the transcripts that have any of the words, in the order of the transcript.
+//
+// Unlike Video, whole words are matched, so "cat" does not match "category".
+// NOTE: you must stem the words yourself, ModePhrase is searched with Video instead.
+func Words(vid *store.Video, words []string, mode Mode) ([]int64, error) {
+	lines, err := searchableLines(vid.SearchableTranscript)
+	if err != nil {
+		return nil, err
+	}
+
+	want := make(map[string]bool, len(words))
+	for _, word := range words {
+		if word != "" {
+			want[word] = true
+		}
+	}
+
+	if len(want) == 0 {
+		return nil, nil
+	}
+
+	// The query words in each line.
+	found := make([]map[string]bool, len(lines))
+	for i, line := range lines {
+		for _, word := range strings.Fields(line.text) {
+			if want[word] {
+				if found[i] == nil {
+					found[i] = make(map[string]bool)
+				}
+
+				found[i][word] = true
+			}
+		}
+	}
+
+	var res []int64
+	switch mode {
+	case ModeAny:
+		for i, line := range lines {
+			if found[i] != nil {
+				res = append(res, line.id)
+			}
+		}
+	case ModeAll:
+		all := make(map[string]bool, len(want))
+		for i, line := range lines {
+			if found[i] != nil {
+				res = append(res, line.id)
+			}
+
+			for word := range found[i] {
+				all[word] = true
+			}
+		}
+
+		if len(all) < len(want) {
+			return nil, nil
+		}
+	case ModeNear:
+		// The lines of each window that has all the words, a line is returned once.
+		returned := -1
+		for end := range lines {
+			start := end - NearLines + 1
+			if start < 0 {
+				start = 0
+			}
+
+			window := make(map[string]bool, len(want))
+			for i := start; i <= end; i++ {
+				for word := range found[i] {
+					window[word] = true
+				}
+			}
+
+			if len(window) < len(want) {
+				continue
+			}
+
+			for i := start; i <= end; i++ {
+				if i > returned && found[i] != nil {
+					res = append(res, lines[i].id)
+					returned = i
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown search mode %q", mode)
+	}
+
+	return res, nil
+}
+
+// matchText returns whether the searchable text, a title or description, matches the query as required by the mode.
+func matchText(text string, stemmedQuery string, words []string, mode Mode) bool {
+	if mode == ModePhrase {
+		return strings.Contains(text, stemmedQuery)
+	}
+
+	fields := make(map[string]bool)
+	for _, field := range strings.Fields(text) {
+		fields[field] = true
+	}
+
+	var matching int
+	for _, word := range words {
+		if fields[word] {
+			matching++
+		}
+	}
+
+	if mode == ModeAny {
+		return matching > 0
+	}
+
+	// A title or description is a single window.
+	return matching == len(words)
+}
+
+type searchableLine struct {
+	id   int64
+	text string
+}
+
+// searchableLines splits a searchable_transcript, "~1~first line~2~second line", into its lines.
+func searchableLines(searchable string) ([]searchableLine, error) {
+	parts := strings.Split(searchable, "~")
+	if len(parts) < 3 {
+		return nil, nil
+	}
+
+	lines := make([]searchableLine, 0, len(parts)/2)
+	for i := 1; i+1 < len(parts); i += 2 {
+		id, err := strconv.ParseInt(parts[i], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse id string: %w", err)
+		}
+
+		lines = append(lines, searchableLine{id: id, text: parts[i+1]})
+	}
+
+	return lines, nil
+}
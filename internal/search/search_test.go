@@ -6,11 +6,13 @@ import (
 	"database/sql"
 	"log"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/laytan/youtupedia/internal/index"
 	"github.com/laytan/youtupedia/internal/search"
 	"github.com/laytan/youtupedia/internal/store"
+	"github.com/laytan/youtupedia/internal/tube"
 	_ "github.com/lib/pq"
 )
 
@@ -34,19 +36,71 @@ func BenchmarkSearch(b *testing.B) {
 	buf := bytes.Buffer{}
 	log.SetOutput(&buf)
 
-	queries := store.New(d)
-	index.Queries = queries
-	search.Queries = queries
+	db := store.NewDB(d)
+	indexer := index.New(db, &tube.Client{Key: os.Getenv("YT_KEY")}, index.Options{})
+	searcher := search.New(db, search.Options{})
 
-	channel, err := index.Channel(ctx, Channel)
+	channel, err := indexer.Channel(ctx, Channel)
 	if err != nil {
 		panic(err)
 	}
 
 	for i := 0; i < b.N; i++ {
-		_, err := search.Channel(ctx, channel, Query)
+		_, err := searcher.Channel(ctx, channel, search.Query{Text: Query})
 		if err != nil {
 			panic(err)
 		}
 	}
 }
+
+func TestSplitSpeaker(t *testing.T) {
+	cases := []struct {
+		text        string
+		wantText    string
+		wantSpeaker string
+	}{
+		{"thanks for watching", "thanks for watching", ""},
+		{"speaker:2 thanks for watching", "thanks for watching", "2"},
+		{"thanks  speaker:1 for watching", "thanks for watching", "1"},
+		{"thanks for watching speaker:", "thanks for watching speaker:", ""},
+		{"speaker:1", "", "1"},
+	}
+
+	for _, c := range cases {
+		text, speaker := search.SplitSpeaker(c.text)
+		if got, want := []string{text, speaker}, []string{c.wantText, c.wantSpeaker}; !reflect.DeepEqual(got, want) {
+			t.Errorf("SplitSpeaker(%q) = %q, want %q", c.text, got, want)
+		}
+	}
+}
+
+func TestWords(t *testing.T) {
+	vid := &store.Video{
+		SearchableTranscript: "~1~thank you for watch~2~the categori of cat~3~and dog~4~are great pet~5~see you next time",
+	}
+
+	cases := []struct {
+		words []string
+		mode  search.Mode
+		want  []int64
+	}{
+		{[]string{"cat", "dog"}, search.ModeAny, []int64{2, 3}},
+		{[]string{"cat", "next"}, search.ModeAll, []int64{2, 5}},
+		{[]string{"cat", "bird"}, search.ModeAll, nil},
+		{[]string{"cat", "dog"}, search.ModeNear, []int64{2, 3}},
+		{[]string{"cat", "pet"}, search.ModeNear, []int64{2, 4}},
+		{[]string{"thank", "pet"}, search.ModeNear, nil},
+		{[]string{"categ"}, search.ModeAny, nil},
+	}
+
+	for _, c := range cases {
+		got, err := search.Words(vid, c.words, c.mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Words(%q, %s) = %v, want %v", c.words, c.mode, got, c.want)
+		}
+	}
+}
@@ -7,6 +7,10 @@ import (
 	"github.com/reiver/go-porterstemmer"
 )
 
+// Version is increased whenever the output of StemLine changes,
+// videos stemmed with an older version have to be reindexed before they can be searched reliably.
+const Version = 1
+
 var builders = sync.Pool{
 	New: func() any {
 		return &strings.Builder{}
@@ -48,3 +52,8 @@ func StemLine(value string) string {
 func trimPuntuation(r rune) bool {
 	return r == ',' || r == '.' || r == '!' || r == '?' || r == '"'
 }
+
+// StemText is StemLine for text that can span multiple lines, any whitespace separates words.
+func StemText(value string) string {
+	return StemLine(strings.Join(strings.Fields(value), " "))
+}
@@ -5,6 +5,38 @@ type FailureType string
 const (
 	FailureTypeNoCaptions FailureType = "no_captions" // No captions available from yt itself, data is the video ID.
 	FailureTypePageQuota  FailureType = "page_quota"  // Quota exceeded while fetching video pages, data is the page token that failed.
+
+	// An indexed video is transcribed again, with the current whisper settings of its channel, data is the video ID.
+	FailureTypeRetranscribe FailureType = "retranscribe"
+
+	// The TubeAuto captions of an indexed video are transcribed by whisper,
+	// and replaced if the result is better, see quality.Better, data is the video ID.
+	FailureTypeUpgrade FailureType = "upgrade"
+
+	// A podcast episode is transcribed by whisper, data is the video ID of the episode, see SourcePodcast.
+	FailureTypeEpisode FailureType = "episode"
+
+	// A file of a media library is transcribed by whisper, data is the video ID of the file, see SourceDirectory.
+	FailureTypeFile FailureType = "file"
+)
+
+// FailureTypes are the known failure types.
+var FailureTypes = []FailureType{
+	FailureTypeNoCaptions,
+	FailureTypePageQuota,
+	FailureTypeRetranscribe,
+	FailureTypeUpgrade,
+	FailureTypeEpisode,
+	FailureTypeFile,
+}
+
+// Source is where the videos of a channel come from.
+type Source string
+
+const (
+	SourceYouTube   Source = "youtube"   // A YouTube channel, retrieved using the YouTube API.
+	SourcePodcast   Source = "podcast"   // A podcast RSS feed, see Channel.FeedUrl, its episodes are stored as videos.
+	SourceDirectory Source = "directory" // A local media library, see Channel.Directory and MediaFile, its files are stored as videos.
 )
 
 type TranscriptType string
@@ -12,5 +44,49 @@ type TranscriptType string
 const (
 	TubeAuto    TranscriptType = "tube_auto"    // Auto generated YouTube.
 	TubeManual  TranscriptType = "tube_manual"  // Manually added YouTube (creator or community).
-	WhisperBase TranscriptType = "whisper_base" // OpenAI Whisper base model.
+	WhisperBase TranscriptType = "whisper_base" // OpenAI Whisper base model, before the backend and model were recorded.
+	Community   TranscriptType = "community"    // Submitted by a contributor, see Submission.
+
+	// Transcripts of the failures pipeline are of type "<backend>:<model>", see transcribe.Transcriber.
+)
+
+type CaptionSource string
+
+const (
+	CaptionSourceBest    CaptionSource = "best"    // Manual captions, falling back to automatic captions.
+	CaptionSourceManual  CaptionSource = "manual"  // Only manual captions, other videos go to whisper.
+	CaptionSourceAuto    CaptionSource = "auto"    // Automatic captions, falling back to manual captions.
+	CaptionSourceWhisper CaptionSource = "whisper" // Always transcribe using whisper.
+)
+
+// UpgradeCaptions is when the TubeAuto captions of a channel are upgraded, see FailureTypeUpgrade.
+type UpgradeCaptions string
+
+const (
+	UpgradeCaptionsNever      UpgradeCaptions = "never"       // Keep the automatic captions.
+	UpgradeCaptionsLowQuality UpgradeCaptions = "low_quality" // Upgrade captions that quality.Measure considers low quality.
+	UpgradeCaptionsAlways     UpgradeCaptions = "always"      // Upgrade all automatic captions.
+)
+
+type TranscriptVersionStatus string
+
+const (
+	TranscriptVersionReplaced TranscriptVersionStatus = "replaced" // The transcript of the video before it was replaced.
+	TranscriptVersionRejected TranscriptVersionStatus = "rejected" // A transcript that was not better than the transcript of the video.
+)
+
+type SubmissionStatus string
+
+const (
+	SubmissionPending  SubmissionStatus = "pending"  // Waiting for moderation.
+	SubmissionApproved SubmissionStatus = "approved" // Indexed as the Community transcript of the video.
+	SubmissionRejected SubmissionStatus = "rejected" // Not indexed, the reason is recorded.
+)
+
+type SubmissionFormat string
+
+const (
+	SubmissionSRT  SubmissionFormat = "srt"  // SubRip subtitles.
+	SubmissionVTT  SubmissionFormat = "vtt"  // WebVTT subtitles.
+	SubmissionText SubmissionFormat = "text" // Lines starting with a timestamp, like "1:23 text" or "[01:02:03] text".
 )
@@ -2,8 +2,17 @@ package store
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -11,12 +20,183 @@ func (t *Transcript) StartDuration() time.Duration {
 	return time.Duration(t.Start) * time.Second
 }
 
+// URL returns where the video is played, from the given time if it is not zero.
+// The audio of podcast episodes is linked with a media fragment, like "episode.mp3#t=90",
+// local files with the path of the built-in player, like "/play/file-abc?t=90", see PlayerPath.
+func (v Video) URL(at time.Duration) string {
+	seconds := int(at.Seconds())
+	switch {
+	case strings.HasPrefix(v.AudioUrl, "/") && seconds > 0:
+		return fmt.Sprintf("%s?t=%d", v.AudioUrl, seconds)
+	case v.AudioUrl != "" && seconds > 0:
+		return fmt.Sprintf("%s#t=%d", v.AudioUrl, seconds)
+	case v.AudioUrl != "":
+		return v.AudioUrl
+	case seconds > 0:
+		return fmt.Sprintf("https://youtu.be/%s?t=%d", v.ID, seconds)
+	default:
+		return "https://youtu.be/" + v.ID
+	}
+}
+
+// PlayerPath is where the built-in player of the web interface plays the local file of the video,
+// it is the AudioUrl of the videos of SourceDirectory channels, their files have no page to link to.
+func PlayerPath(videoID string) string {
+	return "/play/" + videoID
+}
+
+// Word is a word of a transcript line, TranscriptDetail.Words is a JSON array of them.
+type Word struct {
+	Start int32  `json:"start"` // In milliseconds from the start of the video.
+	End   int32  `json:"end"`   // In milliseconds from the start of the video.
+	Text  string `json:"text"`
+}
+
+func (d *TranscriptDetail) ParseWords() ([]Word, error) {
+	var words []Word
+	if err := json.Unmarshal(d.Words, &words); err != nil {
+		return nil, fmt.Errorf("unmarshalling words of transcript %d: %w", d.TranscriptID, err)
+	}
+
+	return words, nil
+}
+
+// VersionLine is a line of a transcript, TranscriptVersion.Lines is a JSON array of them.
+type VersionLine struct {
+	Start int32  `json:"start"` // In seconds from the start of the video.
+	Text  string `json:"text"`
+}
+
+func (v *TranscriptVersion) ParseLines() ([]VersionLine, error) {
+	var lines []VersionLine
+	if err := json.Unmarshal(v.Lines, &lines); err != nil {
+		return nil, fmt.Errorf("unmarshalling lines of transcript version %d: %w", v.ID, err)
+	}
+
+	return lines, nil
+}
+
+func (s *Submission) ParseLines() ([]VersionLine, error) {
+	var lines []VersionLine
+	if err := json.Unmarshal(s.Lines, &lines); err != nil {
+		return nil, fmt.Errorf("unmarshalling lines of submission %d: %w", s.ID, err)
+	}
+
+	return lines, nil
+}
+
+// State describes whether the failure is dead, claimed by a worker, due, or waiting for its next attempt.
+func (f *Failure) State() string {
+	switch {
+	case f.Dead:
+		return "dead"
+	case f.ClaimedBy != "" && f.NextAttemptAt.After(time.Now()):
+		return "claimed by " + f.ClaimedBy
+	case f.NextAttemptAt.After(time.Now()):
+		return "retry in " + time.Until(f.NextAttemptAt).Round(time.Minute).String()
+	default:
+		return "due"
+	}
+}
+
+// NewContributorToken returns a random token for a contributor to sign in with, and its hash to store.
+func NewContributorToken() (string, string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("generating token: %w", err)
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, HashContributorToken(token), nil
+}
+
+// HashContributorToken returns the hash of the token, as stored in Contributor.TokenHash.
+func HashContributorToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// DefaultChannelSettings returns the settings of a channel that has none stored.
+func DefaultChannelSettings(channelID string) ChannelSetting {
+	return ChannelSetting{
+		ChannelID:        channelID,
+		IncludeShorts:    true,
+		IncludeLive:      true,
+		IncludePremieres: true,
+		CaptionSource:    string(CaptionSourceBest),
+		WhisperFallback:  true,
+		UpgradeCaptions:  string(UpgradeCaptionsNever),
+	}
+}
+
+// ChannelSettingsOrDefault retrieves the settings of the channel,
+// returning DefaultChannelSettings if it has none stored.
+func ChannelSettingsOrDefault(ctx context.Context, q Querier, channelID string) (ChannelSetting, error) {
+	settings, err := q.ChannelSettings(ctx, channelID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DefaultChannelSettings(channelID), nil
+	}
+
+	return settings, err
+}
+
+// WordMatch is how VideosOfChannelMatching matches the words.
+type WordMatch int
+
+const (
+	WordsInOrder WordMatch = iota // All the words, in order.
+	WordsAll                      // All the words, in any order.
+	WordsAny                      // Any of the words.
+)
+
 // VideosOfChannelWithWords is an optimized query to retrieve videos that
 // might be a match of a query, words must be stemmed.
 func (q *Queries) VideosOfChannelWithWords(
 	ctx context.Context,
 	channelID string,
 	words []string,
+) ([]Video, error) {
+	return q.videosOfChannelWithWordsIn(ctx, channelID, words, WordsInOrder, "searchable_transcript")
+}
+
+// VideosOfChannelWithWordsAnywhere is VideosOfChannelWithWords,
+// but also matches videos that have the words in their title or description.
+func (q *Queries) VideosOfChannelWithWordsAnywhere(
+	ctx context.Context,
+	channelID string,
+	words []string,
+) ([]Video, error) {
+	return q.VideosOfChannelMatching(ctx, channelID, words, WordsInOrder, true)
+}
+
+// VideosOfChannelMatching is VideosOfChannelWithWords, matching the words as given by match,
+// and also matching the title and description of videos when anywhere is set.
+//
+// Words are matched as substrings, so the videos are optimistic matches,
+// with anywhere, WordsAll also matches videos that have some of the words in the metadata and some in the transcript.
+func (q *Queries) VideosOfChannelMatching(
+	ctx context.Context,
+	channelID string,
+	words []string,
+	match WordMatch,
+	anywhere bool,
+) ([]Video, error) {
+	columns := []string{"searchable_transcript"}
+	if anywhere {
+		columns = append(columns, "searchable_title", "searchable_description")
+	}
+
+	return q.videosOfChannelWithWordsIn(ctx, channelID, words, match, columns...)
+}
+
+// videosOfChannelWithWordsIn retrieves the videos of the channel that have the words,
+// as given by match, in at least one of the given columns.
+func (q *Queries) videosOfChannelWithWordsIn(
+	ctx context.Context,
+	channelID string,
+	words []string,
+	match WordMatch,
+	columns ...string,
 ) ([]Video, error) {
 	if len(words) == 0 {
 		return nil, nil
@@ -30,12 +210,45 @@ func (q *Queries) VideosOfChannelWithWords(
 	ifs := make([]interface{}, len(words)+1)
 	ifs[0] = channelID
 
-	query := "SELECT * FROM videos WHERE channel_id = $1 AND searchable_transcript LIKE '%' "
-	for i, word := range words {
-		query += "|| $" + strconv.Itoa(i+2) + " || '%' "
-		ifs[i+1] = word
+	var where string
+	switch match {
+	case WordsInOrder:
+		pattern := "'%' "
+		for i, word := range words {
+			pattern += "|| $" + strconv.Itoa(i+2) + " || '%' "
+			ifs[i+1] = word
+		}
+
+		conds := make([]string, len(columns))
+		for i, column := range columns {
+			conds[i] = column + " LIKE " + pattern
+		}
+
+		where = strings.Join(conds, "OR ")
+	case WordsAll, WordsAny:
+		wordConds := make([]string, len(words))
+		for i, word := range words {
+			ifs[i+1] = word
+
+			conds := make([]string, len(columns))
+			for j, column := range columns {
+				conds[j] = column + " LIKE '%' || $" + strconv.Itoa(i+2) + " || '%'"
+			}
+
+			wordConds[i] = "(" + strings.Join(conds, " OR ") + ")"
+		}
+
+		join := " AND "
+		if match == WordsAny {
+			join = " OR "
+		}
+
+		where = strings.Join(wordConds, join)
+	default:
+		return nil, fmt.Errorf("unknown word match %d", match)
 	}
-	query += ";"
+
+	query := "SELECT * FROM videos WHERE channel_id = $1 AND (" + where + ");"
 
 	rows, err := q.db.QueryContext(ctx, query, ifs...)
 	if err != nil {
@@ -56,6 +269,11 @@ func (q *Queries) VideosOfChannelWithWords(
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.TranscriptType,
+			&i.SearchableTitle,
+			&i.SearchableDescription,
+			&i.StemVersion,
+			&i.AudioUrl,
+			&i.Segmented,
 		); err != nil {
 			return nil, err
 		}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// FailuresChannel is notified with the type of a failure as payload when it becomes due,
+// because it was created or retried.
+const FailuresChannel = "failures"
+
+// Listen listens for notifications on the channel of the Postgres database at dsn,
+// sending their payloads on the returned channel until ctx is done.
+//
+// Notifications are dropped when the previous one has not been received yet,
+// so a receiver should treat a payload as "something changed", not as a single event.
+// After reconnecting, an empty payload is sent, because notifications may have been missed.
+func Listen(ctx context.Context, dsn string, channel string) (<-chan string, error) {
+	listener := pq.NewListener(dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("[WARN]: listening on %q: %v", channel, err)
+		}
+	})
+
+	if err := listener.Listen(channel); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("listening on %q: %w", channel, err)
+	}
+
+	c := make(chan string, 1)
+	go func() {
+		defer listener.Close()
+
+		ping := time.NewTicker(90 * time.Second)
+		defer ping.Stop()
+
+		for {
+			var payload string
+			select {
+			case <-ctx.Done():
+				return
+			case <-ping.C:
+				// Checks the connection, a broken one is reconnected in the background.
+				go listener.Ping()
+				continue
+			case n := <-listener.Notify:
+				if n != nil {
+					payload = n.Extra
+				}
+			}
+
+			select {
+			case c <- payload:
+			default:
+			}
+		}
+	}()
+
+	return c, nil
+}
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE videos
+ADD COLUMN searchable_title TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE videos
+ADD COLUMN searchable_description TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE videos DROP COLUMN searchable_description;
+
+ALTER TABLE videos DROP COLUMN searchable_title;
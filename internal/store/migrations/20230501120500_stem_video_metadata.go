@@ -0,0 +1,55 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/laytan/youtupedia/internal/stem"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigration(upStemVideoMetadata, downStemVideoMetadata)
+}
+
+func upStemVideoMetadata(tx *sql.Tx) error {
+	rows, err := tx.Query("SELECT id, title, description FROM videos;")
+	if err != nil {
+		return fmt.Errorf("retrieving videos: %w", err)
+	}
+	defer rows.Close()
+
+	type video struct {
+		id, title, description string
+	}
+
+	// Read all rows first, the connection can't execute updates while rows are still being read.
+	var videos []video
+	for rows.Next() {
+		var v video
+		if err := rows.Scan(&v.id, &v.title, &v.description); err != nil {
+			return fmt.Errorf("scanning video row: %w", err)
+		}
+		videos = append(videos, v)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterating video rows: %w", err)
+	}
+
+	for _, v := range videos {
+		if _, err := tx.Exec(
+			"UPDATE videos SET searchable_title = $1, searchable_description = $2 WHERE id = $3;",
+			stem.StemText(v.title),
+			stem.StemText(v.description),
+			v.id,
+		); err != nil {
+			return fmt.Errorf("updating video %q: %w", v.id, err)
+		}
+	}
+
+	return nil
+}
+
+func downStemVideoMetadata(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,12 @@
+-- +goose Up
+
+-- Existing rows get version 0, which is older than any stem.Version, so they are rebuilt.
+ALTER TABLE videos
+ADD COLUMN stem_version INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS videos_stem_version ON videos(stem_version);
+
+-- +goose Down
+DROP INDEX IF EXISTS videos_stem_version;
+
+ALTER TABLE videos DROP COLUMN stem_version;
@@ -0,0 +1,19 @@
+-- +goose Up
+
+-- Channels without a row use the defaults, see store.DefaultChannelSettings.
+CREATE TABLE IF NOT EXISTS channel_settings (
+    channel_id        VARCHAR(255) NOT NULL PRIMARY KEY REFERENCES channels ON DELETE CASCADE ON UPDATE CASCADE,
+    include_shorts    BOOLEAN NOT NULL DEFAULT TRUE,
+    include_live      BOOLEAN NOT NULL DEFAULT TRUE,
+    include_premieres BOOLEAN NOT NULL DEFAULT TRUE,
+    min_duration      INTEGER NOT NULL DEFAULT 0, -- In seconds.
+    title_blocklist   TEXT NOT NULL DEFAULT '', -- Regular expression, videos with a matching title are skipped.
+    caption_source    VARCHAR(25) NOT NULL DEFAULT 'best',
+    whisper_fallback  BOOLEAN NOT NULL DEFAULT TRUE,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS channel_settings;
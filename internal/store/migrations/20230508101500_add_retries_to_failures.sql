@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE failures ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE failures ADD COLUMN last_error TEXT NOT NULL DEFAULT ''; -- Why the last attempt failed, or why the failure is dead.
+ALTER TABLE failures ADD COLUMN next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE failures ADD COLUMN dead BOOLEAN NOT NULL DEFAULT FALSE; -- Dead failures are not attempted again.
+
+CREATE INDEX failures_next_attempt_at ON failures (next_attempt_at) WHERE NOT dead;
+
+-- +goose Down
+DROP INDEX IF EXISTS failures_next_attempt_at;
+
+ALTER TABLE failures DROP COLUMN dead;
+ALTER TABLE failures DROP COLUMN next_attempt_at;
+ALTER TABLE failures DROP COLUMN last_error;
+ALTER TABLE failures DROP COLUMN attempts;
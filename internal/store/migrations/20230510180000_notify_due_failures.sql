@@ -0,0 +1,23 @@
+-- +goose Up
+
+-- Notifies the 'failures' channel, with the type as payload, when a failure becomes due.
+-- Workers in watch mode listen on it, see store.Listen.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_failure_due() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('failures', NEW.type);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER failures_notify_due
+AFTER INSERT OR UPDATE OF next_attempt_at, dead ON failures
+FOR EACH ROW
+WHEN (NOT NEW.dead AND NEW.next_attempt_at <= CURRENT_TIMESTAMP)
+EXECUTE FUNCTION notify_failure_due();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS failures_notify_due ON failures;
+
+DROP FUNCTION IF EXISTS notify_failure_due;
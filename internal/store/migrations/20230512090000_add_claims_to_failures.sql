@@ -0,0 +1,8 @@
+-- +goose Up
+
+-- A worker claims a failure by setting claimed_by and pushing next_attempt_at to the end of its lease,
+-- when the worker crashes, the lease expires and the failure is due again for any worker.
+ALTER TABLE failures ADD COLUMN claimed_by VARCHAR(255) NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE failures DROP COLUMN claimed_by;
@@ -0,0 +1,9 @@
+-- +goose Up
+
+-- Transcript types of whisper backends include the model, for example 'whisper_cpp:medium.en'.
+ALTER TABLE videos
+ALTER COLUMN transcript_type TYPE VARCHAR(100);
+
+-- +goose Down
+ALTER TABLE videos
+ALTER COLUMN transcript_type TYPE VARCHAR(25);
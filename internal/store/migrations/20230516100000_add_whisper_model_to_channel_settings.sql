@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE channel_settings ADD COLUMN whisper_model VARCHAR(100) NOT NULL DEFAULT ''; -- Empty for the model of the transcriber.
+ALTER TABLE channel_settings ADD COLUMN whisper_language VARCHAR(10) NOT NULL DEFAULT ''; -- Language hint, empty for the default of the transcriber.
+
+-- +goose Down
+ALTER TABLE channel_settings DROP COLUMN whisper_language;
+ALTER TABLE channel_settings DROP COLUMN whisper_model;
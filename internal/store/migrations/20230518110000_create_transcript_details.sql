@@ -0,0 +1,11 @@
+-- +goose Up
+
+-- Details of transcript lines that are transcribed by whisper, kept out of transcripts so it stays slim.
+CREATE TABLE IF NOT EXISTS transcript_details (
+    transcript_id BIGINT NOT NULL PRIMARY KEY REFERENCES transcripts ON DELETE CASCADE,
+    confidence    REAL NOT NULL, -- Average probability of the tokens of the line, from 0 to 1.
+    words         JSONB NOT NULL DEFAULT '[]' -- See store.Word.
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS transcript_details;
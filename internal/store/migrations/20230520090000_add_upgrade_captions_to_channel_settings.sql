@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE channel_settings ADD COLUMN upgrade_captions VARCHAR(25) NOT NULL DEFAULT 'never'; -- See store.UpgradeCaptions.
+
+-- +goose Down
+ALTER TABLE channel_settings DROP COLUMN upgrade_captions;
@@ -0,0 +1,19 @@
+-- +goose Up
+
+-- Transcripts that were replaced, or candidates that were rejected, kept for audit.
+CREATE TABLE IF NOT EXISTS transcript_versions (
+    id              BIGSERIAL PRIMARY KEY,
+    video_id        VARCHAR(255) NOT NULL REFERENCES videos ON DELETE CASCADE ON UPDATE CASCADE,
+    transcript_type VARCHAR(100) NOT NULL,
+    status          VARCHAR(25) NOT NULL, -- See store.TranscriptVersionStatus.
+    reason          TEXT NOT NULL,
+    lines           JSONB NOT NULL DEFAULT '[]', -- See store.VersionLine.
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS transcript_versions_video_id ON transcript_versions(video_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS transcript_versions_video_id;
+DROP TABLE IF EXISTS transcript_versions;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE channel_settings ADD COLUMN priority INTEGER NOT NULL DEFAULT 0; -- Failures of channels with a higher priority are processed first.
+
+-- +goose Down
+ALTER TABLE channel_settings DROP COLUMN priority;
@@ -0,0 +1,10 @@
+-- +goose Up
+
+-- Failures with a higher priority are claimed first, see index.Priority.
+ALTER TABLE failures ADD COLUMN priority DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS failures_priority ON failures(priority DESC, id) WHERE NOT dead;
+
+-- +goose Down
+DROP INDEX IF EXISTS failures_priority;
+ALTER TABLE failures DROP COLUMN priority;
@@ -0,0 +1,35 @@
+-- +goose Up
+
+-- Contributors sign in to the web interface with a token, which is only stored hashed.
+CREATE TABLE IF NOT EXISTS contributors (
+    id         BIGSERIAL PRIMARY KEY,
+    name       VARCHAR(255) NOT NULL UNIQUE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE, -- Hex encoded SHA-256 of the token.
+    disabled   BOOLEAN NOT NULL DEFAULT FALSE,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+-- Transcripts submitted by contributors for videos without captions, waiting for moderation.
+CREATE TABLE IF NOT EXISTS submissions (
+    id             BIGSERIAL PRIMARY KEY,
+    video_id       VARCHAR(255) NOT NULL, -- Not a reference, the video is created when the submission is approved.
+    channel_id     VARCHAR(255) NOT NULL REFERENCES channels ON DELETE CASCADE ON UPDATE CASCADE,
+    contributor_id BIGINT NOT NULL REFERENCES contributors ON DELETE CASCADE,
+    format         VARCHAR(10) NOT NULL, -- See store.SubmissionFormat.
+    content        TEXT NOT NULL, -- As submitted.
+    lines          JSONB NOT NULL DEFAULT '[]', -- Parsed from content, see store.VersionLine.
+    status         VARCHAR(25) NOT NULL DEFAULT 'pending', -- See store.SubmissionStatus.
+    reason         TEXT NOT NULL DEFAULT '', -- Why it was rejected.
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS submissions_video_id ON submissions(video_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS submissions_video_id;
+DROP TABLE IF EXISTS submissions;
+DROP TABLE IF EXISTS contributors;
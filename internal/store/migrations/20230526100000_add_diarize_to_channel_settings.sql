@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE channel_settings ADD COLUMN diarize BOOLEAN NOT NULL DEFAULT false; -- Label speakers when transcribing with whisper, needs a tinydiarize model.
+
+-- +goose Down
+ALTER TABLE channel_settings DROP COLUMN diarize;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE transcript_details ADD COLUMN speaker VARCHAR(50) NOT NULL DEFAULT ''; -- Label of the speaker, empty if not diarized.
+
+-- +goose Down
+ALTER TABLE transcript_details DROP COLUMN speaker;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE channels ADD COLUMN source VARCHAR(25) NOT NULL DEFAULT 'youtube'; -- See store.Source.
+ALTER TABLE channels ADD COLUMN feed_url TEXT NOT NULL DEFAULT ''; -- The RSS feed of podcasts, empty for YouTube channels.
+
+-- +goose Down
+ALTER TABLE channels DROP COLUMN feed_url;
+ALTER TABLE channels DROP COLUMN source;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE videos ADD COLUMN audio_url TEXT NOT NULL DEFAULT ''; -- The audio of podcast episodes, empty for YouTube videos.
+
+-- +goose Down
+ALTER TABLE videos DROP COLUMN audio_url;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE channels ADD COLUMN directory TEXT NOT NULL DEFAULT ''; -- The media library of directory channels, empty for other channels.
+
+-- +goose Down
+ALTER TABLE channels DROP COLUMN directory;
@@ -0,0 +1,19 @@
+-- +goose Up
+
+-- The files of directory channels, by their video, which is identified by a hash of the content of the file.
+CREATE TABLE IF NOT EXISTS media_files (
+    video_id    VARCHAR(255) NOT NULL PRIMARY KEY, -- Not a reference, the video is created when the file is transcribed.
+    channel_id  VARCHAR(255) NOT NULL REFERENCES channels ON DELETE CASCADE ON UPDATE CASCADE,
+    path        TEXT NOT NULL, -- Absolute.
+    size        BIGINT NOT NULL, -- With modified_at, tells if the file changed since it was hashed.
+    modified_at TIMESTAMP NOT NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS media_files_channel_id ON media_files(channel_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS media_files_channel_id;
+DROP TABLE IF EXISTS media_files;
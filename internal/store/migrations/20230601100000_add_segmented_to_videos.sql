@@ -0,0 +1,8 @@
+-- +goose Up
+-- Existing videos are not known to be segmented, `resegment` segments them once,
+-- new videos are segmented when they are indexed.
+ALTER TABLE videos ADD COLUMN segmented BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE videos ALTER COLUMN segmented SET DEFAULT TRUE;
+
+-- +goose Down
+ALTER TABLE videos DROP COLUMN segmented;
@@ -0,0 +1,8 @@
+-- +goose Up
+
+-- The searchable transcripts stemmed by 20230423133953 are rebuilt from the stored transcripts,
+-- by `reindex`, or when the web interface is served, see index.Reindex.
+UPDATE videos SET stem_version = 0;
+
+-- +goose Down
+-- Nothing to undo, reindexing with the current stemmer is always safe.
@@ -0,0 +1,22 @@
+-- +goose Up
+-- Media files were keyed by their video, so copies of a file had one row, and the file was hashed on every scan.
+-- The scan looks them up by path, the video is kept in an index.
+DELETE FROM media_files AS stale USING media_files AS current
+WHERE stale.channel_id = current.channel_id
+AND stale.path = current.path
+AND (stale.updated_at, stale.video_id) < (current.updated_at, current.video_id);
+
+ALTER TABLE media_files DROP CONSTRAINT media_files_pkey;
+ALTER TABLE media_files ADD PRIMARY KEY (channel_id, path);
+DROP INDEX IF EXISTS media_files_channel_id;
+CREATE INDEX IF NOT EXISTS media_files_video_id ON media_files(video_id);
+
+-- +goose Down
+DELETE FROM media_files AS stale USING media_files AS current
+WHERE stale.video_id = current.video_id
+AND (stale.updated_at, stale.path) < (current.updated_at, current.path);
+
+DROP INDEX IF EXISTS media_files_video_id;
+CREATE INDEX IF NOT EXISTS media_files_channel_id ON media_files(channel_id);
+ALTER TABLE media_files DROP CONSTRAINT media_files_pkey;
+ALTER TABLE media_files ADD PRIMARY KEY (video_id);
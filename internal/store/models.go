@@ -5,6 +5,7 @@
 package store
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -16,17 +17,77 @@ type Channel struct {
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 	CustomUrl    string
+	Source       string
+	FeedUrl      string
+	Directory    string
 }
 
-type Failure struct {
+type ChannelSetting struct {
+	ChannelID        string
+	IncludeShorts    bool
+	IncludeLive      bool
+	IncludePremieres bool
+	MinDuration      int32
+	TitleBlocklist   string
+	CaptionSource    string
+	WhisperFallback  bool
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	WhisperModel     string
+	WhisperLanguage  string
+	UpgradeCaptions  string
+	Priority         int32
+	Diarize          bool
+}
+
+type Contributor struct {
 	ID        int64
-	ChannelID string
-	Data      string
-	Type      string
+	Name      string
+	TokenHash string
+	Disabled  bool
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
 
+type Failure struct {
+	ID            int64
+	ChannelID     string
+	Data          string
+	Type          string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Attempts      int32
+	LastError     string
+	NextAttemptAt time.Time
+	Dead          bool
+	ClaimedBy     string
+	Priority      float64
+}
+
+type MediaFile struct {
+	VideoID    string
+	ChannelID  string
+	Path       string
+	Size       int64
+	ModifiedAt time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type Submission struct {
+	ID            int64
+	VideoID       string
+	ChannelID     string
+	ContributorID int64
+	Format        string
+	Content       string
+	Lines         json.RawMessage
+	Status        string
+	Reason        string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
 type Transcript struct {
 	ID      int64
 	VideoID string
@@ -34,15 +95,37 @@ type Transcript struct {
 	Text    string
 }
 
+type TranscriptDetail struct {
+	TranscriptID int64
+	Confidence   float32
+	Words        json.RawMessage
+	Speaker      string
+}
+
+type TranscriptVersion struct {
+	ID             int64
+	VideoID        string
+	TranscriptType string
+	Status         string
+	Reason         string
+	Lines          json.RawMessage
+	CreatedAt      time.Time
+}
+
 type Video struct {
-	ID                   string
-	ChannelID            string
-	PublishedAt          time.Time
-	Title                string
-	Description          string
-	ThumbnailUrl         string
-	SearchableTranscript string
-	CreatedAt            time.Time
-	UpdatedAt            time.Time
-	TranscriptType       string
+	ID                    string
+	ChannelID             string
+	PublishedAt           time.Time
+	Title                 string
+	Description           string
+	ThumbnailUrl          string
+	SearchableTranscript  string
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+	TranscriptType        string
+	SearchableTitle       string
+	SearchableDescription string
+	StemVersion           int32
+	AudioUrl              string
+	Segmented             bool
 }
@@ -13,9 +13,9 @@ SELECT * FROM channels;
 
 -- name: CreateChannel :one
 INSERT INTO channels (
-    id, title, videos_list_id, thumbnail_url
+    id, title, videos_list_id, thumbnail_url, custom_url, source, feed_url, directory
 ) VALUES (
-    $1, $2,    $3,             $4
+    $1, $2,    $3,             $4,            $5,         $6,     $7,       $8
 )
 RETURNING *;
 
@@ -35,9 +35,9 @@ RETURNING id;
 
 -- name: CreateVideo :exec
 INSERT INTO videos (
-    id, channel_id, published_at, title, description, thumbnail_url, searchable_transcript, transcript_type
+    id, channel_id, published_at, title, description, thumbnail_url, searchable_transcript, transcript_type, searchable_title, searchable_description, stem_version, audio_url
 ) VALUES (
-    $1,  $2,        $3,           $4,    $5,          $6,            $7,                    $8
+    $1,  $2,        $3,           $4,    $5,          $6,            $7,                    $8,              $9,               $10,                    $11,          $12
 );
 
 -- name: VideosOfChannel :many
@@ -50,9 +50,9 @@ WHERE id = $1;
 
 -- name: CreateFailure :exec
 INSERT INTO failures (
-    channel_id, data, type
+    channel_id, data, type, last_error, dead, priority
 ) VALUES (
-    $1,         $2,   $3
+    $1,         $2,   $3,   $4,         $5,   $6
 );
 
 -- name: NoCaptionFailures :many
@@ -64,17 +64,67 @@ AND type = "no_captions";
 DELETE FROM failures
 WHERE id = $1;
 
--- name: NextFailure :one
-SELECT * FROM failures
-WHERE id > $1
-AND type = $2
-ORDER BY id ASC
-LIMIT 1;
+-- name: CompleteFailure :execrows
+DELETE FROM failures
+WHERE id = @id
+AND claimed_by = @worker_id;
+
+-- name: ClaimFailure :one
+UPDATE failures
+SET claimed_by = @worker_id,
+    next_attempt_at = CURRENT_TIMESTAMP + (@lease_seconds::integer * INTERVAL '1 second'),
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = (
+    SELECT f.id FROM failures AS f
+    WHERE f.type = ANY(@types::varchar[])
+    AND (sqlc.narg(channel_id)::varchar IS NULL OR f.channel_id = sqlc.narg(channel_id))
+    AND NOT f.dead
+    AND f.next_attempt_at <= CURRENT_TIMESTAMP
+    ORDER BY f.priority DESC, f.id ASC
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: RenewClaim :execrows
+UPDATE failures
+SET next_attempt_at = CURRENT_TIMESTAMP + (@lease_seconds::integer * INTERVAL '1 second')
+WHERE id = @id
+AND claimed_by = @worker_id;
+
+-- name: ReleaseClaim :exec
+UPDATE failures
+SET claimed_by = '',
+    next_attempt_at = CURRENT_TIMESTAMP,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = @id
+AND claimed_by = @worker_id;
 
 -- name: CountFailures :one
 SELECT COUNT(*) FROM failures
-WHERE type = $1
-AND id > $2; -- Need second arg here because type is a reserved word in go.
+WHERE type = @type
+AND NOT dead
+AND (sqlc.narg(channel_id)::varchar IS NULL OR channel_id = sqlc.narg(channel_id));
+
+-- name: FailAttempt :execrows
+UPDATE failures
+SET attempts = @attempts,
+    last_error = @last_error,
+    next_attempt_at = CURRENT_TIMESTAMP + (@backoff_seconds::integer * INTERVAL '1 second'),
+    dead = @dead,
+    claimed_by = '',
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = @id
+AND claimed_by = @worker_id;
+
+-- name: ClaimedByOther :one
+SELECT EXISTS (
+    SELECT 1 FROM failures
+    WHERE id = @id
+    AND claimed_by != ''
+    AND claimed_by != @worker_id
+    AND next_attempt_at > CURRENT_TIMESTAMP
+);
 
 -- name: Video :one
 SELECT * FROM videos
@@ -84,7 +134,326 @@ WHERE id = $1;
 SELECT * FROM transcripts
 WHERE id = ANY(@ids::bigint[]);
 
+-- name: SpeakersOfTranscripts :many
+SELECT transcript_id, speaker FROM transcript_details
+WHERE transcript_id = ANY(@ids::bigint[])
+AND speaker != '';
+
 -- name: SetSearchableTranscript :exec
 UPDATE videos
 SET searchable_transcript = $2
 WHERE id = $1;
+
+-- name: VideoIDsToResegment :many
+SELECT id FROM videos
+WHERE transcript_type = $1
+AND NOT segmented
+ORDER BY id;
+
+-- name: SetSegmented :exec
+UPDATE videos
+SET segmented = TRUE
+WHERE id = $1;
+
+-- name: VideoIDsOfChannel :many
+SELECT id FROM videos
+WHERE channel_id = $1;
+
+-- name: TranscriptsOfVideo :many
+SELECT * FROM transcripts
+WHERE video_id = $1
+ORDER BY start, id;
+
+-- name: DeleteTranscriptsOfVideo :exec
+DELETE FROM transcripts
+WHERE video_id = $1;
+
+-- name: StaleVideoIDs :many
+SELECT id FROM videos
+WHERE stem_version < @stem_version
+AND (sqlc.narg(channel_id)::varchar IS NULL OR channel_id = sqlc.narg(channel_id))
+AND (sqlc.narg(video_id)::varchar IS NULL OR id = sqlc.narg(video_id))
+AND id > @after
+ORDER BY id
+LIMIT @batch_size;
+
+-- name: SetSearchable :exec
+UPDATE videos
+SET searchable_transcript = $2,
+    searchable_title = $3,
+    searchable_description = $4,
+    stem_version = $5
+WHERE id = $1;
+
+-- name: UpdateChannel :one
+UPDATE channels
+SET title = $2,
+    videos_list_id = $3,
+    thumbnail_url = $4,
+    custom_url = $5,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteChannel :exec
+DELETE FROM channels
+WHERE id = $1;
+
+-- name: ChannelCounts :one
+SELECT
+    (SELECT COUNT(*) FROM videos WHERE channel_id = $1) AS videos,
+    (SELECT COUNT(*) FROM failures WHERE channel_id = $1) AS failures;
+
+-- name: DeleteVideo :exec
+DELETE FROM videos
+WHERE id = $1;
+
+-- name: Stats :one
+SELECT
+    (SELECT COUNT(*) FROM channels) AS channels,
+    (SELECT COUNT(*) FROM videos) AS videos,
+    (SELECT COUNT(*) FROM videos WHERE stem_version < $1) AS outdated_videos,
+    (SELECT COUNT(*) FROM transcripts) AS transcripts,
+    (SELECT COUNT(*) FROM failures) AS failures;
+
+-- name: VideoCountsByTranscriptType :many
+SELECT transcript_type, COUNT(*) AS count FROM videos
+GROUP BY transcript_type
+ORDER BY transcript_type;
+
+-- name: FailureCountsByType :many
+SELECT type, COUNT(*) AS count FROM failures
+GROUP BY type
+ORDER BY type;
+
+-- name: ChannelSettings :one
+SELECT * FROM channel_settings
+WHERE channel_id = $1;
+
+-- name: UpsertChannelSettings :one
+INSERT INTO channel_settings (
+    channel_id, include_shorts, include_live, include_premieres, min_duration, title_blocklist, caption_source, whisper_fallback, whisper_model, whisper_language, upgrade_captions, priority, diarize
+) VALUES (
+    $1,         $2,             $3,           $4,                $5,           $6,              $7,             $8,               $9,            $10,              $11,              $12,      $13
+)
+ON CONFLICT (channel_id) DO UPDATE
+SET include_shorts = EXCLUDED.include_shorts,
+    include_live = EXCLUDED.include_live,
+    include_premieres = EXCLUDED.include_premieres,
+    min_duration = EXCLUDED.min_duration,
+    title_blocklist = EXCLUDED.title_blocklist,
+    caption_source = EXCLUDED.caption_source,
+    whisper_fallback = EXCLUDED.whisper_fallback,
+    whisper_model = EXCLUDED.whisper_model,
+    whisper_language = EXCLUDED.whisper_language,
+    upgrade_captions = EXCLUDED.upgrade_captions,
+    priority = EXCLUDED.priority,
+    diarize = EXCLUDED.diarize,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: Failure :one
+SELECT * FROM failures
+WHERE id = $1;
+
+-- name: ListFailures :many
+SELECT * FROM failures
+WHERE (sqlc.narg(channel_id)::varchar IS NULL OR channel_id = sqlc.narg(channel_id))
+AND (sqlc.narg(type)::varchar IS NULL OR type = sqlc.narg(type))
+AND (sqlc.narg(created_before)::timestamp IS NULL OR created_at < sqlc.narg(created_before))
+AND (sqlc.narg(dead)::boolean IS NULL OR dead = sqlc.narg(dead))
+ORDER BY dead, priority DESC, id
+LIMIT @max_results;
+
+-- name: RetryFailure :exec
+UPDATE failures
+SET attempts = 0,
+    next_attempt_at = CURRENT_TIMESTAMP,
+    dead = FALSE,
+    claimed_by = '',
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: FailureCountsByChannel :many
+SELECT channels.id AS channel_id, channels.title, failures.type,
+    COUNT(*) AS total,
+    COUNT(*) FILTER (WHERE failures.dead) AS dead,
+    COUNT(*) FILTER (WHERE NOT failures.dead AND failures.next_attempt_at <= CURRENT_TIMESTAMP) AS due
+FROM failures
+JOIN channels ON channels.id = failures.channel_id
+GROUP BY channels.id, channels.title, failures.type
+ORDER BY channels.title, failures.type;
+
+-- name: SetTranscriptType :exec
+UPDATE videos
+SET transcript_type = $2
+WHERE id = $1;
+
+-- name: QueueRetranscribes :execrows
+INSERT INTO failures (channel_id, data, type, priority)
+SELECT videos.channel_id, videos.id, @failure_type::varchar, @priority::double precision FROM videos
+WHERE videos.channel_id = @channel_id
+AND (
+    (sqlc.narg(transcript_type)::varchar IS NULL AND videos.transcript_type NOT IN ('tube_auto', 'tube_manual'))
+    OR videos.transcript_type = sqlc.narg(transcript_type)
+)
+AND (sqlc.narg(video_id)::varchar IS NULL OR videos.id = sqlc.narg(video_id))
+AND NOT EXISTS (
+    SELECT 1 FROM failures
+    WHERE failures.data = videos.id
+    AND failures.type = @failure_type::varchar
+    AND NOT failures.dead
+)
+AND NOT EXISTS (
+    SELECT 1 FROM transcript_versions
+    WHERE transcript_versions.video_id = videos.id
+    AND transcript_versions.status = 'rejected'
+    AND videos.transcript_type = 'tube_auto'
+);
+
+-- name: CreateTranscriptDetails :exec
+INSERT INTO transcript_details (
+    transcript_id, confidence, words, speaker
+) VALUES (
+    $1,            $2,         $3,    $4
+);
+
+-- name: TranscriptDetailsOfVideo :many
+SELECT transcript_details.* FROM transcript_details
+JOIN transcripts ON transcripts.id = transcript_details.transcript_id
+WHERE transcripts.video_id = $1;
+
+-- name: CreateTranscriptVersion :exec
+INSERT INTO transcript_versions (
+    video_id, transcript_type, status, reason, lines
+) VALUES (
+    $1,       $2,              $3,     $4,     $5
+);
+
+-- name: TranscriptVersionsOfVideo :many
+SELECT * FROM transcript_versions
+WHERE video_id = $1
+ORDER BY id;
+
+-- name: BumpFailures :execrows
+UPDATE failures
+SET priority = failures.priority + bump.amount,
+    updated_at = CURRENT_TIMESTAMP
+FROM (
+    SELECT MAX(f.priority) - MIN(f.priority) FILTER (
+        WHERE (sqlc.narg(channel_id)::varchar IS NULL OR f.channel_id = sqlc.narg(channel_id))
+        AND (sqlc.narg(data)::text IS NULL OR f.data = sqlc.narg(data))
+    ) + 1 AS amount
+    FROM failures AS f
+    WHERE NOT f.dead
+) AS bump
+WHERE (sqlc.narg(channel_id)::varchar IS NULL OR failures.channel_id = sqlc.narg(channel_id))
+AND (sqlc.narg(data)::text IS NULL OR failures.data = sqlc.narg(data))
+AND NOT failures.dead;
+
+-- name: ShiftFailurePriorities :exec
+UPDATE failures
+SET priority = priority + @amount::double precision
+WHERE channel_id = @channel_id
+AND NOT dead;
+
+-- name: CreateContributor :one
+INSERT INTO contributors (
+    name, token_hash
+) VALUES (
+    $1,   $2
+)
+RETURNING *;
+
+-- name: ContributorByTokenHash :one
+SELECT * FROM contributors
+WHERE token_hash = $1
+AND NOT disabled;
+
+-- name: Contributors :many
+SELECT * FROM contributors
+ORDER BY id;
+
+-- name: DisableContributor :execrows
+UPDATE contributors
+SET disabled = TRUE,
+    updated_at = CURRENT_TIMESTAMP
+WHERE name = $1;
+
+-- name: CreateSubmission :one
+INSERT INTO submissions (
+    video_id, channel_id, contributor_id, format, content, lines
+) VALUES (
+    $1,       $2,         $3,             $4,     $5,      $6
+)
+RETURNING *;
+
+-- name: Submission :one
+SELECT * FROM submissions
+WHERE id = $1;
+
+-- name: ListSubmissions :many
+SELECT submissions.*, contributors.name AS contributor FROM submissions
+JOIN contributors ON contributors.id = submissions.contributor_id
+WHERE (sqlc.narg(status)::varchar IS NULL OR submissions.status = sqlc.narg(status))
+ORDER BY submissions.id
+LIMIT @max_results;
+
+-- name: SetSubmissionStatus :exec
+UPDATE submissions
+SET status = $2,
+    reason = $3,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: NoCaptionsFailureOfVideo :one
+SELECT * FROM failures
+WHERE data = $1
+AND type = 'no_captions'
+ORDER BY id
+LIMIT 1;
+
+-- name: LockNoCaptionsFailuresOfVideo :many
+SELECT claimed_by, next_attempt_at > CURRENT_TIMESTAMP AS leased FROM failures
+WHERE data = @video_id
+AND type = 'no_captions'
+FOR UPDATE;
+
+-- name: FailureDataOfChannel :many
+SELECT data FROM failures
+WHERE channel_id = $1
+AND type = $2;
+
+-- name: DeleteFailuresOfVideo :exec
+DELETE FROM failures
+WHERE data = @video_id
+AND type = @type;
+
+-- name: UpsertMediaFile :exec
+INSERT INTO media_files (
+    video_id, channel_id, path, size, modified_at
+) VALUES (
+    $1,       $2,         $3,   $4,   $5
+)
+ON CONFLICT (channel_id, path) DO UPDATE
+SET video_id = EXCLUDED.video_id,
+    size = EXCLUDED.size,
+    modified_at = EXCLUDED.modified_at,
+    updated_at = CURRENT_TIMESTAMP;
+
+-- name: MediaFile :one
+SELECT * FROM media_files
+WHERE channel_id = @channel_id
+AND video_id = @video_id
+ORDER BY updated_at DESC
+LIMIT 1;
+
+-- name: MediaFilesOfChannel :many
+SELECT * FROM media_files
+WHERE channel_id = $1
+ORDER BY path;
+
+-- name: DeleteMediaFile :exec
+DELETE FROM media_files
+WHERE channel_id = @channel_id
+AND path = @path;
@@ -7,13 +7,45 @@ package store
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/lib/pq"
 )
 
+const bumpFailures = `-- name: BumpFailures :execrows
+UPDATE failures
+SET priority = failures.priority + bump.amount,
+    updated_at = CURRENT_TIMESTAMP
+FROM (
+    SELECT MAX(f.priority) - MIN(f.priority) FILTER (
+        WHERE ($1::varchar IS NULL OR f.channel_id = $1)
+        AND ($2::text IS NULL OR f.data = $2)
+    ) + 1 AS amount
+    FROM failures AS f
+    WHERE NOT f.dead
+) AS bump
+WHERE ($1::varchar IS NULL OR failures.channel_id = $1)
+AND ($2::text IS NULL OR failures.data = $2)
+AND NOT failures.dead
+`
+
+type BumpFailuresParams struct {
+	ChannelID sql.NullString
+	Data      sql.NullString
+}
+
+func (q *Queries) BumpFailures(ctx context.Context, arg BumpFailuresParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, bumpFailures, arg.ChannelID, arg.Data)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const channel = `-- name: Channel :one
-SELECT id, title, videos_list_id, thumbnail_url, created_at, updated_at, custom_url FROM channels
+SELECT id, title, videos_list_id, thumbnail_url, created_at, updated_at, custom_url, source, feed_url, directory FROM channels
 WHERE id = $1
 LIMIT 1
 `
@@ -29,12 +61,15 @@ func (q *Queries) Channel(ctx context.Context, id string) (Channel, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.CustomUrl,
+		&i.Source,
+		&i.FeedUrl,
+		&i.Directory,
 	)
 	return i, err
 }
 
 const channelByUrl = `-- name: ChannelByUrl :one
-SELECT id, title, videos_list_id, thumbnail_url, created_at, updated_at, custom_url FROM channels
+SELECT id, title, videos_list_id, thumbnail_url, created_at, updated_at, custom_url, source, feed_url, directory FROM channels
 WHERE custom_url = $1
 LIMIT 1
 `
@@ -50,12 +85,61 @@ func (q *Queries) ChannelByUrl(ctx context.Context, customUrl string) (Channel,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.CustomUrl,
+		&i.Source,
+		&i.FeedUrl,
+		&i.Directory,
+	)
+	return i, err
+}
+
+const channelCounts = `-- name: ChannelCounts :one
+SELECT
+    (SELECT COUNT(*) FROM videos WHERE channel_id = $1) AS videos,
+    (SELECT COUNT(*) FROM failures WHERE channel_id = $1) AS failures
+`
+
+type ChannelCountsRow struct {
+	Videos   int64
+	Failures int64
+}
+
+func (q *Queries) ChannelCounts(ctx context.Context, channelID string) (ChannelCountsRow, error) {
+	row := q.db.QueryRowContext(ctx, channelCounts, channelID)
+	var i ChannelCountsRow
+	err := row.Scan(&i.Videos, &i.Failures)
+	return i, err
+}
+
+const channelSettings = `-- name: ChannelSettings :one
+SELECT channel_id, include_shorts, include_live, include_premieres, min_duration, title_blocklist, caption_source, whisper_fallback, created_at, updated_at, whisper_model, whisper_language, upgrade_captions, priority, diarize FROM channel_settings
+WHERE channel_id = $1
+`
+
+func (q *Queries) ChannelSettings(ctx context.Context, channelID string) (ChannelSetting, error) {
+	row := q.db.QueryRowContext(ctx, channelSettings, channelID)
+	var i ChannelSetting
+	err := row.Scan(
+		&i.ChannelID,
+		&i.IncludeShorts,
+		&i.IncludeLive,
+		&i.IncludePremieres,
+		&i.MinDuration,
+		&i.TitleBlocklist,
+		&i.CaptionSource,
+		&i.WhisperFallback,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.WhisperModel,
+		&i.WhisperLanguage,
+		&i.UpgradeCaptions,
+		&i.Priority,
+		&i.Diarize,
 	)
 	return i, err
 }
 
 const channels = `-- name: Channels :many
-SELECT id, title, videos_list_id, thumbnail_url, created_at, updated_at, custom_url FROM channels
+SELECT id, title, videos_list_id, thumbnail_url, created_at, updated_at, custom_url, source, feed_url, directory FROM channels
 `
 
 func (q *Queries) Channels(ctx context.Context) ([]Channel, error) {
@@ -75,6 +159,155 @@ func (q *Queries) Channels(ctx context.Context) ([]Channel, error) {
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.CustomUrl,
+			&i.Source,
+			&i.FeedUrl,
+			&i.Directory,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const claimFailure = `-- name: ClaimFailure :one
+UPDATE failures
+SET claimed_by = $1,
+    next_attempt_at = CURRENT_TIMESTAMP + ($2::integer * INTERVAL '1 second'),
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = (
+    SELECT f.id FROM failures AS f
+    WHERE f.type = ANY($3::varchar[])
+    AND ($4::varchar IS NULL OR f.channel_id = $4)
+    AND NOT f.dead
+    AND f.next_attempt_at <= CURRENT_TIMESTAMP
+    ORDER BY f.priority DESC, f.id ASC
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, channel_id, data, type, created_at, updated_at, attempts, last_error, next_attempt_at, dead, claimed_by, priority
+`
+
+type ClaimFailureParams struct {
+	WorkerID     string
+	LeaseSeconds int32
+	Types        []string
+	ChannelID    sql.NullString
+}
+
+func (q *Queries) ClaimFailure(ctx context.Context, arg ClaimFailureParams) (Failure, error) {
+	row := q.db.QueryRowContext(ctx, claimFailure,
+		arg.WorkerID,
+		arg.LeaseSeconds,
+		pq.Array(arg.Types),
+		arg.ChannelID,
+	)
+	var i Failure
+	err := row.Scan(
+		&i.ID,
+		&i.ChannelID,
+		&i.Data,
+		&i.Type,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Attempts,
+		&i.LastError,
+		&i.NextAttemptAt,
+		&i.Dead,
+		&i.ClaimedBy,
+		&i.Priority,
+	)
+	return i, err
+}
+
+const claimedByOther = `-- name: ClaimedByOther :one
+SELECT EXISTS (
+    SELECT 1 FROM failures
+    WHERE id = $1
+    AND claimed_by != ''
+    AND claimed_by != $2
+    AND next_attempt_at > CURRENT_TIMESTAMP
+)
+`
+
+type ClaimedByOtherParams struct {
+	ID       int64
+	WorkerID string
+}
+
+func (q *Queries) ClaimedByOther(ctx context.Context, arg ClaimedByOtherParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, claimedByOther, arg.ID, arg.WorkerID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const completeFailure = `-- name: CompleteFailure :execrows
+DELETE FROM failures
+WHERE id = $1
+AND claimed_by = $2
+`
+
+type CompleteFailureParams struct {
+	ID       int64
+	WorkerID string
+}
+
+func (q *Queries) CompleteFailure(ctx context.Context, arg CompleteFailureParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, completeFailure, arg.ID, arg.WorkerID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const contributorByTokenHash = `-- name: ContributorByTokenHash :one
+SELECT id, name, token_hash, disabled, created_at, updated_at FROM contributors
+WHERE token_hash = $1
+AND NOT disabled
+`
+
+func (q *Queries) ContributorByTokenHash(ctx context.Context, tokenHash string) (Contributor, error) {
+	row := q.db.QueryRowContext(ctx, contributorByTokenHash, tokenHash)
+	var i Contributor
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.TokenHash,
+		&i.Disabled,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const contributors = `-- name: Contributors :many
+SELECT id, name, token_hash, disabled, created_at, updated_at FROM contributors
+ORDER BY id
+`
+
+func (q *Queries) Contributors(ctx context.Context) ([]Contributor, error) {
+	rows, err := q.db.QueryContext(ctx, contributors)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Contributor
+	for rows.Next() {
+		var i Contributor
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.TokenHash,
+			&i.Disabled,
+			&i.CreatedAt,
+			&i.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -92,16 +325,17 @@ func (q *Queries) Channels(ctx context.Context) ([]Channel, error) {
 const countFailures = `-- name: CountFailures :one
 SELECT COUNT(*) FROM failures
 WHERE type = $1
-AND id > $2
+AND NOT dead
+AND ($2::varchar IS NULL OR channel_id = $2)
 `
 
 type CountFailuresParams struct {
-	Type string
-	ID   int64
+	Type      string
+	ChannelID sql.NullString
 }
 
 func (q *Queries) CountFailures(ctx context.Context, arg CountFailuresParams) (int64, error) {
-	row := q.db.QueryRowContext(ctx, countFailures, arg.Type, arg.ID)
+	row := q.db.QueryRowContext(ctx, countFailures, arg.Type, arg.ChannelID)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
@@ -109,11 +343,11 @@ func (q *Queries) CountFailures(ctx context.Context, arg CountFailuresParams) (i
 
 const createChannel = `-- name: CreateChannel :one
 INSERT INTO channels (
-    id, title, videos_list_id, thumbnail_url
+    id, title, videos_list_id, thumbnail_url, custom_url, source, feed_url, directory
 ) VALUES (
-    $1, $2,    $3,             $4
+    $1, $2,    $3,             $4,            $5,         $6,     $7,       $8
 )
-RETURNING id, title, videos_list_id, thumbnail_url, created_at, updated_at, custom_url
+RETURNING id, title, videos_list_id, thumbnail_url, created_at, updated_at, custom_url, source, feed_url, directory
 `
 
 type CreateChannelParams struct {
@@ -121,6 +355,10 @@ type CreateChannelParams struct {
 	Title        string
 	VideosListID string
 	ThumbnailUrl string
+	CustomUrl    string
+	Source       string
+	FeedUrl      string
+	Directory    string
 }
 
 func (q *Queries) CreateChannel(ctx context.Context, arg CreateChannelParams) (Channel, error) {
@@ -129,6 +367,10 @@ func (q *Queries) CreateChannel(ctx context.Context, arg CreateChannelParams) (C
 		arg.Title,
 		arg.VideosListID,
 		arg.ThumbnailUrl,
+		arg.CustomUrl,
+		arg.Source,
+		arg.FeedUrl,
+		arg.Directory,
 	)
 	var i Channel
 	err := row.Scan(
@@ -139,15 +381,46 @@ func (q *Queries) CreateChannel(ctx context.Context, arg CreateChannelParams) (C
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.CustomUrl,
+		&i.Source,
+		&i.FeedUrl,
+		&i.Directory,
+	)
+	return i, err
+}
+
+const createContributor = `-- name: CreateContributor :one
+INSERT INTO contributors (
+    name, token_hash
+) VALUES (
+    $1,   $2
+)
+RETURNING id, name, token_hash, disabled, created_at, updated_at
+`
+
+type CreateContributorParams struct {
+	Name      string
+	TokenHash string
+}
+
+func (q *Queries) CreateContributor(ctx context.Context, arg CreateContributorParams) (Contributor, error) {
+	row := q.db.QueryRowContext(ctx, createContributor, arg.Name, arg.TokenHash)
+	var i Contributor
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.TokenHash,
+		&i.Disabled,
+		&i.CreatedAt,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
 
 const createFailure = `-- name: CreateFailure :exec
 INSERT INTO failures (
-    channel_id, data, type
+    channel_id, data, type, last_error, dead, priority
 ) VALUES (
-    $1,         $2,   $3
+    $1,         $2,   $3,   $4,         $5,   $6
 )
 `
 
@@ -155,13 +428,67 @@ type CreateFailureParams struct {
 	ChannelID string
 	Data      string
 	Type      string
+	LastError string
+	Dead      bool
+	Priority  float64
 }
 
 func (q *Queries) CreateFailure(ctx context.Context, arg CreateFailureParams) error {
-	_, err := q.db.ExecContext(ctx, createFailure, arg.ChannelID, arg.Data, arg.Type)
+	_, err := q.db.ExecContext(ctx, createFailure,
+		arg.ChannelID,
+		arg.Data,
+		arg.Type,
+		arg.LastError,
+		arg.Dead,
+		arg.Priority,
+	)
 	return err
 }
 
+const createSubmission = `-- name: CreateSubmission :one
+INSERT INTO submissions (
+    video_id, channel_id, contributor_id, format, content, lines
+) VALUES (
+    $1,       $2,         $3,             $4,     $5,      $6
+)
+RETURNING id, video_id, channel_id, contributor_id, format, content, lines, status, reason, created_at, updated_at
+`
+
+type CreateSubmissionParams struct {
+	VideoID       string
+	ChannelID     string
+	ContributorID int64
+	Format        string
+	Content       string
+	Lines         json.RawMessage
+}
+
+func (q *Queries) CreateSubmission(ctx context.Context, arg CreateSubmissionParams) (Submission, error) {
+	row := q.db.QueryRowContext(ctx, createSubmission,
+		arg.VideoID,
+		arg.ChannelID,
+		arg.ContributorID,
+		arg.Format,
+		arg.Content,
+		arg.Lines,
+	)
+	var i Submission
+	err := row.Scan(
+		&i.ID,
+		&i.VideoID,
+		&i.ChannelID,
+		&i.ContributorID,
+		&i.Format,
+		&i.Content,
+		&i.Lines,
+		&i.Status,
+		&i.Reason,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const createTranscript = `-- name: CreateTranscript :one
 INSERT INTO transcripts (
     video_id, start, text
@@ -184,23 +511,79 @@ func (q *Queries) CreateTranscript(ctx context.Context, arg CreateTranscriptPara
 	return id, err
 }
 
+const createTranscriptDetails = `-- name: CreateTranscriptDetails :exec
+INSERT INTO transcript_details (
+    transcript_id, confidence, words, speaker
+) VALUES (
+    $1,            $2,         $3,    $4
+)
+`
+
+type CreateTranscriptDetailsParams struct {
+	TranscriptID int64
+	Confidence   float32
+	Words        json.RawMessage
+	Speaker      string
+}
+
+func (q *Queries) CreateTranscriptDetails(ctx context.Context, arg CreateTranscriptDetailsParams) error {
+	_, err := q.db.ExecContext(ctx, createTranscriptDetails,
+		arg.TranscriptID,
+		arg.Confidence,
+		arg.Words,
+		arg.Speaker,
+	)
+	return err
+}
+
+const createTranscriptVersion = `-- name: CreateTranscriptVersion :exec
+INSERT INTO transcript_versions (
+    video_id, transcript_type, status, reason, lines
+) VALUES (
+    $1,       $2,              $3,     $4,     $5
+)
+`
+
+type CreateTranscriptVersionParams struct {
+	VideoID        string
+	TranscriptType string
+	Status         string
+	Reason         string
+	Lines          json.RawMessage
+}
+
+func (q *Queries) CreateTranscriptVersion(ctx context.Context, arg CreateTranscriptVersionParams) error {
+	_, err := q.db.ExecContext(ctx, createTranscriptVersion,
+		arg.VideoID,
+		arg.TranscriptType,
+		arg.Status,
+		arg.Reason,
+		arg.Lines,
+	)
+	return err
+}
+
 const createVideo = `-- name: CreateVideo :exec
 INSERT INTO videos (
-    id, channel_id, published_at, title, description, thumbnail_url, searchable_transcript, transcript_type
+    id, channel_id, published_at, title, description, thumbnail_url, searchable_transcript, transcript_type, searchable_title, searchable_description, stem_version, audio_url
 ) VALUES (
-    $1,  $2,        $3,           $4,    $5,          $6,            $7,                    $8
+    $1,  $2,        $3,           $4,    $5,          $6,            $7,                    $8,              $9,               $10,                    $11,          $12
 )
 `
 
 type CreateVideoParams struct {
-	ID                   string
-	ChannelID            string
-	PublishedAt          time.Time
-	Title                string
-	Description          string
-	ThumbnailUrl         string
-	SearchableTranscript string
-	TranscriptType       string
+	ID                    string
+	ChannelID             string
+	PublishedAt           time.Time
+	Title                 string
+	Description           string
+	ThumbnailUrl          string
+	SearchableTranscript  string
+	TranscriptType        string
+	SearchableTitle       string
+	SearchableDescription string
+	StemVersion           int32
+	AudioUrl              string
 }
 
 func (q *Queries) CreateVideo(ctx context.Context, arg CreateVideoParams) error {
@@ -213,10 +596,24 @@ func (q *Queries) CreateVideo(ctx context.Context, arg CreateVideoParams) error
 		arg.ThumbnailUrl,
 		arg.SearchableTranscript,
 		arg.TranscriptType,
+		arg.SearchableTitle,
+		arg.SearchableDescription,
+		arg.StemVersion,
+		arg.AudioUrl,
 	)
 	return err
 }
 
+const deleteChannel = `-- name: DeleteChannel :exec
+DELETE FROM channels
+WHERE id = $1
+`
+
+func (q *Queries) DeleteChannel(ctx context.Context, id string) error {
+	_, err := q.db.ExecContext(ctx, deleteChannel, id)
+	return err
+}
+
 const deleteFailure = `-- name: DeleteFailure :exec
 DELETE FROM failures
 WHERE id = $1
@@ -227,46 +624,116 @@ func (q *Queries) DeleteFailure(ctx context.Context, id int64) error {
 	return err
 }
 
-const lastVideo = `-- name: LastVideo :one
-SELECT id, channel_id, published_at, title, description, thumbnail_url, searchable_transcript, created_at, updated_at, transcript_type FROM videos
+const deleteFailuresOfVideo = `-- name: DeleteFailuresOfVideo :exec
+DELETE FROM failures
+WHERE data = $1
+AND type = $2
+`
+
+type DeleteFailuresOfVideoParams struct {
+	VideoID string
+	Type    string
+}
+
+func (q *Queries) DeleteFailuresOfVideo(ctx context.Context, arg DeleteFailuresOfVideoParams) error {
+	_, err := q.db.ExecContext(ctx, deleteFailuresOfVideo, arg.VideoID, arg.Type)
+	return err
+}
+
+const deleteMediaFile = `-- name: DeleteMediaFile :exec
+DELETE FROM media_files
 WHERE channel_id = $1
-ORDER BY published_at
-DESC LIMIT 1
+AND path = $2
 `
 
-func (q *Queries) LastVideo(ctx context.Context, channelID string) (Video, error) {
-	row := q.db.QueryRowContext(ctx, lastVideo, channelID)
-	var i Video
-	err := row.Scan(
-		&i.ID,
-		&i.ChannelID,
-		&i.PublishedAt,
-		&i.Title,
-		&i.Description,
-		&i.ThumbnailUrl,
-		&i.SearchableTranscript,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.TranscriptType,
-	)
-	return i, err
+type DeleteMediaFileParams struct {
+	ChannelID string
+	Path      string
 }
 
-const nextFailure = `-- name: NextFailure :one
-SELECT id, channel_id, data, type, created_at, updated_at FROM failures
-WHERE id > $1
-AND type = $2
-ORDER BY id ASC
-LIMIT 1
+func (q *Queries) DeleteMediaFile(ctx context.Context, arg DeleteMediaFileParams) error {
+	_, err := q.db.ExecContext(ctx, deleteMediaFile, arg.ChannelID, arg.Path)
+	return err
+}
+
+const deleteTranscriptsOfVideo = `-- name: DeleteTranscriptsOfVideo :exec
+DELETE FROM transcripts
+WHERE video_id = $1
+`
+
+func (q *Queries) DeleteTranscriptsOfVideo(ctx context.Context, videoID string) error {
+	_, err := q.db.ExecContext(ctx, deleteTranscriptsOfVideo, videoID)
+	return err
+}
+
+const deleteVideo = `-- name: DeleteVideo :exec
+DELETE FROM videos
+WHERE id = $1
+`
+
+func (q *Queries) DeleteVideo(ctx context.Context, id string) error {
+	_, err := q.db.ExecContext(ctx, deleteVideo, id)
+	return err
+}
+
+const disableContributor = `-- name: DisableContributor :execrows
+UPDATE contributors
+SET disabled = TRUE,
+    updated_at = CURRENT_TIMESTAMP
+WHERE name = $1
+`
+
+func (q *Queries) DisableContributor(ctx context.Context, name string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, disableContributor, name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const failAttempt = `-- name: FailAttempt :execrows
+UPDATE failures
+SET attempts = $1,
+    last_error = $2,
+    next_attempt_at = CURRENT_TIMESTAMP + ($3::integer * INTERVAL '1 second'),
+    dead = $4,
+    claimed_by = '',
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $5
+AND claimed_by = $6
 `
 
-type NextFailureParams struct {
-	ID   int64
-	Type string
+type FailAttemptParams struct {
+	Attempts       int32
+	LastError      string
+	BackoffSeconds int32
+	Dead           bool
+	ID             int64
+	WorkerID       string
+}
+
+func (q *Queries) FailAttempt(ctx context.Context, arg FailAttemptParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, failAttempt,
+		arg.Attempts,
+		arg.LastError,
+		arg.BackoffSeconds,
+		arg.Dead,
+		arg.ID,
+		arg.WorkerID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
-func (q *Queries) NextFailure(ctx context.Context, arg NextFailureParams) (Failure, error) {
-	row := q.db.QueryRowContext(ctx, nextFailure, arg.ID, arg.Type)
+const failure = `-- name: Failure :one
+SELECT id, channel_id, data, type, created_at, updated_at, attempts, last_error, next_attempt_at, dead, claimed_by, priority FROM failures
+WHERE id = $1
+`
+
+func (q *Queries) Failure(ctx context.Context, id int64) (Failure, error) {
+	row := q.db.QueryRowContext(ctx, failure, id)
 	var i Failure
 	err := row.Scan(
 		&i.ID,
@@ -275,32 +742,52 @@ func (q *Queries) NextFailure(ctx context.Context, arg NextFailureParams) (Failu
 		&i.Type,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Attempts,
+		&i.LastError,
+		&i.NextAttemptAt,
+		&i.Dead,
+		&i.ClaimedBy,
+		&i.Priority,
 	)
 	return i, err
 }
 
-const noCaptionFailures = `-- name: NoCaptionFailures :many
-SELECT id, channel_id, data, type, created_at, updated_at FROM failures
-WHERE channel_id = $1
-AND type = "no_captions"
+const failureCountsByChannel = `-- name: FailureCountsByChannel :many
+SELECT channels.id AS channel_id, channels.title, failures.type,
+    COUNT(*) AS total,
+    COUNT(*) FILTER (WHERE failures.dead) AS dead,
+    COUNT(*) FILTER (WHERE NOT failures.dead AND failures.next_attempt_at <= CURRENT_TIMESTAMP) AS due
+FROM failures
+JOIN channels ON channels.id = failures.channel_id
+GROUP BY channels.id, channels.title, failures.type
+ORDER BY channels.title, failures.type
 `
 
-func (q *Queries) NoCaptionFailures(ctx context.Context, channelID string) ([]Failure, error) {
-	rows, err := q.db.QueryContext(ctx, noCaptionFailures, channelID)
+type FailureCountsByChannelRow struct {
+	ChannelID string
+	Title     string
+	Type      string
+	Total     int64
+	Dead      int64
+	Due       int64
+}
+
+func (q *Queries) FailureCountsByChannel(ctx context.Context) ([]FailureCountsByChannelRow, error) {
+	rows, err := q.db.QueryContext(ctx, failureCountsByChannel)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Failure
+	var items []FailureCountsByChannelRow
 	for rows.Next() {
-		var i Failure
+		var i FailureCountsByChannelRow
 		if err := rows.Scan(
-			&i.ID,
 			&i.ChannelID,
-			&i.Data,
+			&i.Title,
 			&i.Type,
-			&i.CreatedAt,
-			&i.UpdatedAt,
+			&i.Total,
+			&i.Dead,
+			&i.Due,
 		); err != nil {
 			return nil, err
 		}
@@ -315,28 +802,746 @@ func (q *Queries) NoCaptionFailures(ctx context.Context, channelID string) ([]Fa
 	return items, nil
 }
 
-const setSearchableTranscript = `-- name: SetSearchableTranscript :exec
-UPDATE videos
-SET searchable_transcript = $2
-WHERE id = $1
+const failureCountsByType = `-- name: FailureCountsByType :many
+SELECT type, COUNT(*) AS count FROM failures
+GROUP BY type
+ORDER BY type
 `
 
-type SetSearchableTranscriptParams struct {
-	ID                   string
-	SearchableTranscript string
-}
-
-func (q *Queries) SetSearchableTranscript(ctx context.Context, arg SetSearchableTranscriptParams) error {
-	_, err := q.db.ExecContext(ctx, setSearchableTranscript, arg.ID, arg.SearchableTranscript)
-	return err
+type FailureCountsByTypeRow struct {
+	Type  string
+	Count int64
 }
 
-const transcript = `-- name: Transcript :one
-SELECT id, video_id, start, text FROM transcripts
-WHERE id = $1
-`
-
-func (q *Queries) Transcript(ctx context.Context, id int64) (Transcript, error) {
+func (q *Queries) FailureCountsByType(ctx context.Context) ([]FailureCountsByTypeRow, error) {
+	rows, err := q.db.QueryContext(ctx, failureCountsByType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FailureCountsByTypeRow
+	for rows.Next() {
+		var i FailureCountsByTypeRow
+		if err := rows.Scan(&i.Type, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const failureDataOfChannel = `-- name: FailureDataOfChannel :many
+SELECT data FROM failures
+WHERE channel_id = $1
+AND type = $2
+`
+
+type FailureDataOfChannelParams struct {
+	ChannelID string
+	Type      string
+}
+
+func (q *Queries) FailureDataOfChannel(ctx context.Context, arg FailureDataOfChannelParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, failureDataOfChannel, arg.ChannelID, arg.Type)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		items = append(items, data)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lastVideo = `-- name: LastVideo :one
+SELECT id, channel_id, published_at, title, description, thumbnail_url, searchable_transcript, created_at, updated_at, transcript_type, searchable_title, searchable_description, stem_version, audio_url, segmented FROM videos
+WHERE channel_id = $1
+ORDER BY published_at
+DESC LIMIT 1
+`
+
+func (q *Queries) LastVideo(ctx context.Context, channelID string) (Video, error) {
+	row := q.db.QueryRowContext(ctx, lastVideo, channelID)
+	var i Video
+	err := row.Scan(
+		&i.ID,
+		&i.ChannelID,
+		&i.PublishedAt,
+		&i.Title,
+		&i.Description,
+		&i.ThumbnailUrl,
+		&i.SearchableTranscript,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.TranscriptType,
+		&i.SearchableTitle,
+		&i.SearchableDescription,
+		&i.StemVersion,
+		&i.AudioUrl,
+		&i.Segmented,
+	)
+	return i, err
+}
+
+const listFailures = `-- name: ListFailures :many
+SELECT id, channel_id, data, type, created_at, updated_at, attempts, last_error, next_attempt_at, dead, claimed_by, priority FROM failures
+WHERE ($1::varchar IS NULL OR channel_id = $1)
+AND ($2::varchar IS NULL OR type = $2)
+AND ($3::timestamp IS NULL OR created_at < $3)
+AND ($4::boolean IS NULL OR dead = $4)
+ORDER BY dead, priority DESC, id
+LIMIT $5
+`
+
+type ListFailuresParams struct {
+	ChannelID     sql.NullString
+	Type          sql.NullString
+	CreatedBefore sql.NullTime
+	Dead          sql.NullBool
+	MaxResults    int32
+}
+
+func (q *Queries) ListFailures(ctx context.Context, arg ListFailuresParams) ([]Failure, error) {
+	rows, err := q.db.QueryContext(ctx, listFailures,
+		arg.ChannelID,
+		arg.Type,
+		arg.CreatedBefore,
+		arg.Dead,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Failure
+	for rows.Next() {
+		var i Failure
+		if err := rows.Scan(
+			&i.ID,
+			&i.ChannelID,
+			&i.Data,
+			&i.Type,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Attempts,
+			&i.LastError,
+			&i.NextAttemptAt,
+			&i.Dead,
+			&i.ClaimedBy,
+			&i.Priority,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSubmissions = `-- name: ListSubmissions :many
+SELECT submissions.id, submissions.video_id, submissions.channel_id, submissions.contributor_id, submissions.format, submissions.content, submissions.lines, submissions.status, submissions.reason, submissions.created_at, submissions.updated_at, contributors.name AS contributor FROM submissions
+JOIN contributors ON contributors.id = submissions.contributor_id
+WHERE ($1::varchar IS NULL OR submissions.status = $1)
+ORDER BY submissions.id
+LIMIT $2
+`
+
+type ListSubmissionsParams struct {
+	Status     sql.NullString
+	MaxResults int32
+}
+
+type ListSubmissionsRow struct {
+	ID            int64
+	VideoID       string
+	ChannelID     string
+	ContributorID int64
+	Format        string
+	Content       string
+	Lines         json.RawMessage
+	Status        string
+	Reason        string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Contributor   string
+}
+
+func (q *Queries) ListSubmissions(ctx context.Context, arg ListSubmissionsParams) ([]ListSubmissionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSubmissions, arg.Status, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSubmissionsRow
+	for rows.Next() {
+		var i ListSubmissionsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.VideoID,
+			&i.ChannelID,
+			&i.ContributorID,
+			&i.Format,
+			&i.Content,
+			&i.Lines,
+			&i.Status,
+			&i.Reason,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Contributor,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockNoCaptionsFailuresOfVideo = `-- name: LockNoCaptionsFailuresOfVideo :many
+SELECT claimed_by, next_attempt_at > CURRENT_TIMESTAMP AS leased FROM failures
+WHERE data = $1
+AND type = 'no_captions'
+FOR UPDATE
+`
+
+type LockNoCaptionsFailuresOfVideoRow struct {
+	ClaimedBy string
+	Leased    bool
+}
+
+func (q *Queries) LockNoCaptionsFailuresOfVideo(ctx context.Context, videoID string) ([]LockNoCaptionsFailuresOfVideoRow, error) {
+	rows, err := q.db.QueryContext(ctx, lockNoCaptionsFailuresOfVideo, videoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LockNoCaptionsFailuresOfVideoRow
+	for rows.Next() {
+		var i LockNoCaptionsFailuresOfVideoRow
+		if err := rows.Scan(&i.ClaimedBy, &i.Leased); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const mediaFile = `-- name: MediaFile :one
+SELECT video_id, channel_id, path, size, modified_at, created_at, updated_at FROM media_files
+WHERE channel_id = $1
+AND video_id = $2
+ORDER BY updated_at DESC
+LIMIT 1
+`
+
+type MediaFileParams struct {
+	ChannelID string
+	VideoID   string
+}
+
+func (q *Queries) MediaFile(ctx context.Context, arg MediaFileParams) (MediaFile, error) {
+	row := q.db.QueryRowContext(ctx, mediaFile, arg.ChannelID, arg.VideoID)
+	var i MediaFile
+	err := row.Scan(
+		&i.VideoID,
+		&i.ChannelID,
+		&i.Path,
+		&i.Size,
+		&i.ModifiedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const mediaFilesOfChannel = `-- name: MediaFilesOfChannel :many
+SELECT video_id, channel_id, path, size, modified_at, created_at, updated_at FROM media_files
+WHERE channel_id = $1
+ORDER BY path
+`
+
+func (q *Queries) MediaFilesOfChannel(ctx context.Context, channelID string) ([]MediaFile, error) {
+	rows, err := q.db.QueryContext(ctx, mediaFilesOfChannel, channelID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MediaFile
+	for rows.Next() {
+		var i MediaFile
+		if err := rows.Scan(
+			&i.VideoID,
+			&i.ChannelID,
+			&i.Path,
+			&i.Size,
+			&i.ModifiedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const noCaptionFailures = `-- name: NoCaptionFailures :many
+SELECT id, channel_id, data, type, created_at, updated_at, attempts, last_error, next_attempt_at, dead, claimed_by, priority FROM failures
+WHERE channel_id = $1
+AND type = "no_captions"
+`
+
+func (q *Queries) NoCaptionFailures(ctx context.Context, channelID string) ([]Failure, error) {
+	rows, err := q.db.QueryContext(ctx, noCaptionFailures, channelID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Failure
+	for rows.Next() {
+		var i Failure
+		if err := rows.Scan(
+			&i.ID,
+			&i.ChannelID,
+			&i.Data,
+			&i.Type,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Attempts,
+			&i.LastError,
+			&i.NextAttemptAt,
+			&i.Dead,
+			&i.ClaimedBy,
+			&i.Priority,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const noCaptionsFailureOfVideo = `-- name: NoCaptionsFailureOfVideo :one
+SELECT id, channel_id, data, type, created_at, updated_at, attempts, last_error, next_attempt_at, dead, claimed_by, priority FROM failures
+WHERE data = $1
+AND type = 'no_captions'
+ORDER BY id
+LIMIT 1
+`
+
+func (q *Queries) NoCaptionsFailureOfVideo(ctx context.Context, data string) (Failure, error) {
+	row := q.db.QueryRowContext(ctx, noCaptionsFailureOfVideo, data)
+	var i Failure
+	err := row.Scan(
+		&i.ID,
+		&i.ChannelID,
+		&i.Data,
+		&i.Type,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Attempts,
+		&i.LastError,
+		&i.NextAttemptAt,
+		&i.Dead,
+		&i.ClaimedBy,
+		&i.Priority,
+	)
+	return i, err
+}
+
+const queueRetranscribes = `-- name: QueueRetranscribes :execrows
+INSERT INTO failures (channel_id, data, type, priority)
+SELECT videos.channel_id, videos.id, $1::varchar, $2::double precision FROM videos
+WHERE videos.channel_id = $3
+AND (
+    ($4::varchar IS NULL AND videos.transcript_type NOT IN ('tube_auto', 'tube_manual'))
+    OR videos.transcript_type = $4
+)
+AND ($5::varchar IS NULL OR videos.id = $5)
+AND NOT EXISTS (
+    SELECT 1 FROM failures
+    WHERE failures.data = videos.id
+    AND failures.type = $1::varchar
+    AND NOT failures.dead
+)
+AND NOT EXISTS (
+    SELECT 1 FROM transcript_versions
+    WHERE transcript_versions.video_id = videos.id
+    AND transcript_versions.status = 'rejected'
+    AND videos.transcript_type = 'tube_auto'
+)
+`
+
+type QueueRetranscribesParams struct {
+	FailureType    string
+	Priority       float64
+	ChannelID      string
+	TranscriptType sql.NullString
+	VideoID        sql.NullString
+}
+
+func (q *Queries) QueueRetranscribes(ctx context.Context, arg QueueRetranscribesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, queueRetranscribes,
+		arg.FailureType,
+		arg.Priority,
+		arg.ChannelID,
+		arg.TranscriptType,
+		arg.VideoID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const releaseClaim = `-- name: ReleaseClaim :exec
+UPDATE failures
+SET claimed_by = '',
+    next_attempt_at = CURRENT_TIMESTAMP,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+AND claimed_by = $2
+`
+
+type ReleaseClaimParams struct {
+	ID       int64
+	WorkerID string
+}
+
+func (q *Queries) ReleaseClaim(ctx context.Context, arg ReleaseClaimParams) error {
+	_, err := q.db.ExecContext(ctx, releaseClaim, arg.ID, arg.WorkerID)
+	return err
+}
+
+const renewClaim = `-- name: RenewClaim :execrows
+UPDATE failures
+SET next_attempt_at = CURRENT_TIMESTAMP + ($1::integer * INTERVAL '1 second')
+WHERE id = $2
+AND claimed_by = $3
+`
+
+type RenewClaimParams struct {
+	LeaseSeconds int32
+	ID           int64
+	WorkerID     string
+}
+
+func (q *Queries) RenewClaim(ctx context.Context, arg RenewClaimParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, renewClaim, arg.LeaseSeconds, arg.ID, arg.WorkerID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const retryFailure = `-- name: RetryFailure :exec
+UPDATE failures
+SET attempts = 0,
+    next_attempt_at = CURRENT_TIMESTAMP,
+    dead = FALSE,
+    claimed_by = '',
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+func (q *Queries) RetryFailure(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, retryFailure, id)
+	return err
+}
+
+const setSearchable = `-- name: SetSearchable :exec
+UPDATE videos
+SET searchable_transcript = $2,
+    searchable_title = $3,
+    searchable_description = $4,
+    stem_version = $5
+WHERE id = $1
+`
+
+type SetSearchableParams struct {
+	ID                    string
+	SearchableTranscript  string
+	SearchableTitle       string
+	SearchableDescription string
+	StemVersion           int32
+}
+
+func (q *Queries) SetSearchable(ctx context.Context, arg SetSearchableParams) error {
+	_, err := q.db.ExecContext(ctx, setSearchable,
+		arg.ID,
+		arg.SearchableTranscript,
+		arg.SearchableTitle,
+		arg.SearchableDescription,
+		arg.StemVersion,
+	)
+	return err
+}
+
+const setSearchableTranscript = `-- name: SetSearchableTranscript :exec
+UPDATE videos
+SET searchable_transcript = $2
+WHERE id = $1
+`
+
+type SetSearchableTranscriptParams struct {
+	ID                   string
+	SearchableTranscript string
+}
+
+func (q *Queries) SetSearchableTranscript(ctx context.Context, arg SetSearchableTranscriptParams) error {
+	_, err := q.db.ExecContext(ctx, setSearchableTranscript, arg.ID, arg.SearchableTranscript)
+	return err
+}
+
+const setSegmented = `-- name: SetSegmented :exec
+UPDATE videos
+SET segmented = TRUE
+WHERE id = $1
+`
+
+func (q *Queries) SetSegmented(ctx context.Context, id string) error {
+	_, err := q.db.ExecContext(ctx, setSegmented, id)
+	return err
+}
+
+const setSubmissionStatus = `-- name: SetSubmissionStatus :exec
+UPDATE submissions
+SET status = $2,
+    reason = $3,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+type SetSubmissionStatusParams struct {
+	ID     int64
+	Status string
+	Reason string
+}
+
+func (q *Queries) SetSubmissionStatus(ctx context.Context, arg SetSubmissionStatusParams) error {
+	_, err := q.db.ExecContext(ctx, setSubmissionStatus, arg.ID, arg.Status, arg.Reason)
+	return err
+}
+
+const setTranscriptType = `-- name: SetTranscriptType :exec
+UPDATE videos
+SET transcript_type = $2
+WHERE id = $1
+`
+
+type SetTranscriptTypeParams struct {
+	ID             string
+	TranscriptType string
+}
+
+func (q *Queries) SetTranscriptType(ctx context.Context, arg SetTranscriptTypeParams) error {
+	_, err := q.db.ExecContext(ctx, setTranscriptType, arg.ID, arg.TranscriptType)
+	return err
+}
+
+const shiftFailurePriorities = `-- name: ShiftFailurePriorities :exec
+UPDATE failures
+SET priority = priority + $1::double precision
+WHERE channel_id = $2
+AND NOT dead
+`
+
+type ShiftFailurePrioritiesParams struct {
+	Amount    float64
+	ChannelID string
+}
+
+func (q *Queries) ShiftFailurePriorities(ctx context.Context, arg ShiftFailurePrioritiesParams) error {
+	_, err := q.db.ExecContext(ctx, shiftFailurePriorities, arg.Amount, arg.ChannelID)
+	return err
+}
+
+const speakersOfTranscripts = `-- name: SpeakersOfTranscripts :many
+SELECT transcript_id, speaker FROM transcript_details
+WHERE transcript_id = ANY($1::bigint[])
+AND speaker != ''
+`
+
+type SpeakersOfTranscriptsRow struct {
+	TranscriptID int64
+	Speaker      string
+}
+
+func (q *Queries) SpeakersOfTranscripts(ctx context.Context, ids []int64) ([]SpeakersOfTranscriptsRow, error) {
+	rows, err := q.db.QueryContext(ctx, speakersOfTranscripts, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SpeakersOfTranscriptsRow
+	for rows.Next() {
+		var i SpeakersOfTranscriptsRow
+		if err := rows.Scan(&i.TranscriptID, &i.Speaker); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const staleVideoIDs = `-- name: StaleVideoIDs :many
+SELECT id FROM videos
+WHERE stem_version < $1
+AND ($2::varchar IS NULL OR channel_id = $2)
+AND ($3::varchar IS NULL OR id = $3)
+AND id > $4
+ORDER BY id
+LIMIT $5
+`
+
+type StaleVideoIDsParams struct {
+	StemVersion int32
+	ChannelID   sql.NullString
+	VideoID     sql.NullString
+	After       string
+	BatchSize   int32
+}
+
+func (q *Queries) StaleVideoIDs(ctx context.Context, arg StaleVideoIDsParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, staleVideoIDs,
+		arg.StemVersion,
+		arg.ChannelID,
+		arg.VideoID,
+		arg.After,
+		arg.BatchSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const stats = `-- name: Stats :one
+SELECT
+    (SELECT COUNT(*) FROM channels) AS channels,
+    (SELECT COUNT(*) FROM videos) AS videos,
+    (SELECT COUNT(*) FROM videos WHERE stem_version < $1) AS outdated_videos,
+    (SELECT COUNT(*) FROM transcripts) AS transcripts,
+    (SELECT COUNT(*) FROM failures) AS failures
+`
+
+type StatsRow struct {
+	Channels       int64
+	Videos         int64
+	OutdatedVideos int64
+	Transcripts    int64
+	Failures       int64
+}
+
+func (q *Queries) Stats(ctx context.Context, stemVersion int32) (StatsRow, error) {
+	row := q.db.QueryRowContext(ctx, stats, stemVersion)
+	var i StatsRow
+	err := row.Scan(
+		&i.Channels,
+		&i.Videos,
+		&i.OutdatedVideos,
+		&i.Transcripts,
+		&i.Failures,
+	)
+	return i, err
+}
+
+const submission = `-- name: Submission :one
+SELECT id, video_id, channel_id, contributor_id, format, content, lines, status, reason, created_at, updated_at FROM submissions
+WHERE id = $1
+`
+
+func (q *Queries) Submission(ctx context.Context, id int64) (Submission, error) {
+	row := q.db.QueryRowContext(ctx, submission, id)
+	var i Submission
+	err := row.Scan(
+		&i.ID,
+		&i.VideoID,
+		&i.ChannelID,
+		&i.ContributorID,
+		&i.Format,
+		&i.Content,
+		&i.Lines,
+		&i.Status,
+		&i.Reason,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const transcript = `-- name: Transcript :one
+SELECT id, video_id, start, text FROM transcripts
+WHERE id = $1
+`
+
+func (q *Queries) Transcript(ctx context.Context, id int64) (Transcript, error) {
 	row := q.db.QueryRowContext(ctx, transcript, id)
 	var i Transcript
 	err := row.Scan(
@@ -348,6 +1553,77 @@ func (q *Queries) Transcript(ctx context.Context, id int64) (Transcript, error)
 	return i, err
 }
 
+const transcriptDetailsOfVideo = `-- name: TranscriptDetailsOfVideo :many
+SELECT transcript_details.transcript_id, transcript_details.confidence, transcript_details.words, transcript_details.speaker FROM transcript_details
+JOIN transcripts ON transcripts.id = transcript_details.transcript_id
+WHERE transcripts.video_id = $1
+`
+
+func (q *Queries) TranscriptDetailsOfVideo(ctx context.Context, videoID string) ([]TranscriptDetail, error) {
+	rows, err := q.db.QueryContext(ctx, transcriptDetailsOfVideo, videoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TranscriptDetail
+	for rows.Next() {
+		var i TranscriptDetail
+		if err := rows.Scan(
+			&i.TranscriptID,
+			&i.Confidence,
+			&i.Words,
+			&i.Speaker,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const transcriptVersionsOfVideo = `-- name: TranscriptVersionsOfVideo :many
+SELECT id, video_id, transcript_type, status, reason, lines, created_at FROM transcript_versions
+WHERE video_id = $1
+ORDER BY id
+`
+
+func (q *Queries) TranscriptVersionsOfVideo(ctx context.Context, videoID string) ([]TranscriptVersion, error) {
+	rows, err := q.db.QueryContext(ctx, transcriptVersionsOfVideo, videoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TranscriptVersion
+	for rows.Next() {
+		var i TranscriptVersion
+		if err := rows.Scan(
+			&i.ID,
+			&i.VideoID,
+			&i.TranscriptType,
+			&i.Status,
+			&i.Reason,
+			&i.Lines,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const transcriptsByIds = `-- name: TranscriptsByIds :many
 SELECT id, video_id, start, text FROM transcripts
 WHERE id = ANY($1::bigint[])
@@ -381,13 +1657,196 @@ func (q *Queries) TranscriptsByIds(ctx context.Context, ids []int64) ([]Transcri
 	return items, nil
 }
 
-const video = `-- name: Video :one
+const transcriptsOfVideo = `-- name: TranscriptsOfVideo :many
+SELECT id, video_id, start, text FROM transcripts
+WHERE video_id = $1
+ORDER BY start, id
+`
+
+func (q *Queries) TranscriptsOfVideo(ctx context.Context, videoID string) ([]Transcript, error) {
+	rows, err := q.db.QueryContext(ctx, transcriptsOfVideo, videoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Transcript
+	for rows.Next() {
+		var i Transcript
+		if err := rows.Scan(
+			&i.ID,
+			&i.VideoID,
+			&i.Start,
+			&i.Text,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateChannel = `-- name: UpdateChannel :one
+UPDATE channels
+SET title = $2,
+    videos_list_id = $3,
+    thumbnail_url = $4,
+    custom_url = $5,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING id, title, videos_list_id, thumbnail_url, created_at, updated_at, custom_url, source, feed_url, directory
+`
+
+type UpdateChannelParams struct {
+	ID           string
+	Title        string
+	VideosListID string
+	ThumbnailUrl string
+	CustomUrl    string
+}
+
+func (q *Queries) UpdateChannel(ctx context.Context, arg UpdateChannelParams) (Channel, error) {
+	row := q.db.QueryRowContext(ctx, updateChannel,
+		arg.ID,
+		arg.Title,
+		arg.VideosListID,
+		arg.ThumbnailUrl,
+		arg.CustomUrl,
+	)
+	var i Channel
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.VideosListID,
+		&i.ThumbnailUrl,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CustomUrl,
+		&i.Source,
+		&i.FeedUrl,
+		&i.Directory,
+	)
+	return i, err
+}
+
+const upsertChannelSettings = `-- name: UpsertChannelSettings :one
+INSERT INTO channel_settings (
+    channel_id, include_shorts, include_live, include_premieres, min_duration, title_blocklist, caption_source, whisper_fallback, whisper_model, whisper_language, upgrade_captions, priority, diarize
+) VALUES (
+    $1,         $2,             $3,           $4,                $5,           $6,              $7,             $8,               $9,            $10,              $11,              $12,      $13
+)
+ON CONFLICT (channel_id) DO UPDATE
+SET include_shorts = EXCLUDED.include_shorts,
+    include_live = EXCLUDED.include_live,
+    include_premieres = EXCLUDED.include_premieres,
+    min_duration = EXCLUDED.min_duration,
+    title_blocklist = EXCLUDED.title_blocklist,
+    caption_source = EXCLUDED.caption_source,
+    whisper_fallback = EXCLUDED.whisper_fallback,
+    whisper_model = EXCLUDED.whisper_model,
+    whisper_language = EXCLUDED.whisper_language,
+    upgrade_captions = EXCLUDED.upgrade_captions,
+    priority = EXCLUDED.priority,
+    diarize = EXCLUDED.diarize,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING channel_id, include_shorts, include_live, include_premieres, min_duration, title_blocklist, caption_source, whisper_fallback, created_at, updated_at, whisper_model, whisper_language, upgrade_captions, priority, diarize
+`
+
+type UpsertChannelSettingsParams struct {
+	ChannelID        string
+	IncludeShorts    bool
+	IncludeLive      bool
+	IncludePremieres bool
+	MinDuration      int32
+	TitleBlocklist   string
+	CaptionSource    string
+	WhisperFallback  bool
+	WhisperModel     string
+	WhisperLanguage  string
+	UpgradeCaptions  string
+	Priority         int32
+	Diarize          bool
+}
+
+func (q *Queries) UpsertChannelSettings(ctx context.Context, arg UpsertChannelSettingsParams) (ChannelSetting, error) {
+	row := q.db.QueryRowContext(ctx, upsertChannelSettings,
+		arg.ChannelID,
+		arg.IncludeShorts,
+		arg.IncludeLive,
+		arg.IncludePremieres,
+		arg.MinDuration,
+		arg.TitleBlocklist,
+		arg.CaptionSource,
+		arg.WhisperFallback,
+		arg.WhisperModel,
+		arg.WhisperLanguage,
+		arg.UpgradeCaptions,
+		arg.Priority,
+		arg.Diarize,
+	)
+	var i ChannelSetting
+	err := row.Scan(
+		&i.ChannelID,
+		&i.IncludeShorts,
+		&i.IncludeLive,
+		&i.IncludePremieres,
+		&i.MinDuration,
+		&i.TitleBlocklist,
+		&i.CaptionSource,
+		&i.WhisperFallback,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.WhisperModel,
+		&i.WhisperLanguage,
+		&i.UpgradeCaptions,
+		&i.Priority,
+		&i.Diarize,
+	)
+	return i, err
+}
+
+const upsertMediaFile = `-- name: UpsertMediaFile :exec
+INSERT INTO media_files (
+    video_id, channel_id, path, size, modified_at
+) VALUES (
+    $1,       $2,         $3,   $4,   $5
+)
+ON CONFLICT (channel_id, path) DO UPDATE
+SET video_id = EXCLUDED.video_id,
+    size = EXCLUDED.size,
+    modified_at = EXCLUDED.modified_at,
+    updated_at = CURRENT_TIMESTAMP
+`
+
+type UpsertMediaFileParams struct {
+	VideoID    string
+	ChannelID  string
+	Path       string
+	Size       int64
+	ModifiedAt time.Time
+}
+
+func (q *Queries) UpsertMediaFile(ctx context.Context, arg UpsertMediaFileParams) error {
+	_, err := q.db.ExecContext(ctx, upsertMediaFile,
+		arg.VideoID,
+		arg.ChannelID,
+		arg.Path,
+		arg.Size,
+		arg.ModifiedAt,
+	)
+	return err
+}
 
-SELECT id, channel_id, published_at, title, description, thumbnail_url, searchable_transcript, created_at, updated_at, transcript_type FROM videos
+const video = `-- name: Video :one
+SELECT id, channel_id, published_at, title, description, thumbnail_url, searchable_transcript, created_at, updated_at, transcript_type, searchable_title, searchable_description, stem_version, audio_url, segmented FROM videos
 WHERE id = $1
 `
 
-// Need second arg here because type is a reserved word in go.
 func (q *Queries) Video(ctx context.Context, id string) (Video, error) {
 	row := q.db.QueryRowContext(ctx, video, id)
 	var i Video
@@ -402,12 +1861,109 @@ func (q *Queries) Video(ctx context.Context, id string) (Video, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.TranscriptType,
+		&i.SearchableTitle,
+		&i.SearchableDescription,
+		&i.StemVersion,
+		&i.AudioUrl,
+		&i.Segmented,
 	)
 	return i, err
 }
 
+const videoCountsByTranscriptType = `-- name: VideoCountsByTranscriptType :many
+SELECT transcript_type, COUNT(*) AS count FROM videos
+GROUP BY transcript_type
+ORDER BY transcript_type
+`
+
+type VideoCountsByTranscriptTypeRow struct {
+	TranscriptType string
+	Count          int64
+}
+
+func (q *Queries) VideoCountsByTranscriptType(ctx context.Context) ([]VideoCountsByTranscriptTypeRow, error) {
+	rows, err := q.db.QueryContext(ctx, videoCountsByTranscriptType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []VideoCountsByTranscriptTypeRow
+	for rows.Next() {
+		var i VideoCountsByTranscriptTypeRow
+		if err := rows.Scan(&i.TranscriptType, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const videoIDsOfChannel = `-- name: VideoIDsOfChannel :many
+SELECT id FROM videos
+WHERE channel_id = $1
+`
+
+func (q *Queries) VideoIDsOfChannel(ctx context.Context, channelID string) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, videoIDsOfChannel, channelID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const videoIDsToResegment = `-- name: VideoIDsToResegment :many
+SELECT id FROM videos
+WHERE transcript_type = $1
+AND NOT segmented
+ORDER BY id
+`
+
+func (q *Queries) VideoIDsToResegment(ctx context.Context, transcriptType string) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, videoIDsToResegment, transcriptType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const videosOfChannel = `-- name: VideosOfChannel :many
-SELECT id, channel_id, published_at, title, description, thumbnail_url, searchable_transcript, created_at, updated_at, transcript_type FROM videos
+SELECT id, channel_id, published_at, title, description, thumbnail_url, searchable_transcript, created_at, updated_at, transcript_type, searchable_title, searchable_description, stem_version, audio_url, segmented FROM videos
 WHERE channel_id = $1
 `
 
@@ -431,6 +1987,11 @@ func (q *Queries) VideosOfChannel(ctx context.Context, channelID string) ([]Vide
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.TranscriptType,
+			&i.SearchableTitle,
+			&i.SearchableDescription,
+			&i.StemVersion,
+			&i.AudioUrl,
+			&i.Segmented,
 		); err != nil {
 			return nil, err
 		}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Store is the database interface the rest of the application depends on.
+// It is implemented by *DB, tests can provide their own implementation.
+type Store interface {
+	Querier
+
+	// See (*Queries).VideosOfChannelWithWords.
+	VideosOfChannelWithWords(ctx context.Context, channelID string, words []string) ([]Video, error)
+	// See (*Queries).VideosOfChannelWithWordsAnywhere.
+	VideosOfChannelWithWordsAnywhere(ctx context.Context, channelID string, words []string) ([]Video, error)
+	// See (*Queries).VideosOfChannelMatching.
+	VideosOfChannelMatching(ctx context.Context, channelID string, words []string, match WordMatch, anywhere bool) ([]Video, error)
+
+	// Tx calls f with a Querier that runs inside a transaction.
+	// The transaction is committed when f returns nil, and rolled back otherwise.
+	Tx(ctx context.Context, f func(q Querier) error) error
+}
+
+// DB is the Postgres backed Store.
+type DB struct {
+	*Queries
+	db *sql.DB
+}
+
+var _ Store = (*DB)(nil)
+
+func NewDB(db *sql.DB) *DB {
+	return &DB{
+		Queries: New(db),
+		db:      db,
+	}
+}
+
+func (d *DB) Tx(ctx context.Context, f func(q Querier) error) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback, ignore error which is returned if tx is committed.
+
+	if err := f(d.WithTx(tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,179 @@
+// Package subtitles parses transcripts that are submitted by contributors, SRT and WebVTT files and timestamped text.
+package subtitles
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Line is a line of a transcript.
+type Line struct {
+	Start time.Duration
+	End   time.Duration // 0 if unknown.
+	Text  string
+}
+
+// ErrEmpty is returned when the content has no lines with text.
+var ErrEmpty = errors.New("no lines with text")
+
+// ParseSRT parses SubRip subtitles, the numbers of the cues are ignored.
+func ParseSRT(content string) ([]Line, error) {
+	return parseCues(content)
+}
+
+// ParseVTT parses WebVTT subtitles, notes, styles and cue settings are ignored.
+func ParseVTT(content string) ([]Line, error) {
+	content = strings.TrimPrefix(content, "\uFEFF")
+	if !strings.HasPrefix(content, "WEBVTT") {
+		return nil, errors.New("WebVTT files start with WEBVTT")
+	}
+
+	return parseCues(content)
+}
+
+// parseCues parses the cues of SRT and WebVTT files, a timing line followed by text lines, up to an empty line.
+func parseCues(content string) ([]Line, error) {
+	var lines []Line
+	var cue *Line
+	for n, raw := range splitLines(content) {
+		raw = strings.TrimSpace(raw)
+
+		switch {
+		case raw == "":
+			cue = nil
+		case strings.Contains(raw, "-->"):
+			start, end, err := parseTiming(raw)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+
+			lines = append(lines, Line{Start: start, End: end})
+			cue = &lines[len(lines)-1]
+		case cue != nil:
+			cue.Text = strings.TrimSpace(cue.Text + " " + clean(raw))
+		}
+	}
+
+	return finish(lines)
+}
+
+// parseTiming parses a timing line like "00:00:01,000 --> 00:00:04,000", or "00:01.000 --> 00:04.000 align:start".
+func parseTiming(line string) (time.Duration, time.Duration, error) {
+	from, to, _ := strings.Cut(line, "-->")
+	start, err := ParseTimestamp(strings.TrimSpace(from))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	fields := strings.Fields(to)
+	if len(fields) == 0 {
+		return 0, 0, fmt.Errorf("timing %q has no end", line)
+	}
+
+	end, err := ParseTimestamp(fields[0])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return start, end, nil
+}
+
+// textLine is a line of timestamped text, the timestamp is optionally between brackets or parentheses.
+var textLine = regexp.MustCompile(`^[\[(]?((?:\d+:)?\d{1,2}:\d{2}(?:[.,]\d+)?)[\])]?\s*(?:-\s+)?(.*)$`)
+
+// ParseText parses lines that start with a timestamp, like "1:23 text" or "[01:02:03] text".
+// Lines without a timestamp continue the text of the line before them.
+func ParseText(content string) ([]Line, error) {
+	var lines []Line
+	for n, raw := range splitLines(content) {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+
+		match := textLine.FindStringSubmatch(raw)
+		if match == nil {
+			if len(lines) == 0 {
+				return nil, fmt.Errorf("line %d: the first line needs to start with a timestamp", n+1)
+			}
+
+			last := &lines[len(lines)-1]
+			last.Text = strings.TrimSpace(last.Text + " " + clean(raw))
+			continue
+		}
+
+		start, err := ParseTimestamp(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+
+		lines = append(lines, Line{Start: start, Text: clean(match[2])})
+	}
+
+	return finish(lines)
+}
+
+// ParseTimestamp parses timestamps like 1:23, 01:02:03, 00:01.500 and 00:00:01,500.
+func ParseTimestamp(value string) (time.Duration, error) {
+	clock, fraction, _ := strings.Cut(strings.ReplaceAll(value, ",", "."), ".")
+	parts := strings.Split(clock, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp %q", value)
+	}
+
+	var d time.Duration
+	for n, part := range parts {
+		v, err := strconv.Atoi(part)
+		if err != nil || v < 0 || (n > 0 && v >= 60) {
+			return 0, fmt.Errorf("invalid timestamp %q", value)
+		}
+
+		d = d*60 + time.Duration(v)
+	}
+	d *= time.Second
+
+	if fraction != "" {
+		f, err := strconv.ParseFloat("0."+fraction, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid timestamp %q", value)
+		}
+
+		d += time.Duration(f * float64(time.Second))
+	}
+
+	return d, nil
+}
+
+var tags = regexp.MustCompile(`<[^>]*>`)
+
+// clean removes markup, like <i> and the word timings of WebVTT, and unescapes entities.
+func clean(text string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(tags.ReplaceAllString(text, ""))), " ")
+}
+
+func splitLines(content string) []string {
+	return strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+}
+
+// finish drops lines without text, and sorts the lines by their start.
+func finish(lines []Line) ([]Line, error) {
+	kept := lines[:0]
+	for _, line := range lines {
+		if line.Text != "" {
+			kept = append(kept, line)
+		}
+	}
+
+	if len(kept) == 0 {
+		return nil, ErrEmpty
+	}
+
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].Start < kept[j].Start })
+	return kept, nil
+}
@@ -0,0 +1,81 @@
+package subtitles_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/subtitles"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		parse   func(string) ([]subtitles.Line, error)
+		content string
+		want    []subtitles.Line
+	}{
+		{
+			name:  "srt",
+			parse: subtitles.ParseSRT,
+			content: "1\r\n00:00:01,000 --> 00:00:04,500\r\nHello <i>there</i>,\r\nfriend.\r\n\r\n" +
+				"2\r\n00:01:00,000 --> 00:01:02,000\r\nBye &amp; thanks\r\n",
+			want: []subtitles.Line{
+				{Start: time.Second, End: 4500 * time.Millisecond, Text: "Hello there, friend."},
+				{Start: time.Minute, End: time.Minute + 2*time.Second, Text: "Bye & thanks"},
+			},
+		},
+		{
+			name:  "vtt",
+			parse: subtitles.ParseVTT,
+			content: "WEBVTT\n\nNOTE written by hand\n\nintro\n00:01.000 --> 00:02.000 align:start\n" +
+				"<00:01.000><c>so</c> <00:01.500><c>today</c>\n\n01:00:00.000 --> 01:00:01.000\n\n",
+			want: []subtitles.Line{{Start: time.Second, End: 2 * time.Second, Text: "so today"}},
+		},
+		{
+			name:    "text",
+			parse:   subtitles.ParseText,
+			content: "[0:05] First line\ncontinues here\n\n1:02:03 - Second line\n(10:00) Third",
+			want: []subtitles.Line{
+				{Start: 5 * time.Second, Text: "First line continues here"},
+				{Start: 10 * time.Minute, Text: "Third"},
+				{Start: time.Hour + 2*time.Minute + 3*time.Second, Text: "Second line"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parse(tt.content)
+			if err != nil {
+				t.Fatalf("parse() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		parse   func(string) ([]subtitles.Line, error)
+		content string
+	}{
+		{name: "empty", parse: subtitles.ParseSRT, content: "\n\n"},
+		{name: "vtt without header", parse: subtitles.ParseVTT, content: "00:01.000 --> 00:02.000\nhi"},
+		{name: "invalid timing", parse: subtitles.ParseSRT, content: "1\n00:00:01 --> soon\nhi"},
+		{name: "text without timestamp", parse: subtitles.ParseText, content: "hi\n0:01 there"},
+		{name: "text with invalid timestamp", parse: subtitles.ParseText, content: "0:75 hi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.parse(tt.content); err == nil {
+				t.Errorf("parse() error = nil, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package transcribe
+
+import (
+	"context"
+
+	"github.com/laytan/youtupedia/internal/store"
+)
+
+// Fake returns Segments, or Err, for every file, without looking at it.
+// Useful in tests, and to try out the pipeline without installing whisper.
+type Fake struct {
+	Segments []Segment
+	Err      error
+	Model    string // Only used for the transcript type, defaults to "fake".
+}
+
+var _ Transcriber = (*Fake)(nil)
+
+func (f *Fake) Type() store.TranscriptType {
+	if f.Model == "" {
+		return transcriptType("fake", "fake")
+	}
+
+	return transcriptType("fake", f.Model)
+}
+
+// With accepts any model and language, and diarizing, Segments are returned as is.
+func (f *Fake) With(params Params) (Transcriber, error) {
+	fake := *f
+	if params.Model != "" {
+		fake.Model = params.Model
+	}
+
+	return &fake, nil
+}
+
+func (f *Fake) Transcribe(ctx context.Context, path string) ([]Segment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return f.Segments, f.Err
+}
@@ -0,0 +1,256 @@
+package transcribe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/store"
+)
+
+type ServerOptions struct {
+	URL      string       // Base URL of the whisper.cpp server, for example "http://localhost:8080".
+	Model    string       // Name of the model the server runs, only used for the transcript type, defaults to "unknown".
+	Language string       // Sent as language, if set.
+	Client   *http.Client // Defaults to http.DefaultClient.
+}
+
+// Server transcribes using the inference endpoint of a whisper.cpp server.
+type Server struct {
+	opts ServerOptions
+}
+
+var _ Transcriber = (*Server)(nil)
+
+func NewServer(opts ServerOptions) *Server {
+	if opts.Model == "" {
+		opts.Model = "unknown"
+	}
+
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+
+	opts.URL = strings.TrimSuffix(opts.URL, "/")
+	return &Server{opts: opts}
+}
+
+func (s *Server) Type() store.TranscriptType {
+	return transcriptType("whisper_server", s.opts.Model)
+}
+
+// With only accepts the model the server runs, a whisper.cpp server can't switch models per request.
+func (s *Server) With(params Params) (Transcriber, error) {
+	opts := s.opts
+	if params.Model != "" && params.Model != opts.Model {
+		return nil, fmt.Errorf("the whisper.cpp server runs model %q, it can't transcribe with %q", opts.Model, params.Model)
+	}
+
+	if params.Diarize {
+		return nil, errors.New("the whisper.cpp server can't diarize, use the whisper.cpp binary with a tinydiarize model")
+	}
+
+	if params.Language != "" {
+		opts.Language = params.Language
+	}
+
+	if err := checkLanguage(opts.Model, opts.Language); err != nil {
+		return nil, err
+	}
+
+	return &Server{opts: opts}, nil
+}
+
+func (s *Server) Transcribe(ctx context.Context, path string) ([]Segment, error) {
+	fields := map[string]string{"response_format": "verbose_json"}
+	if s.opts.Language != "" {
+		fields["language"] = s.opts.Language
+	}
+
+	return postAudio(ctx, s.opts.Client, s.opts.URL+"/inference", nil, fields, path)
+}
+
+type OpenAIOptions struct {
+	BaseURL  string       // Defaults to "https://api.openai.com", any OpenAI compatible API works.
+	APIKey   string       // Sent as bearer token, if set.
+	Model    string       // Defaults to "whisper-1".
+	Language string       // Sent as language, if set, the API detects the language otherwise.
+	Client   *http.Client // Defaults to http.DefaultClient.
+}
+
+// OpenAI transcribes using an OpenAI compatible /v1/audio/transcriptions endpoint.
+// Note that OpenAI limits uploads to 25 MB, roughly 13 minutes of 16 kHz WAV.
+type OpenAI struct {
+	opts OpenAIOptions
+}
+
+var _ Transcriber = (*OpenAI)(nil)
+
+func NewOpenAI(opts OpenAIOptions) *OpenAI {
+	if opts.BaseURL == "" {
+		opts.BaseURL = "https://api.openai.com"
+	}
+
+	if opts.Model == "" {
+		opts.Model = "whisper-1"
+	}
+
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+
+	opts.BaseURL = strings.TrimSuffix(opts.BaseURL, "/")
+	return &OpenAI{opts: opts}
+}
+
+func (o *OpenAI) Type() store.TranscriptType {
+	return transcriptType("openai", o.opts.Model)
+}
+
+func (o *OpenAI) With(params Params) (Transcriber, error) {
+	if params.Diarize {
+		return nil, errors.New("the OpenAI API can't diarize")
+	}
+
+	opts := o.opts
+	if params.Model != "" {
+		opts.Model = params.Model
+	}
+
+	// The API detects the language itself, it does not accept "auto".
+	if params.Language == "auto" {
+		opts.Language = ""
+	} else if params.Language != "" {
+		opts.Language = params.Language
+	}
+
+	return &OpenAI{opts: opts}, nil
+}
+
+func (o *OpenAI) Transcribe(ctx context.Context, path string) ([]Segment, error) {
+	var headers map[string]string
+	if o.opts.APIKey != "" {
+		headers = map[string]string{"Authorization": "Bearer " + o.opts.APIKey}
+	}
+
+	fields := map[string]string{
+		"model":           o.opts.Model,
+		"response_format": "verbose_json",
+	}
+	if o.opts.Language != "" {
+		fields["language"] = o.opts.Language
+	}
+
+	return postAudio(ctx, o.opts.Client, o.opts.BaseURL+"/v1/audio/transcriptions", headers, fields, path)
+}
+
+// verboseJSON is the verbose_json response format, shared by OpenAI and the whisper.cpp server.
+type verboseJSON struct {
+	Segments []struct {
+		Start      float64 // In seconds.
+		End        float64 // In seconds.
+		Text       string
+		AvgLogprob *float64 `json:"avg_logprob"` // Average log probability of the tokens.
+	}
+}
+
+// postAudio uploads the audio file at path as a multipart form, with the fields, and parses the verbose_json response.
+func postAudio(
+	ctx context.Context,
+	client *http.Client,
+	url string,
+	headers map[string]string,
+	fields map[string]string,
+	path string,
+) ([]Segment, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening audio: %w", err)
+	}
+	defer fh.Close()
+
+	// Stream the file into the request instead of buffering it, audio files can be large.
+	body, bodyW := io.Pipe()
+	form := multipart.NewWriter(bodyW)
+	go func() {
+		for name, value := range fields {
+			if err := form.WriteField(name, value); err != nil {
+				bodyW.CloseWithError(err)
+				return
+			}
+		}
+
+		part, err := form.CreateFormFile("file", filepath.Base(path))
+		if err != nil {
+			bodyW.CloseWithError(err)
+			return
+		}
+
+		if _, err := io.Copy(part, fh); err != nil {
+			bodyW.CloseWithError(err)
+			return
+		}
+
+		bodyW.CloseWithError(form.Close())
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		body.Close()
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		return nil, fmt.Errorf("transcription request: %w", err)
+	}
+	defer res.Body.Close()
+
+	content, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading transcription response: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("transcription status code %d: %s", res.StatusCode, content)
+	}
+
+	var result verboseJSON
+	if err := json.Unmarshal(content, &result); err != nil {
+		return nil, fmt.Errorf("unmarshalling transcription response %q: %w", content, err)
+	}
+
+	segments := make([]Segment, 0, len(result.Segments))
+	for _, s := range result.Segments {
+		segment := Segment{
+			Start: time.Duration(s.Start * float64(time.Second)),
+			End:   time.Duration(s.End * float64(time.Second)),
+			Text:  strings.TrimSpace(s.Text),
+		}
+
+		if s.AvgLogprob != nil {
+			segment.Confidence = math.Exp(*s.AvgLogprob)
+		}
+
+		segments = append(segments, segment)
+	}
+
+	return segments, nil
+}
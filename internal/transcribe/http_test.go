@@ -0,0 +1,91 @@
+package transcribe_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/transcribe"
+)
+
+func TestOpenAI(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audio.wav")
+	if err := os.WriteFile(path, []byte("RIFF"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/transcriptions" {
+			t.Errorf("path = %q, want /v1/audio/transcriptions", r.URL.Path)
+		}
+
+		if got := r.Header.Get("Authorization"); got != "Bearer key" {
+			t.Errorf("Authorization = %q, want %q", got, "Bearer key")
+		}
+
+		if got := r.FormValue("model"); got != "whisper-1" {
+			t.Errorf("model = %q, want whisper-1", got)
+		}
+
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("reading file: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		content, _ := io.ReadAll(file)
+		if string(content) != "RIFF" {
+			t.Errorf("file = %q, want RIFF", content)
+		}
+
+		w.Write([]byte(`{"text":"hello world","segments":[{"start":0,"end":1.5,"text":" hello"},{"start":1.5,"end":3,"text":" world"}]}`))
+	}))
+	defer srv.Close()
+
+	o := transcribe.NewOpenAI(transcribe.OpenAIOptions{BaseURL: srv.URL, APIKey: "key"})
+	got, err := o.Transcribe(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []transcribe.Segment{
+		{Start: 0, End: 1500 * time.Millisecond, Text: "hello"},
+		{Start: 1500 * time.Millisecond, End: 3 * time.Second, Text: "world"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Transcribe() = %+v, want %+v", got, want)
+	}
+
+	if typ := o.Type(); typ != "openai:whisper-1" {
+		t.Errorf("Type() = %q, want openai:whisper-1", typ)
+	}
+}
+
+func TestServerError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audio.wav")
+	if err := os.WriteFile(path, []byte("RIFF"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model not loaded", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	s := transcribe.NewServer(transcribe.ServerOptions{URL: srv.URL})
+	if _, err := s.Transcribe(context.Background(), path); err == nil {
+		t.Error("Transcribe() returned no error for a failed request")
+	}
+}
+
+func TestModelName(t *testing.T) {
+	if got := transcribe.ModelName("../whisper.cpp/models/ggml-base.en.bin"); got != "base.en" {
+		t.Errorf("ModelName() = %q, want base.en", got)
+	}
+}
@@ -0,0 +1,74 @@
+// Package transcribe turns audio into timed text, using one of several whisper backends.
+package transcribe
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/store"
+)
+
+// Segment is a piece of transcribed text.
+type Segment struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+
+	Confidence float64 // From 0 to 1, 0 if the backend does not report it.
+	Words      []Word  // Nil if the backend does not report word timings.
+	Speaker    string  // Label of the speaker, like "1", empty if the audio was not diarized.
+}
+
+// Word is a word of a Segment.
+type Word struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+// Transcriber transcribes audio files.
+type Transcriber interface {
+	// Transcribe transcribes the 16 kHz mono WAV file at path.
+	// If ctx is done before it finishes, ctx.Err() is returned.
+	Transcribe(ctx context.Context, path string) ([]Segment, error)
+
+	// Type is the transcript type of videos transcribed by this transcriber,
+	// it identifies the backend and the model, for example "whisper_cpp:base.en".
+	Type() store.TranscriptType
+
+	// With returns a transcriber of the same backend, using the model and language of params,
+	// or an error if the backend can't use them.
+	With(params Params) (Transcriber, error)
+}
+
+// Params select the model and language of a Transcriber, empty fields keep the configured ones.
+type Params struct {
+	Model    string // Name of the model, for example "small.en", the backend decides what names it accepts.
+	Language string // Language hint as ISO 639-1 code, for example "de", or "auto" to detect it.
+	Diarize  bool   // Label the speaker of segments, not every backend or model supports it.
+}
+
+// checkLanguage returns an error if the English-only model can't transcribe the language.
+func checkLanguage(model string, language string) error {
+	if strings.HasSuffix(model, ".en") && language != "" && language != "en" {
+		return fmt.Errorf("model %q is English-only, it can't transcribe language %q", model, language)
+	}
+
+	return nil
+}
+
+// transcriptType combines the backend and model into a store.TranscriptType.
+func transcriptType(backend string, model string) store.TranscriptType {
+	return store.TranscriptType(backend + ":" + model)
+}
+
+// ModelName returns the name of a ggml model from its path,
+// for example "models/ggml-base.en.bin" becomes "base.en".
+func ModelName(path string) string {
+	name := filepath.Base(path)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	return strings.TrimPrefix(name, "ggml-")
+}
@@ -0,0 +1,241 @@
+package transcribe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/store"
+)
+
+type WhisperCppOptions struct {
+	Bin        string // Path to the whisper.cpp main binary, defaults to "../whisper.cpp/main".
+	Model      string // Path to the ggml model, defaults to "../whisper.cpp/models/ggml-base.en.bin".
+	Language   string // Passed as -l, defaults to the default of whisper.cpp, which is "en".
+	Threads    int    // Defaults to runtime.NumCPU() - 1, keeping 1 processor for non-whisper stuff.
+	Processors int    // Defaults to 1, more processors split the audio, which is less accurate around the splits.
+	Diarize    bool   // Passes -tdrz, which needs a tinydiarize model, like ggml-small.en-tdrz.bin.
+}
+
+// WhisperCpp transcribes by running the whisper.cpp main binary.
+type WhisperCpp struct {
+	opts WhisperCppOptions
+}
+
+var _ Transcriber = (*WhisperCpp)(nil)
+
+func NewWhisperCpp(opts WhisperCppOptions) *WhisperCpp {
+	if opts.Bin == "" {
+		opts.Bin = "../whisper.cpp/main"
+	}
+
+	if opts.Model == "" {
+		opts.Model = "../whisper.cpp/models/ggml-base.en.bin"
+	}
+
+	if opts.Threads <= 0 {
+		opts.Threads = runtime.NumCPU() - 1
+		if opts.Threads < 1 {
+			opts.Threads = 1
+		}
+	}
+
+	if opts.Processors <= 0 {
+		opts.Processors = 1
+	}
+
+	return &WhisperCpp{opts: opts}
+}
+
+func (w *WhisperCpp) Type() store.TranscriptType {
+	return transcriptType("whisper_cpp", ModelName(w.opts.Model))
+}
+
+// With resolves a model name, like "small.en", to ggml-small.en.bin in the directory of the configured model.
+// A path to a model, ending in .bin, is used as is.
+// Diarizing needs a tinydiarize model, which has "tdrz" in its name.
+func (w *WhisperCpp) With(params Params) (Transcriber, error) {
+	opts := w.opts
+	if params.Model != "" {
+		opts.Model = params.Model
+		if filepath.Ext(params.Model) != ".bin" {
+			opts.Model = filepath.Join(filepath.Dir(w.opts.Model), "ggml-"+params.Model+".bin")
+		}
+
+		if _, err := os.Stat(opts.Model); err != nil {
+			return nil, fmt.Errorf("model %q: %w", params.Model, err)
+		}
+	}
+
+	if params.Language != "" {
+		opts.Language = params.Language
+	}
+
+	if err := checkLanguage(ModelName(opts.Model), opts.Language); err != nil {
+		return nil, err
+	}
+
+	if params.Diarize {
+		opts.Diarize = true
+	}
+
+	if opts.Diarize && !strings.Contains(ModelName(opts.Model), "tdrz") {
+		return nil, fmt.Errorf("model %q can't diarize, use a tinydiarize model, like small.en-tdrz", ModelName(opts.Model))
+	}
+
+	return &WhisperCpp{opts: opts}, nil
+}
+
+// Transcribe runs whisper.cpp with full JSON output, which it writes next to the audio file.
+func (w *WhisperCpp) Transcribe(ctx context.Context, path string) ([]Segment, error) {
+	args := []string{
+		"-m",
+		w.opts.Model,
+		"-f",
+		path,
+		"-ojf",
+		"-t",
+		strconv.Itoa(w.opts.Threads),
+		"-p",
+		strconv.Itoa(w.opts.Processors),
+	}
+	if w.opts.Language != "" {
+		args = append(args, "-l", w.opts.Language)
+	}
+
+	if w.opts.Diarize {
+		args = append(args, "-tdrz")
+	}
+
+	cmd := exec.CommandContext(ctx, w.opts.Bin, args...)
+	stdout := bytes.Buffer{}
+	cmd.Stdout = &stdout // Need to capture stdout for error messages, for some reasons errors are shown on stdout.
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf(
+				"whisper.cpp: exit code %d and stderr %q and stdout %q: %w",
+				exitErr.ExitCode(),
+				string(exitErr.Stderr),
+				stdout.String(),
+				err,
+			)
+		}
+
+		return nil, fmt.Errorf("whisper.cpp: unexpected err: %w", err)
+	}
+
+	jsonPath := path + ".json"
+	defer func() {
+		log.Printf("[INFO]: deleting file %s (cleanup)", jsonPath)
+		if err := os.Remove(jsonPath); err != nil {
+			log.Printf("[WARN]: cleaning %s: %v", jsonPath, err)
+		}
+	}()
+
+	fh, err := os.Open(jsonPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not open %s: %w", jsonPath, err)
+	}
+	defer fh.Close()
+
+	return parseJSON(fh, w.opts.Diarize)
+}
+
+// fullJSON is the output of whisper.cpp with -ojf, only the fields that are used.
+type fullJSON struct {
+	Transcription []struct {
+		Offsets offsets
+		Text    string
+		Tokens  []struct {
+			Text    string
+			Offsets offsets
+			P       float64 // Probability of the token.
+		}
+		SpeakerTurnNext bool `json:"speaker_turn_next"` // Only with -tdrz, the next segment has another speaker.
+	}
+}
+
+// offsets are in milliseconds from the start of the audio.
+type offsets struct {
+	From int64
+	To   int64
+}
+
+// parseJSON parses the full JSON output of whisper.cpp into segments,
+// with the average probability of the tokens as confidence, and words made of the tokens.
+//
+// When diarized, tinydiarize only marks the turns between speakers, it can't tell who is speaking.
+// Segments are labelled "1" and "2", switching at every turn, which works for the common interview or podcast.
+func parseJSON(r io.Reader, diarized bool) ([]Segment, error) {
+	var output fullJSON
+	if err := json.NewDecoder(r).Decode(&output); err != nil {
+		return nil, fmt.Errorf("parsing whisper.cpp output: %w", err)
+	}
+
+	speaker := 1
+	segments := make([]Segment, 0, len(output.Transcription))
+	for _, t := range output.Transcription {
+		segment := Segment{
+			Start: time.Duration(t.Offsets.From) * time.Millisecond,
+			End:   time.Duration(t.Offsets.To) * time.Millisecond,
+			Text:  strings.TrimSpace(t.Text),
+		}
+
+		if diarized {
+			segment.Speaker = strconv.Itoa(speaker)
+			if t.SpeakerTurnNext {
+				speaker = 3 - speaker
+			}
+		}
+
+		var p float64
+		var n int
+		for _, token := range t.Tokens {
+			// Special tokens, like [_BEG_] and [_TT_150], are not part of the text.
+			if strings.HasPrefix(token.Text, "[_") && strings.HasSuffix(token.Text, "]") {
+				continue
+			}
+
+			p += token.P
+			n++
+
+			start := time.Duration(token.Offsets.From) * time.Millisecond
+			end := time.Duration(token.Offsets.To) * time.Millisecond
+
+			// A token starting with a space starts a word, others continue the previous word.
+			// Tokens can split multi-byte characters, their halves decode as replacement characters.
+			last := len(segment.Words) - 1
+			if last < 0 || strings.HasPrefix(token.Text, " ") {
+				segment.Words = append(segment.Words, Word{Start: start, End: end, Text: strings.TrimSpace(token.Text)})
+				continue
+			}
+
+			segment.Words[last].Text += token.Text
+			segment.Words[last].End = end
+		}
+
+		if n > 0 {
+			segment.Confidence = p / float64(n)
+		}
+
+		segments = append(segments, segment)
+	}
+
+	return segments, nil
+}
@@ -0,0 +1,173 @@
+package transcribe_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/store"
+	"github.com/laytan/youtupedia/internal/transcribe"
+)
+
+func TestWhisperCppWith(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"ggml-base.en.bin", "ggml-small.bin", "ggml-small.en-tdrz.bin"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := transcribe.NewWhisperCpp(transcribe.WhisperCppOptions{Model: filepath.Join(dir, "ggml-base.en.bin")})
+
+	cases := []struct {
+		name    string
+		params  transcribe.Params
+		want    store.TranscriptType
+		wantErr bool
+	}{
+		{"defaults", transcribe.Params{}, "whisper_cpp:base.en", false},
+		{"model name", transcribe.Params{Model: "small", Language: "de"}, "whisper_cpp:small", false},
+		{"model path", transcribe.Params{Model: filepath.Join(dir, "ggml-small.bin")}, "whisper_cpp:small", false},
+		{"missing model", transcribe.Params{Model: "large-v2"}, "", true},
+		{"english-only model", transcribe.Params{Language: "de"}, "", true},
+		{"diarize", transcribe.Params{Model: "small.en-tdrz", Diarize: true}, "whisper_cpp:small.en-tdrz", false},
+		{"diarize without tinydiarize model", transcribe.Params{Diarize: true}, "", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := w.With(c.params)
+			if c.wantErr {
+				if err == nil {
+					t.Errorf("With(%+v) returned no error", c.params)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("With(%+v): %v", c.params, err)
+			}
+
+			if got.Type() != c.want {
+				t.Errorf("With(%+v).Type() = %q, want %q", c.params, got.Type(), c.want)
+			}
+		})
+	}
+}
+
+// fakeWhisperCpp writes the -ojf output of whisper.cpp next to the audio file given with -f.
+const fakeWhisperCpp = `#!/bin/sh
+while [ $# -gt 0 ]; do
+	if [ "$1" = "-f" ]; then audio="$2"; fi
+	shift
+done
+cat > "$audio.json" <<'EOF'
+{
+	"result": {"language": "en"},
+	"transcription": [
+		{
+			"timestamps": {"from": "00:00:00,000", "to": "00:00:02,000"},
+			"offsets": {"from": 0, "to": 2000},
+			"text": " Hello wor-ld",
+			"tokens": [
+				{"text": "[_BEG_]", "offsets": {"from": 0, "to": 0}, "id": 50364, "p": 0.9},
+				{"text": " Hello", "offsets": {"from": 0, "to": 800}, "id": 15947, "p": 0.8},
+				{"text": " wor", "offsets": {"from": 900, "to": 1400}, "id": 469, "p": 0.6},
+				{"text": "-ld", "offsets": {"from": 1400, "to": 2000}, "id": 67, "p": 0.4},
+				{"text": "[_TT_100]", "offsets": {"from": 2000, "to": 2000}, "id": 50464, "p": 0.1}
+			]
+		}
+	]
+}
+EOF
+`
+
+func TestWhisperCppTranscribe(t *testing.T) {
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "main")
+	if err := os.WriteFile(bin, []byte(fakeWhisperCpp), 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	audio := filepath.Join(dir, "audio.wav")
+	w := transcribe.NewWhisperCpp(transcribe.WhisperCppOptions{Bin: bin})
+	got, err := w.Transcribe(context.Background(), audio)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []transcribe.Segment{{
+		Start:      0,
+		End:        2 * time.Second,
+		Text:       "Hello wor-ld",
+		Confidence: 0.6,
+		Words: []transcribe.Word{
+			{Start: 0, End: 800 * time.Millisecond, Text: "Hello"},
+			{Start: 900 * time.Millisecond, End: 2 * time.Second, Text: "wor-ld"},
+		},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Transcribe() = %+v, want %+v", got, want)
+	}
+
+	if _, err := os.Stat(audio + ".json"); !os.IsNotExist(err) {
+		t.Errorf("output of whisper.cpp was not removed, stat: %v", err)
+	}
+}
+
+// fakeWhisperCppTdrz writes -ojf output with speaker turns, but only when it is run with -tdrz.
+const fakeWhisperCppTdrz = `#!/bin/sh
+tdrz=false
+while [ $# -gt 0 ]; do
+	if [ "$1" = "-f" ]; then audio="$2"; fi
+	if [ "$1" = "-tdrz" ]; then tdrz=true; fi
+	shift
+done
+cat > "$audio.json" <<EOF
+{
+	"result": {"language": "en"},
+	"transcription": [
+		{"offsets": {"from": 0, "to": 1000}, "text": " How are you?", "speaker_turn_next": $tdrz},
+		{"offsets": {"from": 1000, "to": 2000}, "text": " Good.", "speaker_turn_next": false},
+		{"offsets": {"from": 2000, "to": 3000}, "text": " And you?", "speaker_turn_next": $tdrz},
+		{"offsets": {"from": 3000, "to": 4000}, "text": " Fine.", "speaker_turn_next": false}
+	]
+}
+EOF
+`
+
+func TestWhisperCppDiarize(t *testing.T) {
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "main")
+	if err := os.WriteFile(bin, []byte(fakeWhisperCppTdrz), 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	model := filepath.Join(dir, "ggml-small.en-tdrz.bin")
+	if err := os.WriteFile(model, nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := transcribe.NewWhisperCpp(transcribe.WhisperCppOptions{Bin: bin, Model: model}).With(transcribe.Params{Diarize: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := w.Transcribe(context.Background(), filepath.Join(dir, "audio.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	speakers := make([]string, 0, len(got))
+	for _, segment := range got {
+		speakers = append(speakers, segment.Speaker)
+	}
+
+	want := []string{"1", "2", "2", "1"}
+	if !reflect.DeepEqual(speakers, want) {
+		t.Errorf("speakers = %v, want %v", speakers, want)
+	}
+}
@@ -0,0 +1,3 @@
+package tube
+
+var BestTrack = bestTrack
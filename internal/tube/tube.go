@@ -25,6 +25,18 @@ type Thumbnail struct {
 }
 type Client struct {
 	Key string
+
+	// Web is the YouTube website, the watch and shorts pages are requested from it,
+	// defaults to https://www.youtube.com.
+	Web string
+}
+
+func (c *Client) web() string {
+	if c.Web == "" {
+		return "https://www.youtube.com"
+	}
+
+	return c.Web
 }
 
 type ChannelInfo struct {
@@ -232,12 +244,21 @@ const (
 	TypeManual
 )
 
+// TrackPreference decides which caption track Captions picks.
+type TrackPreference int
+
+const (
+	PreferManual TrackPreference = iota // Manual captions, falling back to automatic captions.
+	PreferAuto                          // Automatic captions, falling back to manual captions.
+	OnlyManual                          // Only manual captions, ErrNoCaptions if there are none.
+)
+
 // NOTE: could use yt-dlp for this: `yt-dlp --write-subs --write-auto-subs --sub-format srv1 --sub-langs "en.*" --no-download "https://youtube.com/watch?v=asdad"`
 // I believe this outputs the same format and might be more reliable.
 // Can check stdout for message: "There's no subtitles for the requested languages", and return ErrNoCaptions.
 // One thing is you can't just say give me the best one, this will write every matching captions.
-func (c *Client) Captions(videoId string) (*Transcript, TranscriptType, error) {
-	res, err := http.Get(fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoId))
+func (c *Client) Captions(videoId string, pref TrackPreference) (*Transcript, TranscriptType, error) {
+	res, err := http.Get(fmt.Sprintf("%s/watch?v=%s", c.web(), videoId))
 	if err != nil {
 		return nil, 0, fmt.Errorf("requesting watch page: %w", err)
 	}
@@ -287,7 +308,7 @@ func (c *Client) Captions(videoId string) (*Transcript, TranscriptType, error) {
 		return nil, 0, fmt.Errorf("could not unmarshal caption results %q: %w", rawCaptions, err)
 	}
 
-	track, trackType := bestTrack(captionsList.PlayerCaptionsTrackListRenderer.CaptionTracks)
+	track, trackType := bestTrack(captionsList.PlayerCaptionsTrackListRenderer.CaptionTracks, pref)
 	if trackType == TypeNone {
 		return nil, 0, ErrNoCaptions
 	}
@@ -321,6 +342,7 @@ type ResVideos struct {
 }
 
 type ResVideo struct {
+	Id      string
 	Snippet struct {
 		PublishedAt          string
 		ChannelId            string
@@ -330,27 +352,72 @@ type ResVideo struct {
 		LiveBroadcastContent string
 		// There is more but not needed.
 	}
+	ContentDetails struct {
+		Duration string // ISO 8601, for example PT1H2M3S.
+	}
+	Statistics struct {
+		ViewCount string // Numbers are strings in the API, missing when the channel hides them.
+	}
+	// Only set for (recordings of) live streams and premieres.
+	LiveStreamingDetails *struct {
+		ActualStartTime    string
+		ScheduledStartTime string
+	}
 }
 
 func (r *ResVideo) IsBroadcast() bool {
 	return r.Snippet.LiveBroadcastContent != "none"
 }
 
+// IsLive returns whether the video is a live stream, or a premiere, which the API does not distinguish.
+// See (*Client).IsLiveContent to tell them apart.
+func (r *ResVideo) IsLive() bool {
+	return r.LiveStreamingDetails != nil
+}
+
+func (r *ResVideo) Duration() (time.Duration, error) {
+	return ParseDuration(r.ContentDetails.Duration)
+}
+
 var ErrNotFound = errors.New("not found")
 
 func (c *Client) Video(id string) (*ResVideo, error) {
-	res, err := http.Get(fmt.Sprintf("%s?part=snippet&id=%s&key=%s", EndpointVideo, id, c.Key))
+	videos, err := c.Videos([]string{id})
 	if err != nil {
-		return nil, fmt.Errorf("video %q request: %w", id, err)
+		return nil, err
+	}
+
+	if len(videos) == 0 {
+		return nil, fmt.Errorf("videos result has no items: %w", ErrNotFound)
+	}
+
+	return &videos[0], nil
+}
+
+// Videos retrieves up to 50 videos at once, for 1 quota.
+// Videos that can't be found are left out of the result.
+func (c *Client) Videos(ids []string) ([]ResVideo, error) {
+	res, err := http.Get(fmt.Sprintf(
+		"%s?part=snippet,contentDetails,liveStreamingDetails,statistics&id=%s&key=%s&maxResults=50",
+		EndpointVideo,
+		strings.Join(ids, ","),
+		c.Key,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("videos %v request: %w", ids, err)
 	}
 	defer res.Body.Close()
 
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
-		return nil, fmt.Errorf("reading videos %q body: %w", id, err)
+		return nil, fmt.Errorf("reading videos %v body: %w", ids, err)
 	}
 
 	if res.StatusCode != 200 {
+		if res.StatusCode == http.StatusForbidden {
+			return nil, ErrQuotaExceeded
+		}
+
 		return nil, fmt.Errorf("videos status code %d: %w", res.StatusCode, ErrNotOk)
 	}
 
@@ -359,11 +426,53 @@ func (c *Client) Video(id string) (*ResVideo, error) {
 		return nil, fmt.Errorf("unmarshalling videos response %q: %w", string(body), err)
 	}
 
-	if len(result.Items) == 0 {
-		return nil, fmt.Errorf("videos result has no items: %w", ErrNotFound)
+	return result.Items, nil
+}
+
+// IsShort returns whether the video is a YouTube Short,
+// YouTube redirects the shorts URL to the watch page for videos that aren't.
+func (c *Client) IsShort(id string) (bool, error) {
+	client := http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
 	}
 
-	return &result.Items[0], nil
+	res, err := client.Head(c.web() + "/shorts/" + id)
+	if err != nil {
+		return false, fmt.Errorf("requesting shorts page: %w", err)
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusOK:
+		return true, nil
+	case res.StatusCode >= 300 && res.StatusCode < 400:
+		return false, nil
+	default:
+		return false, fmt.Errorf("shorts page status code %d: %w", res.StatusCode, ErrNotOk)
+	}
+}
+
+// IsLiveContent returns whether the video is the recording of a live stream,
+// for premieres, which the API also returns live streaming details for, it returns false.
+func (c *Client) IsLiveContent(id string) (bool, error) {
+	res, err := http.Get(fmt.Sprintf("%s/watch?v=%s", c.web(), id))
+	if err != nil {
+		return false, fmt.Errorf("requesting watch page: %w", err)
+	}
+	defer res.Body.Close()
+
+	content, err := io.ReadAll(res.Body)
+	if err != nil {
+		return false, fmt.Errorf("reading response body: %w", err)
+	}
+
+	if res.StatusCode != 200 {
+		return false, fmt.Errorf("watch page status code %d: %w", res.StatusCode, ErrNotOk)
+	}
+
+	return strings.Contains(string(content), `"isLiveContent":true`), nil
 }
 
 var thumbResses = []string{"maxres", "high", "medium", "standard", "default"}
@@ -395,7 +504,29 @@ func ParsePublishedTime(value string) (time.Time, error) {
 // Then for english automatic,
 // Then goes for non-english non-automatic,
 // Then for non-english automatic.
-func bestTrack(tracks []ResTrack) (*ResTrack, TranscriptType) {
+//
+// With PreferAuto, automatic tracks go before manual ones of the same language group,
+// english tracks still go before non-english ones,
+// with OnlyManual, automatic tracks are never returned.
+func bestTrack(tracks []ResTrack, pref TrackPreference) (*ResTrack, TranscriptType) {
+	if pref == OnlyManual {
+		var manual []ResTrack
+		for _, t := range tracks {
+			if t.Kind != "asr" {
+				manual = append(manual, t)
+			}
+		}
+		tracks = manual
+	}
+
+	if pref == PreferAuto {
+		for _, t := range tracks {
+			if strings.HasPrefix(t.LanguageCode, "en") && t.Kind == "asr" {
+				return &t, TypeAuto
+			}
+		}
+	}
+
 	for _, t := range tracks {
 		if strings.HasPrefix(t.LanguageCode, "en") && t.Kind != "asr" {
 			return &t, TypeManual
@@ -408,6 +539,14 @@ func bestTrack(tracks []ResTrack) (*ResTrack, TranscriptType) {
 		}
 	}
 
+	if pref == PreferAuto {
+		for _, t := range tracks {
+			if t.Kind == "asr" {
+				return &t, TypeAuto
+			}
+		}
+	}
+
 	for _, t := range tracks {
 		if t.Kind != "asr" {
 			return &t, TypeManual
@@ -420,3 +559,50 @@ func bestTrack(tracks []ResTrack) (*ResTrack, TranscriptType) {
 
 	return nil, TypeNone
 }
+
+// ParseDuration parses the ISO 8601 durations the API returns, for example PT1H2M3S or P1DT2H.
+func ParseDuration(value string) (time.Duration, error) {
+	rest, ok := strings.CutPrefix(value, "P")
+	if !ok {
+		return 0, fmt.Errorf("parse duration %q: missing P prefix", value)
+	}
+
+	var d time.Duration
+	inTime := false
+	var num int
+	var hasNum bool
+	for _, ch := range rest {
+		switch {
+		case ch >= '0' && ch <= '9':
+			num = num*10 + int(ch-'0')
+			hasNum = true
+			continue
+		case ch == 'T':
+			inTime = true
+			continue
+		case !hasNum:
+			return 0, fmt.Errorf("parse duration %q: missing number before %q", value, ch)
+		case ch == 'W' && !inTime:
+			d += time.Duration(num) * 7 * 24 * time.Hour
+		case ch == 'D' && !inTime:
+			d += time.Duration(num) * 24 * time.Hour
+		case ch == 'H' && inTime:
+			d += time.Duration(num) * time.Hour
+		case ch == 'M' && inTime:
+			d += time.Duration(num) * time.Minute
+		case ch == 'S' && inTime:
+			d += time.Duration(num) * time.Second
+		default:
+			return 0, fmt.Errorf("parse duration %q: unexpected %q", value, ch)
+		}
+
+		num = 0
+		hasNum = false
+	}
+
+	if hasNum {
+		return 0, fmt.Errorf("parse duration %q: missing unit", value)
+	}
+
+	return d, nil
+}
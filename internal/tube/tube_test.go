@@ -0,0 +1,106 @@
+package tube_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/laytan/youtupedia/internal/tube"
+)
+
+func TestParseDuration(t *testing.T) {
+	cases := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"PT1H2M3S", time.Hour + 2*time.Minute + 3*time.Second, false},
+		{"PT45S", 45 * time.Second, false},
+		{"PT10M", 10 * time.Minute, false},
+		{"P1DT2H", 26 * time.Hour, false},
+		{"P1W", 7 * 24 * time.Hour, false},
+		{"P0D", 0, false},
+		{"", 0, true},
+		{"1H", 0, true},
+		{"PTM", 0, true},
+		{"PT5", 0, true},
+		{"P5M", 0, true}, // Months are not durations the API returns.
+	}
+
+	for _, c := range cases {
+		got, err := tube.ParseDuration(c.value)
+		if (err != nil) != c.wantErr || got != c.want {
+			t.Errorf("ParseDuration(%q) = %s, %v, want %s, error: %t", c.value, got, err, c.want, c.wantErr)
+		}
+	}
+}
+
+func TestBestTrack(t *testing.T) {
+	enAuto := tube.ResTrack{LanguageCode: "en", Kind: "asr"}
+	enManual := tube.ResTrack{LanguageCode: "en-GB"}
+	nlAuto := tube.ResTrack{LanguageCode: "nl", Kind: "asr"}
+	nlManual := tube.ResTrack{LanguageCode: "nl"}
+
+	cases := []struct {
+		name     string
+		tracks   []tube.ResTrack
+		pref     tube.TrackPreference
+		want     string
+		wantType tube.TranscriptType
+	}{
+		{"manual english first", []tube.ResTrack{nlManual, enAuto, enManual}, tube.PreferManual, "en-GB", tube.TypeManual},
+		{"manual falls back to english auto", []tube.ResTrack{nlManual, enAuto}, tube.PreferManual, "en", tube.TypeAuto},
+		{"manual non-english", []tube.ResTrack{nlAuto, nlManual}, tube.PreferManual, "nl", tube.TypeManual},
+		{"auto english first", []tube.ResTrack{enManual, enAuto}, tube.PreferAuto, "en", tube.TypeAuto},
+		{"auto falls back to english manual", []tube.ResTrack{nlAuto, enManual}, tube.PreferAuto, "en-GB", tube.TypeManual},
+		{"auto non-english", []tube.ResTrack{nlManual, nlAuto}, tube.PreferAuto, "nl", tube.TypeAuto},
+		{"only manual", []tube.ResTrack{enAuto, nlManual}, tube.OnlyManual, "nl", tube.TypeManual},
+		{"only manual without manual", []tube.ResTrack{enAuto, nlAuto}, tube.OnlyManual, "", tube.TypeNone},
+		{"no tracks", nil, tube.PreferAuto, "", tube.TypeNone},
+	}
+
+	for _, c := range cases {
+		track, typ := tube.BestTrack(c.tracks, c.pref)
+		var got string
+		if track != nil {
+			got = track.LanguageCode
+		}
+
+		if got != c.want || typ != c.wantType {
+			t.Errorf("%s: BestTrack() = %q, %d, want %q, %d", c.name, got, typ, c.want, c.wantType)
+		}
+	}
+}
+
+func TestIsShort(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/shorts/short":
+			w.WriteHeader(http.StatusOK)
+		case "/shorts/video":
+			http.Redirect(w, r, "/watch?v=video", http.StatusSeeOther)
+		default:
+			http.Error(w, "unavailable", http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	client := &tube.Client{Web: server.URL}
+	cases := []struct {
+		id      string
+		want    bool
+		wantErr bool
+	}{
+		{"short", true, false},
+		{"video", false, false},
+		{"broken", false, true},
+	}
+
+	for _, c := range cases {
+		got, err := client.IsShort(c.id)
+		if (err != nil) != c.wantErr || got != c.want {
+			t.Errorf("IsShort(%q) = %t, %v, want %t, error: %t", c.id, got, err, c.want, c.wantErr)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package youtupedia
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/laytan/youtupedia/internal/index"
+	"github.com/laytan/youtupedia/internal/store"
+)
+
+const (
+	contributorCookie = "contributor"
+
+	// maxSubmission is the size limit of a submitted transcript, in bytes.
+	maxSubmission = 1 << 20
+)
+
+type ContributeData struct {
+	Contributor *store.Contributor
+	Failures    []store.Failure
+	Limit       int
+	Error       string
+}
+
+type SubmitData struct {
+	Contributor store.Contributor
+	VideoID     string
+	Formats     []store.SubmissionFormat
+	Format      string
+	Content     string
+	Submitted   bool
+	Error       string
+}
+
+// contributeRoutes serves the pages where contributors submit transcripts for videos in the no captions queue.
+// Contributors sign in with the token they got from `contributors add`, submissions are moderated with the CLI.
+func (s *Server) contributeRoutes(ctx context.Context, app *fiber.App) {
+	app.Get("/contribute", func(c *fiber.Ctx) error {
+		data := ContributeData{Limit: 100}
+		contributor, ok, err := s.contributor(ctx, c)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return c.Render("contribute", data)
+		}
+		data.Contributor = &contributor
+
+		failures, err := s.store.ListFailures(ctx, store.ListFailuresParams{
+			Type:       sql.NullString{String: string(store.FailureTypeNoCaptions), Valid: true},
+			Dead:       sql.NullBool{Bool: false, Valid: true},
+			MaxResults: int32(data.Limit),
+		})
+		if err != nil {
+			log.Printf("[ERROR]: retrieving failures: %v", err)
+			return fiber.NewError(http.StatusInternalServerError, "retrieving videos without captions failed")
+		}
+		data.Failures = failures
+
+		return c.Render("contribute", data)
+	})
+
+	app.Post("/contribute/sign-in", func(c *fiber.Ctx) error {
+		token := strings.TrimSpace(c.FormValue("token"))
+		if _, err := s.store.ContributorByTokenHash(ctx, store.HashContributorToken(token)); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				c.Status(http.StatusUnauthorized)
+				return c.Render("contribute", ContributeData{Error: "Unknown or disabled token"})
+			}
+
+			log.Printf("[ERROR]: retrieving contributor: %v", err)
+			return fiber.NewError(http.StatusInternalServerError, "signing in failed")
+		}
+
+		c.Cookie(&fiber.Cookie{
+			Name:     contributorCookie,
+			Value:    token,
+			Path:     "/contribute",
+			Expires:  time.Now().AddDate(0, 6, 0),
+			HTTPOnly: true,
+			SameSite: fiber.CookieSameSiteStrictMode,
+		})
+		return c.Redirect("/contribute", http.StatusSeeOther)
+	})
+
+	app.Get("/contribute/:video", func(c *fiber.Ctx) error {
+		contributor, ok, err := s.contributor(ctx, c)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return c.Redirect("/contribute", http.StatusSeeOther)
+		}
+
+		return c.Render("submit", SubmitData{
+			Contributor: contributor,
+			VideoID:     c.Params("video"),
+			Formats:     submissionFormats,
+		})
+	})
+
+	app.Post("/contribute/:video", func(c *fiber.Ctx) error {
+		contributor, ok, err := s.contributor(ctx, c)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return fiber.NewError(http.StatusUnauthorized, "sign in to contribute transcripts")
+		}
+
+		data := SubmitData{
+			Contributor: contributor,
+			VideoID:     c.Params("video"),
+			Formats:     submissionFormats,
+			Format:      c.FormValue("format"),
+			Content:     c.FormValue("content"),
+		}
+
+		// An uploaded file takes precedence over pasted text.
+		if file, err := c.FormFile("file"); err == nil && file.Size > 0 {
+			if file.Size > maxSubmission {
+				data.Error = "The file is too large"
+				return c.Status(http.StatusRequestEntityTooLarge).Render("submit", data)
+			}
+
+			switch strings.ToLower(filepath.Ext(file.Filename)) {
+			case ".srt":
+				data.Format = string(store.SubmissionSRT)
+			case ".vtt":
+				data.Format = string(store.SubmissionVTT)
+			}
+
+			fh, err := file.Open()
+			if err != nil {
+				return fiber.NewError(http.StatusBadRequest, "reading the uploaded file failed")
+			}
+			defer fh.Close()
+
+			content, err := io.ReadAll(fh)
+			if err != nil {
+				return fiber.NewError(http.StatusBadRequest, "reading the uploaded file failed")
+			}
+			data.Content = string(content)
+		}
+
+		if len(data.Content) > maxSubmission {
+			data.Error = "The transcript is too large"
+			return c.Status(http.StatusRequestEntityTooLarge).Render("submit", data)
+		}
+
+		_, err = index.Submit(ctx, s.store, contributor, data.VideoID, store.SubmissionFormat(data.Format), data.Content)
+		if err != nil {
+			log.Printf("[WARN]: submission of %q for %q: %v", contributor.Name, data.VideoID, err)
+			data.Error = err.Error()
+			return c.Status(http.StatusUnprocessableEntity).Render("submit", data)
+		}
+
+		data.Submitted = true
+		data.Content = ""
+		return c.Render("submit", data)
+	})
+}
+
+var submissionFormats = []store.SubmissionFormat{store.SubmissionSRT, store.SubmissionVTT, store.SubmissionText}
+
+// contributor returns the contributor that is signed in, if any.
+func (s *Server) contributor(ctx context.Context, c *fiber.Ctx) (store.Contributor, bool, error) {
+	token := c.Cookies(contributorCookie)
+	if token == "" {
+		return store.Contributor{}, false, nil
+	}
+
+	contributor, err := s.store.ContributorByTokenHash(ctx, store.HashContributorToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.ClearCookie(contributorCookie)
+		return store.Contributor{}, false, nil
+	} else if err != nil {
+		log.Printf("[ERROR]: retrieving contributor: %v", err)
+		return store.Contributor{}, false, fiber.NewError(http.StatusInternalServerError, "retrieving contributor failed")
+	}
+
+	return contributor, true, nil
+}
@@ -0,0 +1,83 @@
+package youtupedia
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/laytan/youtupedia/internal/store"
+)
+
+type PlayerData struct {
+	Video       store.Video
+	Start       int // In seconds.
+	Transcripts []store.Transcript
+}
+
+// playerRoutes serves the built-in player for the local files of directory channels, see store.PlayerPath,
+// and the files themselves. Only files that are indexed are served, by the ID of their video,
+// files that were skipped or are not transcribed yet are not found.
+func (s *Server) playerRoutes(ctx context.Context, app *fiber.App) {
+	app.Get("/play/:video", func(c *fiber.Ctx) error {
+		video, err := s.store.Video(ctx, c.Params("video"))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fiber.NewError(http.StatusNotFound, "video not found")
+			}
+
+			log.Printf("[ERROR]: retrieving video: %v", err)
+			return fiber.NewError(http.StatusInternalServerError, "retrieving video failed")
+		}
+
+		if video.AudioUrl != store.PlayerPath(video.ID) {
+			return c.Redirect(video.URL(0))
+		}
+
+		data := PlayerData{Video: video}
+		if t, err := strconv.Atoi(c.Query("t")); err == nil && t > 0 {
+			data.Start = t
+		}
+
+		transcripts, err := s.store.TranscriptsOfVideo(ctx, video.ID)
+		if err != nil {
+			log.Printf("[ERROR]: retrieving transcripts: %v", err)
+			return fiber.NewError(http.StatusInternalServerError, "retrieving transcripts failed")
+		}
+		data.Transcripts = transcripts
+
+		return c.Render("player", data)
+	})
+
+	app.Get("/media/:video", func(c *fiber.Ctx) error {
+		video, err := s.store.Video(ctx, c.Params("video"))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fiber.NewError(http.StatusNotFound, "media file not found")
+			}
+
+			log.Printf("[ERROR]: retrieving video: %v", err)
+			return fiber.NewError(http.StatusInternalServerError, "retrieving video failed")
+		}
+
+		if video.AudioUrl != store.PlayerPath(video.ID) {
+			return fiber.NewError(http.StatusNotFound, "media file not found")
+		}
+
+		file, err := s.store.MediaFile(ctx, store.MediaFileParams{ChannelID: video.ChannelID, VideoID: video.ID})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fiber.NewError(http.StatusNotFound, "media file not found")
+			}
+
+			log.Printf("[ERROR]: retrieving media file: %v", err)
+			return fiber.NewError(http.StatusInternalServerError, "retrieving media file failed")
+		}
+
+		// Supports range requests, so the player can seek without downloading the whole file.
+		return c.SendFile(file.Path)
+	})
+}
@@ -2,6 +2,7 @@ package youtupedia
 
 import (
 	"context"
+	"database/sql"
 	"embed"
 	_ "embed"
 	"errors"
@@ -21,13 +22,10 @@ import (
 
 const (
 	ServeChannel = "UCd3dNckv1Za2coSaHGHl5aA"
-	Port         = ":8080"
 	CheckTime    = time.Hour
 )
 
 var (
-	Queries *store.Queries
-
 	//go:embed templates
 	_templatesFS embed.FS
 	templatesFS  fs.FS
@@ -38,10 +36,32 @@ type IndexData struct {
 }
 
 type ChannelData struct {
-	Channel store.Channel
-	Results []search.Result
-	IsQuery bool
-	Query   string
+	Channel  store.Channel
+	Results  []search.Result
+	IsQuery  bool
+	Query    string
+	Metadata bool
+	Mode     search.Mode
+}
+
+type FailuresData struct {
+	Counts   []store.FailureCountsByChannelRow
+	Failures []store.Failure
+	Limit    int
+}
+
+type SettingsData struct {
+	Channels []ChannelSettings
+}
+
+type ChannelSettings struct {
+	Channel  store.Channel
+	Settings store.ChannelSetting
+}
+
+type SubmissionsData struct {
+	Submissions []store.ListSubmissionsRow
+	Limit       int
 }
 
 func init() {
@@ -52,23 +72,51 @@ func init() {
 	templatesFS = subTemplatesFS
 }
 
-func Start(ctx context.Context) {
+type Options struct {
+	// Admin serves the read-only admin pages under /admin.
+	// There is no authentication, so only enable this when the server is not publicly reachable.
+	Admin bool
+
+	// Contributions serves the pages under /contribute, where contributors submit transcripts
+	// for videos without captions, see store.Contributor and store.Submission.
+	Contributions bool
+}
+
+// Server serves the web interface for searching through the indexed channels.
+type Server struct {
+	store    store.Store
+	searcher *search.Searcher
+	indexer  *index.Indexer
+	opts     Options
+}
+
+func New(s store.Store, searcher *search.Searcher, indexer *index.Indexer, opts Options) *Server {
+	return &Server{
+		store:    s,
+		searcher: searcher,
+		indexer:  indexer,
+		opts:     opts,
+	}
+}
+
+// Start serves the web interface on the given address, for example ":8080".
+func (s *Server) Start(ctx context.Context, addr string) {
 	engine := html.NewFileSystem(http.FS(templatesFS), "")
-    engine.Debug(true)
-    engine.Reload(true)
+	engine.Debug(true)
+	engine.Reload(true)
 
 	app := fiber.New(fiber.Config{
-		Views: engine,
-        ViewsLayout: "layout",
+		Views:       engine,
+		ViewsLayout: "layout",
 	})
 
-	// go periodicallyCheckNewUploads(ctx)
+	// go s.periodicallyCheckNewUploads(ctx)
 
 	// TODO: can this be static?
 	app.Static("/", "internal/youtupedia/static")
 
 	app.Get("/", func(c *fiber.Ctx) error {
-		channels, err := Queries.Channels(ctx)
+		channels, err := s.store.Channels(ctx)
 		if err != nil {
 			log.Println(err)
 			c.Status(http.StatusInternalServerError)
@@ -80,7 +128,7 @@ func Start(ctx context.Context) {
 
 	app.Get("/@:url", func(c *fiber.Ctx) error {
 		var data ChannelData
-		channel, err := Queries.ChannelByUrl(ctx, "@"+c.Params("url"))
+		channel, err := s.store.ChannelByUrl(ctx, "@"+c.Params("url"))
 		if err != nil {
 			return fmt.Errorf("retrieving channel: %w", err)
 		}
@@ -89,6 +137,12 @@ func Start(ctx context.Context) {
 		_, isHtmx := c.GetReqHeaders()["Hx-Request"]
 
 		query := c.Query("q")
+		data.Metadata = c.Query("meta") != ""
+		data.Mode, err = search.ParseMode(c.Query("mode"))
+		if err != nil {
+			return fiber.NewError(http.StatusUnprocessableEntity, err.Error())
+		}
+
 		if query == "" {
 			if isHtmx {
 				return c.Render("results", data.Results)
@@ -97,7 +151,8 @@ func Start(ctx context.Context) {
 			return c.Render("channel", data)
 		}
 
-		if len(query) < 3 {
+		text, speaker := search.SplitSpeaker(query)
+		if len(text) < 3 {
 			return fiber.NewError(
 				http.StatusUnprocessableEntity,
 				"Please type at least 3 characters",
@@ -105,8 +160,13 @@ func Start(ctx context.Context) {
 		}
 		data.Query = strings.Clone(query)
 
-		log.Printf("[INFO]: searching for %q in %q", query, channel.Title)
-		res, err := search.Channel(ctx, &channel, query)
+		log.Printf("[INFO]: searching for %q (%s) in %q", query, data.Mode, channel.Title)
+		res, err := s.searcher.Channel(ctx, &channel, search.Query{
+			Text:     text,
+			Mode:     data.Mode,
+			Metadata: data.Metadata,
+			Speaker:  speaker,
+		})
 		if err != nil {
 			log.Printf("[ERROR]: %v", err)
 			return fiber.NewError(http.StatusInternalServerError, "search failed")
@@ -121,12 +181,84 @@ func Start(ctx context.Context) {
 		return c.Render("channel", data)
 	})
 
-	log.Fatal(app.Listen(Port))
+	if s.opts.Admin {
+		app.Get("/admin/failures", func(c *fiber.Ctx) error {
+			data := FailuresData{Limit: 100}
+
+			counts, err := s.store.FailureCountsByChannel(ctx)
+			if err != nil {
+				log.Printf("[ERROR]: counting failures: %v", err)
+				return fiber.NewError(http.StatusInternalServerError, "counting failures failed")
+			}
+			data.Counts = counts
+
+			failures, err := s.store.ListFailures(ctx, store.ListFailuresParams{
+				ChannelID:  sql.NullString{String: c.Query("channel"), Valid: c.Query("channel") != ""},
+				Type:       sql.NullString{String: c.Query("type"), Valid: c.Query("type") != ""},
+				Dead:       sql.NullBool{Bool: true, Valid: c.Query("dead") != ""},
+				MaxResults: int32(data.Limit),
+			})
+			if err != nil {
+				log.Printf("[ERROR]: retrieving failures: %v", err)
+				return fiber.NewError(http.StatusInternalServerError, "retrieving failures failed")
+			}
+			data.Failures = failures
+
+			return c.Render("failures", data)
+		})
+
+		// Settings are only shown, changing them is done with `channels settings`, the admin pages are not authenticated.
+		app.Get("/admin/settings", func(c *fiber.Ctx) error {
+			var data SettingsData
+
+			channels, err := s.store.Channels(ctx)
+			if err != nil {
+				log.Printf("[ERROR]: retrieving channels: %v", err)
+				return fiber.NewError(http.StatusInternalServerError, "retrieving channels failed")
+			}
+
+			for _, channel := range channels {
+				settings, err := store.ChannelSettingsOrDefault(ctx, s.store, channel.ID)
+				if err != nil {
+					log.Printf("[ERROR]: retrieving settings of %q: %v", channel.ID, err)
+					return fiber.NewError(http.StatusInternalServerError, "retrieving settings failed")
+				}
+
+				data.Channels = append(data.Channels, ChannelSettings{Channel: channel, Settings: settings})
+			}
+
+			return c.Render("settings", data)
+		})
+
+		app.Get("/admin/submissions", func(c *fiber.Ctx) error {
+			data := SubmissionsData{Limit: 100}
+
+			submissions, err := s.store.ListSubmissions(ctx, store.ListSubmissionsParams{
+				Status:     sql.NullString{String: c.Query("status"), Valid: c.Query("status") != ""},
+				MaxResults: int32(data.Limit),
+			})
+			if err != nil {
+				log.Printf("[ERROR]: retrieving submissions: %v", err)
+				return fiber.NewError(http.StatusInternalServerError, "retrieving submissions failed")
+			}
+			data.Submissions = submissions
+
+			return c.Render("submissions", data)
+		})
+	}
+
+	s.playerRoutes(ctx, app)
+
+	if s.opts.Contributions {
+		s.contributeRoutes(ctx, app)
+	}
+
+	log.Fatal(app.Listen(addr))
 }
 
 // NOTE: maybe could do webhooks, like a checkNewUploads, followed by subscribing to the webhooks for the channels.
-func periodicallyCheckNewUploads(ctx context.Context) {
-	if err := checkNewUploads(ctx); err != nil {
+func (s *Server) periodicallyCheckNewUploads(ctx context.Context) {
+	if err := s.checkNewUploads(ctx); err != nil {
 		log.Printf("[ERROR]: checking new uploads: %v", err)
 	}
 
@@ -136,22 +268,22 @@ func periodicallyCheckNewUploads(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			if err := checkNewUploads(ctx); err != nil {
+			if err := s.checkNewUploads(ctx); err != nil {
 				log.Printf("[ERROR]: checking new uploads: %v", err)
 			}
 		}
 	}
 }
 
-func checkNewUploads(ctx context.Context) error {
-	channels, err := Queries.Channels(ctx)
+func (s *Server) checkNewUploads(ctx context.Context) error {
+	channels, err := s.store.Channels(ctx)
 	if err != nil {
 		return fmt.Errorf("retrieving channels: %w", err)
 	}
 
 	for _, channel := range channels {
 		log.Printf("[INFO]: checking new uploads for %q - %q", channel.ID, channel.Title)
-		err := index.IndexChannel(ctx, &channel)
+		err := s.indexer.IndexChannel(ctx, &channel, index.ChannelOptions{})
 		if err != nil && !errors.Is(err, index.ErrAlreadyIndexed) {
 			return fmt.Errorf("indexing channel %q: %w", channel.Title, err)
 		}